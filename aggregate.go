@@ -0,0 +1,156 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// AttrExtent returns the least and greatest values of attribute attr, an
+// index of an AttrFunc passed to IndexAttrs, over the points of t within
+// hr, such as the greatest sensor reading in a box.  ok is false if there
+// are no points within hr.
+//
+// Subtrees whose boxes lie inside hr answer from their stored ranges
+// without visiting their points, so the work is that of finding the
+// subtrees cut by the edges of hr rather than of enumerating the points.
+// As ranges are not shrunk when points are deleted, after deletions the
+// result may include values of deleted points.  Calling IndexAttrs again
+// makes it exact.  A view restricted by Where or InCells tests each point.
+func (t KdTree) AttrExtent(hr HyperRect, attr int) (min, max float64, ok bool) {
+	a := t.attrs[attr]
+	min, max = math.Inf(1), math.Inf(-1)
+	t.boxSearch(hr, func(kd *kdNode, _ HyperRect) bool {
+		if kd.attrs == nil {
+			return false
+		}
+		min = math.Min(min, kd.attrs[2*attr])
+		max = math.Max(max, kd.attrs[2*attr+1])
+		ok = true
+		return true
+	}, func(kd *kdNode) {
+		v := a(kd.rangeElt)
+		min, max = math.Min(min, v), math.Max(max, v)
+		ok = true
+	})
+	if !ok {
+		return 0, 0, false
+	}
+	return
+}
+
+// Histogram counts the points of t within hr in the cells of a regular
+// grid over hr with bins[d] cells in dimension d.  The counts are in row
+// major order, the last dimension varying fastest, so for a two
+// dimensional grid the count of cell (i, j) is at i*bins[1] + j.  A point
+// on a boundary between cells is counted in the upper cell, except on
+// the upper edge of hr, which is in the last cell.  bins must hold a
+// positive number for each dimension.
+//
+// A subtree whose box lies inside a single cell is counted by its size
+// without visiting its points, so the work is that of finding the
+// subtrees cut by cell boundaries, far less than counting point by point
+// when there are many points per cell.  While t has tombstones left by
+// Remove, subtrees are counted point by point.
+func (t KdTree) Histogram(hr HyperRect, bins []int) []int {
+	n := 1
+	for _, b := range bins {
+		n *= b
+	}
+	counts := make([]int, n)
+	// bin returns the cell holding p
+	bin := func(p Point) int {
+		c := 0
+		for d, b := range bins {
+			i := 0
+			if w := hr.Max[d] - hr.Min[d]; w > 0 {
+				i = int((p[d] - hr.Min[d]) / w * float64(b))
+			}
+			c = c*b + min(max(i, 0), b-1)
+		}
+		return c
+	}
+	t.boxSearch(hr, func(kd *kdNode, box HyperRect) bool {
+		if t.dead > 0 {
+			return false
+		}
+		c := bin(box.Min)
+		if bin(box.Max) != c {
+			return false
+		}
+		counts[c] += kd.size
+		return true
+	}, func(kd *kdNode) {
+		counts[bin(kd.domElt)]++
+	})
+	return counts
+}
+
+// boxSearch visits the points of t within hr for aggregate queries.  For
+// each subtree whose box lies inside hr, whole is called with the subtree
+// and its box, and may summarize the subtree and return true, or return
+// false to have whole called for its subtrees in turn and its own point
+// visited.  point is called for each other live point within hr.  Boxes
+// are cells or tight bounds as for regionSearch.  whole is not called for
+// a restricted view, or if t.Brute is set.
+func (t KdTree) boxSearch(hr HyperRect, whole func(kd *kdNode, box HyperRect) bool,
+	point func(kd *kdNode)) {
+	if t.n == nil {
+		return
+	}
+	each := func(kd *kdNode) bool {
+		point(kd)
+		return true
+	}
+	if t.Brute {
+		t.yieldAll(t.n, func(kd *kdNode) bool {
+			return !hr.Contains(kd.domElt) || each(kd)
+		})
+		return
+	}
+	restricted := t.restricted()
+	c := newCellStack(t, 0.)
+	for {
+		kd, _, ok := c.pop()
+		if !ok {
+			return
+		}
+		if t.excludes(kd) {
+			continue
+		}
+		box := c.cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		switch hr.classify(box) {
+		case outside:
+			continue
+		case inside:
+			if restricted {
+				t.yieldAll(kd, each)
+				continue
+			}
+			if whole(kd, box) {
+				continue
+			}
+		}
+		if t.sees(kd) && hr.Contains(kd.domElt) {
+			each(kd)
+		}
+		c.push(kd, kd.right, 0)
+		c.push(kd, kd.left, 0)
+	}
+}
+
+// classify places box outside, partly inside, or inside hr.
+func (hr HyperRect) classify(box HyperRect) int {
+	in := inside
+	for i, min := range hr.Min {
+		if box.Min[i] > hr.Max[i] || box.Max[i] < min {
+			return outside
+		}
+		if box.Min[i] < min || box.Max[i] > hr.Max[i] {
+			in = partial
+		}
+	}
+	return in
+}
@@ -0,0 +1,111 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAttrExtent(t *testing.T) {
+	pts := randomPts(2, 2000)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = record{math.Sin(float64(i)), i % 7}
+	}
+	reading := func(d interface{}) float64 { return d.(record).time }
+	category := func(d interface{}) float64 { return float64(d.(record).category) }
+	kd := NewWith(append([]Point{}, pts...), WithData(data),
+		WithAttrs(reading, category), WithTighten())
+	kd.Brute = false
+	for i := 0; i < 100; i++ {
+		p := randomPt(2)
+		r := record{math.Sin(float64(len(pts))), len(pts) % 7}
+		pts = append(pts, p)
+		data = append(data, r)
+		kd.InsertWithData(p, r)
+	}
+	brute := func(hr HyperRect, cat int) (min, max float64, ok bool) {
+		min, max = math.Inf(1), math.Inf(-1)
+		for i, p := range pts {
+			r := data[i].(record)
+			if hr.Contains(p) && (cat < 0 || r.category == cat) {
+				min, max = math.Min(min, r.time), math.Max(max, r.time)
+				ok = true
+			}
+		}
+		if !ok {
+			return 0, 0, false
+		}
+		return
+	}
+	view := kd.Where(AttrRange{1, 3, 3})
+	for i := 0; i < 50; i++ {
+		a, b := randomPt(2), randomPt(2)
+		hr := HyperRect{
+			Point{math.Min(a[0], b[0]), math.Min(a[1], b[1])},
+			Point{math.Max(a[0], b[0]), math.Max(a[1], b[1])}}
+		min, max, ok := kd.AttrExtent(hr, 0)
+		wMin, wMax, wOk := brute(hr, -1)
+		if min != wMin || max != wMax || ok != wOk {
+			t.Fatal(hr, "got", min, max, ok, "expected", wMin, wMax, wOk)
+		}
+		min, max, ok = view.AttrExtent(hr, 0)
+		wMin, wMax, wOk = brute(hr, 3)
+		if min != wMin || max != wMax || ok != wOk {
+			t.Fatal("view", hr, "got", min, max, ok, "expected", wMin, wMax, wOk)
+		}
+	}
+	if _, _, ok := kd.AttrExtent(HyperRect{Point{2, 2}, Point{3, 3}}, 0); ok {
+		t.Error("extent of empty box")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	pts := randomPts(2, 5000)
+	kd := NewWith(append([]Point{}, pts...), WithTighten())
+	kd.Brute = false
+	hr := HyperRect{Point{.1, .2}, Point{.9, .6}}
+	bins := []int{4, 3}
+	brute := func() []int {
+		c := make([]int, 12)
+		for _, p := range pts {
+			if hr.Contains(p) {
+				i := min(int((p[0]-.1)/.8*4), 3)
+				j := min(int((p[1]-.2)/.4*3), 2)
+				c[i*3+j]++
+			}
+		}
+		return c
+	}
+	check := func(when string) {
+		got, want := kd.Histogram(hr, bins), brute()
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatal(when, "cell", i, "got", got[i], "expected", want[i])
+			}
+		}
+	}
+	check("")
+	for i := 0; i < 500; i++ {
+		p := randomPt(2)
+		pts = append(pts, p)
+		kd.Insert(p)
+	}
+	for _, p := range pts[:300] {
+		kd.Delete(p)
+	}
+	pts = pts[300:]
+	check("after updates,")
+	for _, p := range pts[:100] {
+		kd.Remove(p)
+	}
+	pts = pts[100:]
+	check("with tombstones,")
+}
+
+func TestHistogramDeep(t *testing.T) {
+	kd, pts := deepTree(3000)
+	got := kd.Histogram(HyperRect{Point{0, 0}, Point{1, 1}}, []int{2, 2})
+	if h := len(pts) / 2; got[0] != h || got[3] != h || got[1] != 0 || got[2] != 0 {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// AntiJoin returns the points of t farther than r from every point of u,
+// in no particular order.  These are the gaps in u's coverage of t.
+func (t KdTree) AntiJoin(u KdTree, r float64) (pts []Point) {
+	t.AntiJoinFunc(u, r, func(p Point) bool {
+		pts = append(pts, p)
+		return true
+	})
+	return
+}
+
+// AntiJoinFunc calls f for each point of t farther than r from every
+// point of u, stopping early if f returns false.
+//
+// The trees are traversed together.  Each subtree of t carries the
+// subtrees of u near enough to it to matter, splitting the larger as the
+// traversal descends.  A subtree of t is pruned when some point of u is
+// within r of all of its box, and reported whole when no subtree of u
+// comes within r of its box.
+func (t KdTree) AntiJoinFunc(u KdTree, r float64, f func(Point) bool) {
+	if t.n == nil {
+		return
+	}
+	r2 := r * r
+	s := u.NewSearcher()
+	s.limited, s.limit = true, r2
+	type cand struct {
+		kd  *kdNode
+		box HyperRect // cell or tight bounds of kd
+	}
+	var cands []cand
+	if u.n != nil {
+		cands = []cand{{u.n, u.Bounds.Copy()}}
+	}
+	st := newCellStack(t, cands)
+	for {
+		kd, cands, ok := st.pop()
+		if !ok {
+			return
+		}
+		if t.excludes(kd) {
+			continue
+		}
+		box := st.cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		covered := false
+		var near []cand
+		for len(cands) > 0 {
+			c := cands[len(cands)-1]
+			cands = cands[:len(cands)-1]
+			c.kd.force()
+			if u.excludes(c.kd) {
+				continue
+			}
+			cb := c.box
+			if c.kd.bounds != nil {
+				cb = *c.kd.bounds
+			}
+			if boxSqd(box, cb) > r2 {
+				continue
+			}
+			if u.sees(c.kd) && box.farSqd(c.kd.domElt) <= r2 {
+				covered = true
+				break
+			}
+			if c.kd.size <= kd.size {
+				near = append(near, c)
+				continue
+			}
+			// split the larger subtree, whose point is tested no
+			// further for coverage.
+			sp, pivot := c.kd.split, c.kd.domElt[c.kd.split]
+			if c.kd.left != nil {
+				b := c.box.Copy()
+				b.Max[sp] = pivot
+				cands = append(cands, cand{c.kd.left, b})
+			}
+			if c.kd.right != nil {
+				b := c.box.Copy()
+				b.Min[sp] = pivot
+				cands = append(cands, cand{c.kd.right, b})
+			}
+		}
+		switch {
+		case covered:
+			continue
+		case len(near) == 0:
+			if !t.yieldAll(kd, func(n *kdNode) bool { return f(n.domElt) }) {
+				return
+			}
+			continue
+		}
+		if t.sees(kd) {
+			s.search(kd.domElt, 1)
+			if len(s.h.e) == 0 && !f(kd.domElt) {
+				return
+			}
+		}
+		// each child splits candidates in place, so needs its own list.
+		st.push(kd, kd.right, near)
+		st.push(kd, kd.left, append([]cand(nil), near...))
+	}
+}
+
+// boxSqd returns the square of the least distance between points of a
+// and b, zero if they intersect.
+func boxSqd(a, b HyperRect) float64 {
+	sum := 0.
+	for i := range a.Min {
+		var d float64
+		switch {
+		case b.Max[i] < a.Min[i]:
+			d = a.Min[i] - b.Max[i]
+		case a.Max[i] < b.Min[i]:
+			d = b.Min[i] - a.Max[i]
+		}
+		sum += d * d
+	}
+	return sum
+}
@@ -0,0 +1,47 @@
+package kdtree
+
+import "testing"
+
+func TestAntiJoin(t *testing.T) {
+	a := randomPts(2, 2000)
+	b := randomPts(2, 300)
+	ta := New(append([]Point{}, a...), HyperRect{Point{0, 0}, Point{1, 1}})
+	tb := New(append([]Point{}, b...), HyperRect{Point{0, 0}, Point{1, 1}})
+	ta.Brute, tb.Brute = false, false
+	for _, r := range []float64{.01, .04, .1} {
+		want := 0
+		for _, p := range a {
+			far := true
+			for _, q := range b {
+				if p.Sqd(q) <= r*r {
+					far = false
+					break
+				}
+			}
+			if far {
+				want++
+			}
+		}
+		got := ta.AntiJoin(tb, r)
+		if len(got) != want {
+			t.Fatal("r", r, "found", len(got), "points, expected", want)
+		}
+	}
+	if got := ta.AntiJoin(KdTree{}, .1); len(got) != len(a) {
+		t.Error("anti-join with empty tree found", len(got))
+	}
+}
+
+func TestAntiJoinDeep(t *testing.T) {
+	kd, pts := deepTree(3000)
+	u := New([]Point{{0, 0}}, HyperRect{Point{0, 0}, Point{1, 1}})
+	want := 0
+	for _, p := range pts {
+		if p.Sqd(Point{0, 0}) > .5*.5 {
+			want++
+		}
+	}
+	if got := len(kd.AntiJoin(u, .5)); got != want {
+		t.Fatal("found", got, "points, expected", want)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// Assign finds for each point of t the nearest of targets, a discrete
+// Voronoi assignment.  The result is indexed by point index, as reported
+// in Neighbor.Index, and holds the index in targets of the nearest
+// target, or -1 for indexes of points no longer in t.  Of targets at
+// equal distances, the first is chosen.
+func (t KdTree) Assign(targets []Point) []int {
+	a := make([]int, t.next)
+	for i := range a {
+		a[i] = -1
+	}
+	t.AssignFunc(targets, func(n Neighbor, target int) bool {
+		a[n.Index] = target
+		return true
+	})
+	return a
+}
+
+// AssignFunc calls f for each point of t with the index in targets of the
+// nearest target, stopping early if f returns false.  n.Sqd is the square
+// of the distance to the target.
+//
+// The tree is traversed once for all targets.  At each subtree, targets
+// that cannot be nearest to any point of the subtree's box are dropped, so
+// the work per point falls as the traversal descends.
+func (t KdTree) AssignFunc(targets []Point, f func(n Neighbor, target int) bool) {
+	if t.n == nil || len(targets) == 0 {
+		return
+	}
+	all := make([]int, len(targets))
+	for i := range all {
+		all[i] = i
+	}
+	c := newCellStack(t, all)
+	for {
+		kd, cand, ok := c.pop()
+		if !ok {
+			return
+		}
+		if t.excludes(kd) {
+			continue
+		}
+		box := c.cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		// drop candidates farther from all of box than the least
+		// distance within which some candidate covers all of box.
+		if len(cand) > 1 {
+			lim := math.Inf(1)
+			for _, i := range cand {
+				lim = math.Min(lim, box.farSqd(targets[i]))
+			}
+			keep := make([]int, 0, len(cand))
+			for _, i := range cand {
+				if box.Sqd(targets[i]) <= lim {
+					keep = append(keep, i)
+				}
+			}
+			cand = keep
+		}
+		if t.sees(kd) {
+			best, bestSqd := -1, math.Inf(1)
+			for _, i := range cand {
+				if d := kd.domElt.Sqd(targets[i]); d < bestSqd ||
+					d == bestSqd && i < best {
+					best, bestSqd = i, d
+				}
+			}
+			if !f(kd.neighbor(bestSqd), best) {
+				return
+			}
+		}
+		c.push(kd, kd.right, cand)
+		c.push(kd, kd.left, cand)
+	}
+}
+
+// farSqd returns the square of the distance from p to the farthest point
+// of hr.
+func (hr HyperRect) farSqd(p Point) float64 {
+	sum := 0.
+	for i, c := range p {
+		d := math.Max(math.Abs(c-hr.Min[i]), math.Abs(c-hr.Max[i]))
+		sum += d * d
+	}
+	return sum
+}
@@ -0,0 +1,43 @@
+package kdtree
+
+import "testing"
+
+func TestAssign(t *testing.T) {
+	pts := randomPts(2, 2000)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Tighten()
+	targets := randomPts(2, 12)
+	kd.Remove(pts[5])
+	a := kd.Assign(targets)
+	for i, p := range pts {
+		if i == 5 {
+			if a[i] != -1 {
+				t.Error("removed point assigned", a[i])
+			}
+			continue
+		}
+		best := 0
+		for j, q := range targets {
+			if p.Sqd(q) < p.Sqd(targets[best]) {
+				best = j
+			}
+		}
+		if a[i] != best {
+			t.Fatal("point", i, "assigned", a[i], "expected", best)
+		}
+	}
+}
+
+func TestAssignDeep(t *testing.T) {
+	kd, pts := deepTree(3000)
+	a := kd.Assign([]Point{{0, 0}, {1, 1}})
+	for i, p := range pts {
+		want := 0
+		if p.Sqd(Point{1, 1}) < p.Sqd(Point{0, 0}) {
+			want = 1
+		}
+		if a[i] != want {
+			t.Fatal("point", i, "assigned", a[i], "expected", want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AtomicTree publishes versions of a tree for read-mostly concurrent
+// use.  Readers get the current version with Load, which never takes a
+// lock and never waits.  Writers change a private copy with Update,
+// which then publishes it with an atomic pointer swap.  A version once
+// published is never changed, so a reader may go on using the version
+// it loaded while newer ones are published.
+//
+// The zero value holds an empty tree.
+type AtomicTree struct {
+	p  atomic.Pointer[KdTree]
+	mu sync.Mutex // serializes writers
+
+	// during a RebuildAsync, the updates to forward to the rebuilt tree,
+	// and the channel to close when it is published.
+	log  []func(*KdTree)
+	done chan struct{}
+}
+
+// NewAtomicTree returns an AtomicTree publishing t.  t must not be used
+// afterward except through the AtomicTree.
+func NewAtomicTree(t KdTree) *AtomicTree {
+	a := &AtomicTree{}
+	a.p.Store(&t)
+	return a
+}
+
+// Load returns the current version.  It must not be changed.
+func (a *AtomicTree) Load() KdTree {
+	if t := a.p.Load(); t != nil {
+		return *t
+	}
+	return KdTree{}
+}
+
+// Store publishes t, such as a tree built apart, as the current version.
+// t must not be used afterward except through the AtomicTree.  A rebuild
+// in progress is abandoned, as it is of a version t replaces.
+func (a *AtomicTree) Store(t KdTree) {
+	a.mu.Lock()
+	a.p.Store(&t)
+	a.log, a.done = nil, nil
+	a.mu.Unlock()
+}
+
+// Update calls f with a copy of the current version, as by Snapshot, and
+// publishes the result.  Writers are serialized, so each Update sees the
+// changes of those before it.
+//
+// The copy costs a node per point, so changes should be batched into few
+// calls of Update in preference to many.
+func (a *AtomicTree) Update(f func(t *KdTree)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t := a.Load().Snapshot()
+	f(&t)
+	a.p.Store(&t)
+	if a.done != nil {
+		a.log = append(a.log, f)
+	}
+}
+
+// RebuildAsync rebuilds the current version in a new goroutine, balanced
+// and without tombstones as by Compact, and publishes the result when
+// ready.  Readers and writers go on meanwhile with the current versions.
+// Updates made during the rebuild are applied again to the rebuilt tree
+// before it is published, so f passed to Update must give the same
+// result when repeated on the rebuilt tree, as inserts and removals do.
+//
+// The returned channel is closed when the rebuilt tree is published, or
+// when the rebuild is abandoned because Store replaced the tree.  If a
+// rebuild is already in progress, its channel is returned.
+func (a *AtomicTree) RebuildAsync() <-chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.done != nil {
+		return a.done
+	}
+	done := make(chan struct{})
+	a.done = done
+	v := a.Load()
+	go func() {
+		t := v.Snapshot()
+		t.Compact()
+		a.mu.Lock()
+		// a.done is no longer done if Store superseded the rebuild.
+		if a.done == done {
+			for _, f := range a.log {
+				f(&t)
+			}
+			a.p.Store(&t)
+			a.log, a.done = nil, nil
+		}
+		a.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
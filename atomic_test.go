@@ -0,0 +1,86 @@
+package kdtree
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAtomicTree(t *testing.T) {
+	pts := randomPts(2, 1000)
+	a := NewAtomicTree(NewWith(append([]Point{}, pts[:500]...)))
+	old := a.Load()
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// every version is a consistent tree
+				v := a.Load()
+				if _, _, nv := v.Nearest(randomPt(2)); nv == 0 {
+					t.Error("empty version")
+					return
+				}
+			}
+		}()
+	}
+	for i := 500; i < 1000; i += 50 {
+		a.Update(func(t *KdTree) { t.InsertAll(pts[i : i+50]) })
+	}
+	close(stop)
+	wg.Wait()
+	checkNearest(t, a.Load(), 1000)
+	checkNearest(t, old, 500)
+}
+
+func TestRebuildAsync(t *testing.T) {
+	pts := randomPts(2, 2000)
+	// an unbalanced tree with tombstones
+	kd := NewWith(append([]Point{}, pts[:100]...))
+	kd.Alpha, kd.MaxDead = 1, 1
+	kd.InsertAll(pts[100:1000])
+	for _, p := range pts[:50] {
+		kd.Remove(p)
+	}
+	a := NewAtomicTree(kd)
+	done := a.RebuildAsync()
+	if again := a.RebuildAsync(); again != done {
+		t.Fatal("second rebuild started")
+	}
+	// updates during the rebuild are forwarded
+	for i := 1000; i < 2000; i += 100 {
+		a.Update(func(t *KdTree) { t.InsertAll(pts[i : i+100]) })
+	}
+	<-done
+	v := a.Load()
+	if v.dead != 0 {
+		t.Fatal(v.dead, "tombstones after rebuild")
+	}
+	checkNearest(t, v, 1950)
+	checkSizes(t, v.n)
+}
+
+func TestRebuildAsyncStore(t *testing.T) {
+	pts := randomPts(2, 20000)
+	a := NewAtomicTree(NewWith(append([]Point{}, pts...)))
+	done := a.RebuildAsync()
+	// a tree stored during the rebuild is not replaced by it, and updates
+	// after the Store apply to the stored tree.
+	a.Store(NewWith(append([]Point{}, pts[:10]...)))
+	a.Update(func(t *KdTree) { t.InsertAll(pts[10:20]) })
+	<-done
+	if n := a.Load().n.size; n != 20 {
+		t.Fatal(n, "points after Store")
+	}
+	// a new rebuild may start, and is of the stored tree
+	<-a.RebuildAsync()
+	if n := a.Load().n.size; n != 20 {
+		t.Fatal(n, "points after rebuild")
+	}
+}
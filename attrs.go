@@ -0,0 +1,198 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// AttrFunc extracts a numeric attribute, such as a timestamp or category
+// number, from the data associated with a point.
+type AttrFunc func(data interface{}) float64
+
+// AttrRange is a predicate on attribute Attr, the index of an AttrFunc
+// passed to IndexAttrs, true for values in [Min, Max].
+type AttrRange struct {
+	Attr     int
+	Min, Max float64
+}
+
+// IndexAttrs registers attrs and stores for each subtree the least and
+// greatest value of each attribute over its points, so that queries with
+// attribute predicates, such as InRangeWhere and KNearestWhere, can skip
+// subtrees holding no point that satisfies them.
+//
+// The ranges are kept up to date as points are added and subtrees are
+// rebuilt.  As with Tighten, points deleted leave ranges valid but
+// possibly loose.  The cost is memory for two values per attribute per
+// interior node.  Calling IndexAttrs with no attributes drops the index.
+// It also drops the attributes indexed by IndexTime and IndexRadius.
+func (t *KdTree) IndexAttrs(attrs ...AttrFunc) {
+	t.timeAttr, t.radiusAttr = 0, 0
+	t.timeOf = nil
+	if len(attrs) == 0 {
+		t.attrs = nil
+	} else {
+		t.attrs = attrs
+	}
+	if t.n != nil {
+		indexAttrs(t.n, t.attrs)
+	}
+}
+
+// indexAttr indexes a, replacing attribute i-1 if i is not zero, and
+// returns the index of a plus one.
+func (t *KdTree) indexAttr(i int, a AttrFunc) int {
+	attrs := append([]AttrFunc(nil), t.attrs...)
+	if i == 0 {
+		attrs = append(attrs, a)
+		i = len(attrs)
+	} else {
+		attrs[i-1] = a
+	}
+	time, radius, timeOf := t.timeAttr, t.radiusAttr, t.timeOf
+	t.IndexAttrs(attrs...)
+	t.timeAttr, t.radiusAttr, t.timeOf = time, radius, timeOf
+	return i
+}
+
+// WithAttrs indexes attributes as IndexAttrs.
+func WithAttrs(attrs ...AttrFunc) Option {
+	return func(o *options) { o.attrs = attrs }
+}
+
+// indexAttrs sets attrs for interior nodes of the subtree at kd and
+// returns the ranges of the subtree, or clears them if attrs is nil.
+func indexAttrs(kd *kdNode, attrs []AttrFunc) []float64 {
+	kd.force()
+	if attrs == nil {
+		kd.attrs = nil
+		for _, c := range []*kdNode{kd.left, kd.right} {
+			if c != nil {
+				indexAttrs(c, nil)
+			}
+		}
+		return nil
+	}
+	r := make([]float64, 2*len(attrs))
+	for i, a := range attrs {
+		v := a(kd.rangeElt)
+		r[2*i], r[2*i+1] = v, v
+	}
+	if kd.left == nil && kd.right == nil {
+		kd.attrs = nil
+		return r
+	}
+	for _, c := range []*kdNode{kd.left, kd.right} {
+		if c != nil {
+			cr := indexAttrs(c, attrs)
+			for i := 0; i < len(r); i += 2 {
+				r[i] = math.Min(r[i], cr[i])
+				r[i+1] = math.Max(r[i+1], cr[i+1])
+			}
+		}
+	}
+	kd.attrs = r
+	return r
+}
+
+// extendAttrs extends the ranges of kd to include the attributes of data.
+func (kd *kdNode) extendAttrs(attrs []AttrFunc, data interface{}) {
+	if kd.attrs == nil {
+		kd.attrs = make([]float64, 2*len(attrs))
+		for i, a := range attrs {
+			v := a(kd.rangeElt)
+			kd.attrs[2*i], kd.attrs[2*i+1] = v, v
+		}
+	}
+	for i, a := range attrs {
+		v := a(data)
+		kd.attrs[2*i] = math.Min(kd.attrs[2*i], v)
+		kd.attrs[2*i+1] = math.Max(kd.attrs[2*i+1], v)
+	}
+}
+
+// mayMatch reports whether the subtree at kd may hold a point satisfying
+// all of where, by its attribute ranges.
+func (kd *kdNode) mayMatch(where []AttrRange) bool {
+	if kd.attrs == nil {
+		return true
+	}
+	for _, w := range where {
+		if kd.attrs[2*w.Attr+1] < w.Min || kd.attrs[2*w.Attr] > w.Max {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether data satisfies all of where, and the time
+// window of t, if any.
+func (t KdTree) matches(data interface{}, where []AttrRange) bool {
+	if t.window != nil && !t.window.contains(t.timeOf(data)) {
+		return false
+	}
+	for _, w := range where {
+		if v := t.attrs[w.Attr](data); v < w.Min || v > w.Max {
+			return false
+		}
+	}
+	return true
+}
+
+// InRangeWhere returns the points of t within hr whose data satisfies all
+// of where, in no particular order.  t must have attributes indexed with
+// IndexAttrs.
+func (t KdTree) InRangeWhere(hr HyperRect, where []AttrRange) (pts []Point) {
+	t.rangeSearchWhere(hr, hr.Contains, where, func(kd *kdNode) bool {
+		if t.matches(kd.rangeElt, where) {
+			pts = append(pts, kd.domElt)
+		}
+		return true
+	})
+	return
+}
+
+// KNearestWhere returns the k nearest neighbors of p whose data satisfies
+// all of where, nearest first.  t must have attributes indexed with
+// IndexAttrs.
+//
+// Subtrees holding no satisfying point are skipped, so finding a few
+// matches among many nearer points that fail is much cheaper than
+// filtering the results of a larger KNearest.
+func (t KdTree) KNearestWhere(p Point, k int, where []AttrRange) Neighbors {
+	s := t.NewSearcher()
+	s.where = append(s.where[:len(s.where):len(s.where)], where...)
+	s.search(p, k)
+	n := Neighbors(s.h.e)
+	n.Sort()
+	return n
+}
+
+// Where returns a view of t in which queries see only points whose data
+// satisfies all of where, as well as any restriction of t itself.  t
+// must have attributes indexed with IndexAttrs.
+//
+// The view shares the nodes of t.  All queries of the view, from nearest
+// neighbor and range queries to All, Visit, and the aggregates, honor
+// the restriction, skipping subtrees that hold no satisfying point.
+// Changes such as Insert and Delete are to the tree, not the view.
+func (t KdTree) Where(where ...AttrRange) KdTree {
+	t.filter = append(t.filter[:len(t.filter):len(t.filter)], where...)
+	return t
+}
+
+// excludes reports whether the view of t, restricted by Where or
+// InCells, excludes every point of the subtree at kd, so that traversals
+// may skip it.
+func (t KdTree) excludes(kd *kdNode) bool {
+	return t.filter != nil && !kd.mayMatch(t.filter) ||
+		t.cells != nil && !t.cellsMayMatch(kd)
+}
+
+// sees reports whether the point of kd is live and in the view of t,
+// including its time window, if any.
+func (t KdTree) sees(kd *kdNode) bool {
+	return !kd.deleted &&
+		(t.filter == nil && t.window == nil || t.matches(kd.rangeElt, t.filter)) &&
+		(t.cells == nil || t.inCells(kd.rangeElt))
+}
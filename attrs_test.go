@@ -0,0 +1,302 @@
+package kdtree
+
+import (
+	"slices"
+	"sort"
+	"testing"
+)
+
+type record struct {
+	time     float64
+	category int
+}
+
+func TestAttrs(t *testing.T) {
+	pts := randomPts(2, 3000)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = record{float64(i), i % 7}
+	}
+	attrs := []AttrFunc{
+		func(d interface{}) float64 { return d.(record).time },
+		func(d interface{}) float64 { return float64(d.(record).category) },
+	}
+	kd := NewWith(append([]Point{}, pts...), WithData(data), WithAttrs(attrs...),
+		WithBounds(HyperRect{Point{0, 0}, Point{1, 1}}))
+	kd.Brute = false
+	// insertions after indexing must be covered
+	for i := 0; i < 200; i++ {
+		p := randomPt(2)
+		pts = append(pts, p)
+		r := record{float64(len(pts) - 1), len(pts) % 7}
+		data = append(data, r)
+		kd.InsertWithData(p, r)
+	}
+	where := []AttrRange{{0, 2900, 3300}, {1, 3, 3}}
+	ok := func(i int) bool {
+		r := data[i].(record)
+		return r.time >= 2900 && r.category == 3
+	}
+	p := randomPt(2)
+	got := kd.KNearestWhere(p, 5, where)
+	var want []float64
+	for i, q := range pts {
+		if ok(i) {
+			want = append(want, q.Sqd(p))
+		}
+	}
+	sort.Float64s(want)
+	if len(got) != 5 {
+		t.Fatal("got", len(got), "results")
+	}
+	for i, n := range got {
+		if !ok(n.Index) || n.Sqd != want[i] {
+			t.Fatal("result", i, n, "expected distance^2", want[i])
+		}
+	}
+	hr := HyperRect{Point{.2, .2}, Point{.8, .8}}
+	c := 0
+	for i, q := range pts {
+		if ok(i) && hr.Contains(q) {
+			c++
+		}
+	}
+	if r := kd.InRangeWhere(hr, where); len(r) != c {
+		t.Error("InRangeWhere found", len(r), "expected", c)
+	}
+	kd.Rebalance()
+	if r := kd.InRangeWhere(hr, where); len(r) != c {
+		t.Error("after Rebalance, InRangeWhere found", len(r), "expected", c)
+	}
+}
+
+// viewQueries are queries of each family, each returning what it finds
+// as points, for checking that views restrict them.  pts are the points
+// of kd by index.
+var viewQueries = []struct {
+	name  string
+	query func(kd KdTree, pts []Point) []Point
+}{
+	{"InHalfSpace", func(kd KdTree, _ []Point) []Point {
+		return kd.InHalfSpace(HalfSpace{Point{1, 1}, 1})
+	}},
+	{"InPolytope", func(kd KdTree, _ []Point) []Point {
+		return kd.InPolytope(ConvexPolygon([]Point{{.2, .2}, {.8, .3}, {.5, .9}}))
+	}},
+	{"InAnnulus", func(kd KdTree, _ []Point) []Point {
+		return kd.InAnnulus(Point{.5, .5}, .1, .4)
+	}},
+	{"InCorridor", func(kd KdTree, _ []Point) []Point {
+		return neighborPoints(kd.InCorridor([]Point{{.1, .1}, {.9, .5}}, .1))
+	}},
+	{"Swept", func(kd KdTree, _ []Point) (r []Point) {
+		for _, c := range kd.Swept(Point{.1, .9}, Point{.9, .1}, .1) {
+			r = append(r, c.Point)
+		}
+		return
+	}},
+	{"NearestSeq", func(kd KdTree, _ []Point) (r []Point) {
+		for n := range kd.NearestSeq(Point{.5, .5}) {
+			if r = append(r, n.Point); len(r) == 30 {
+				break
+			}
+		}
+		return
+	}},
+	{"FarthestSeq", func(kd KdTree, _ []Point) (r []Point) {
+		for n := range kd.FarthestSeq(Point{.5, .5}) {
+			if r = append(r, n.Point); len(r) == 30 {
+				break
+			}
+		}
+		return
+	}},
+	{"KNearestPage", func(kd KdTree, _ []Point) []Point {
+		return neighborPoints(kd.KNearestPage(Point{.3, .6}, 10, 10))
+	}},
+	{"All", func(kd KdTree, _ []Point) []Point {
+		return slices.Collect(kd.All())
+	}},
+	{"Visit", func(kd KdTree, _ []Point) (r []Point) {
+		kd.Visit(func(HyperRect) bool { return true }, func(p Point) bool {
+			r = append(r, p)
+			return true
+		})
+		return
+	}},
+	{"Contains", func(kd KdTree, _ []Point) (r []Point) {
+		for _, p := range viewProbes {
+			if kd.Contains(p) || kd.Count(p) > 0 {
+				r = append(r, p)
+			}
+		}
+		return
+	}},
+	{"SpheresContaining", func(kd KdTree, _ []Point) []Point {
+		return neighborPoints(kd.SpheresContaining(Point{.5, .5}))
+	}},
+	{"SpheresIntersecting", func(kd KdTree, _ []Point) []Point {
+		return neighborPoints(kd.SpheresIntersecting(HyperRect{Point{.1, .1}, Point{.2, .3}}))
+	}},
+	{"NearestWeighted", func(kd KdTree, _ []Point) []Point {
+		n, _, _ := kd.NearestWeighted(Point{.5, .5},
+			func(n Neighbor) float64 { return 1 + n.Point[0] }, 2)
+		return []Point{n.Point}
+	}},
+	{"AntiJoin", func(kd KdTree, _ []Point) []Point {
+		u := New(append([]Point{}, viewProbes...), HyperRect{Point{0, 0}, Point{1, 1}})
+		return kd.AntiJoin(u, .1)
+	}},
+	{"Assign", func(kd KdTree, _ []Point) (r []Point) {
+		kd.AssignFunc(viewProbes[:5], func(n Neighbor, target int) bool {
+			r = append(r, append(Point{float64(target)}, n.Point...))
+			return true
+		})
+		return
+	}},
+	{"FarthestPointSample", func(kd KdTree, _ []Point) []Point {
+		return neighborPoints(kd.FarthestPointSample(20, Point{.5, .5}))
+	}},
+	{"DownsampleMedoid", func(kd KdTree, _ []Point) []Point {
+		return neighborPoints(kd.DownsampleMedoid(.25))
+	}},
+	{"KNearestMetric", func(kd KdTree, _ []Point) []Point {
+		return neighborPoints(kd.KNearestMetric(Point{.5, .5}, 10, Manhattan{}))
+	}},
+	{"KNearestAny", func(kd KdTree, _ []Point) []Point {
+		return neighborPoints(kd.KNearestAny([]Point{{.2, .2}, {.8, .8}}, 10))
+	}},
+	{"TraceKNearest", func(kd KdTree, _ []Point) []Point {
+		return neighborPoints(kd.TraceKNearest(Point{.5, .5}, 10).Result)
+	}},
+	{"TopK", func(kd KdTree, _ []Point) []Point {
+		n, _ := kd.TopK(Point{.5, .5}, 10, func(n Neighbor) float64 {
+			return n.Sqd + n.Point[0]
+		}, func(_ HyperRect, minSqd float64) float64 { return minSqd })
+		return neighborPoints(n)
+	}},
+	{"Skyline", func(kd KdTree, _ []Point) []Point {
+		return kd.Skyline([]int{0, 1})
+	}},
+	{"ReverseNearest", func(kd KdTree, _ []Point) []Point {
+		return neighborPoints(kd.ReverseNearest(Point{.5, .5}))
+	}},
+	{"Quantiles", func(kd KdTree, _ []Point) []Point {
+		return kd.Quantiles(HyperRect{Point{.1, .1}, Point{.9, .9}}, .25, .5, .75)
+	}},
+	{"Histogram", func(kd KdTree, _ []Point) []Point {
+		return []Point{intPoint(kd.Histogram(HyperRect{Point{0, 0}, Point{1, 1}}, []int{3, 3}))}
+	}},
+	{"NearestDistanceHist", func(kd KdTree, _ []Point) []Point {
+		return []Point{intPoint(kd.NearestDistanceHist([]float64{0, .01, .02, .05, 1}))}
+	}},
+	{"RipleyK", func(kd KdTree, _ []Point) []Point {
+		return []Point{kd.RipleyK([]float64{.02, .05})}
+	}},
+	{"Outliers", func(kd KdTree, _ []Point) []Point {
+		return neighborPoints(kd.Outliers(5, 1))
+	}},
+	{"EstimateNormals", func(kd KdTree, pts []Point) (r []Point) {
+		for i, n := range kd.EstimateNormals(5) {
+			if n != nil {
+				r = append(r, pts[i])
+			}
+		}
+		return
+	}},
+	{"KNNGraph", func(kd KdTree, pts []Point) []Point {
+		return edgePoints(kd.KNNGraph(3), pts)
+	}},
+	{"RadiusGraph", func(kd KdTree, pts []Point) []Point {
+		return edgePoints(kd.RadiusGraph(.03), pts)
+	}},
+	{"CollisionPairs", func(kd KdTree, pts []Point) (r []Point) {
+		for _, c := range kd.CollisionPairs(func(interface{}) float64 { return .01 }) {
+			r = append(r, pairPoint(pts[c[0]], pts[c[1]]))
+		}
+		return
+	}},
+}
+
+// viewProbes are fixed query points for viewQueries.
+var viewProbes = randomPts(2, 40)
+
+func neighborPoints(n Neighbors) []Point {
+	r := make([]Point, len(n))
+	for i, nb := range n {
+		r[i] = nb.Point
+	}
+	return r
+}
+
+func intPoint(c []int) Point {
+	p := make(Point, len(c))
+	for i, x := range c {
+		p[i] = float64(x)
+	}
+	return p
+}
+
+// pairPoint returns a point for the unordered pair of a and b.
+func pairPoint(a, b Point) Point {
+	if slices.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return append(append(Point{}, a...), b...)
+}
+
+func edgePoints(edges []Edge, pts []Point) []Point {
+	r := make([]Point, len(edges))
+	for i, e := range edges {
+		r[i] = pairPoint(pts[e.I], pts[e.J])
+	}
+	return r
+}
+
+// checkView checks that each of viewQueries finds in view what it finds
+// in a tree of just the points of pts for which in returns true, with
+// their data.  prepare indexes that tree as the tree of view was.
+func checkView(t *testing.T, view KdTree, pts []Point, data []interface{},
+	in func(i int) bool, prepare func(*KdTree)) {
+	var vp []Point
+	var vd []interface{}
+	for i, p := range pts {
+		if in(i) {
+			vp = append(vp, p)
+			vd = append(vd, data[i])
+		}
+	}
+	sub := NewWith(append([]Point{}, vp...), WithData(vd),
+		WithBounds(HyperRect{Point{0, 0}, Point{1, 1}}))
+	sub.Brute = false
+	prepare(&sub)
+	for _, q := range viewQueries {
+		got, want := q.query(view, pts), q.query(sub, vp)
+		slices.SortFunc(got, slices.Compare)
+		slices.SortFunc(want, slices.Compare)
+		if !slices.EqualFunc(got, want, equal) {
+			t.Errorf("%s: got %d results, expected %d", q.name, len(got), len(want))
+		}
+	}
+}
+
+func TestWhereQueries(t *testing.T) {
+	pts := randomPts(2, 2000)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = record{float64(i), i % 7}
+	}
+	prepare := func(kd *KdTree) {
+		kd.IndexAttrs(func(d interface{}) float64 { return d.(record).time },
+			func(d interface{}) float64 { return float64(d.(record).category) })
+		kd.IndexRadius(func(interface{}) float64 { return .2 })
+	}
+	kd := NewWith(append([]Point{}, pts...), WithData(data),
+		WithBounds(HyperRect{Point{0, 0}, Point{1, 1}}))
+	kd.Brute = false
+	prepare(&kd)
+	v := kd.Where(AttrRange{0, 500, 1500}, AttrRange{1, 2, 4})
+	checkView(t, v, pts, data, func(i int) bool {
+		return i >= 500 && i <= 1500 && i%7 >= 2 && i%7 <= 4
+	}, prepare)
+}
@@ -0,0 +1,77 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// DefaultAlpha is the balance factor used when KdTree.Alpha is zero.
+const DefaultAlpha = .75
+
+// Rebalance rebuilds t as New would construct it from the points
+// currently in the tree.
+func (t *KdTree) Rebalance() {
+	if t.n != nil {
+		t.rebuilt("rebalance", t.n.size)
+		t.n = t.rebuild(t.n, t.tight())
+	}
+}
+
+// balancePath rebuilds the highest unbalanced subtree on path, a list of
+// links from the root down.  In the manner of a scapegoat tree, the
+// amortized cost of these rebuilds is O(log n) per update.
+func (t *KdTree) balancePath(path []**kdNode) {
+	tight := t.tight()
+	for _, link := range path {
+		if kd := *link; t.unbalanced(kd) {
+			t.rebuilt("rebalance", kd.size)
+			*link = t.rebuild(kd, tight)
+			return
+		}
+	}
+}
+
+// unbalanced reports whether a child of kd holds more than the fraction
+// t.Alpha of the subtree's nodes, for a subtree small enough to rebuild
+// under t.MaxRebuild.
+func (t *KdTree) unbalanced(kd *kdNode) bool {
+	a := t.Alpha
+	if a == 0 {
+		a = DefaultAlpha
+	}
+	if a >= 1 || kd == nil || t.MaxRebuild > 0 && kd.size > t.MaxRebuild {
+		return false
+	}
+	lim := int(a * float64(kd.size))
+	return kd.left != nil && kd.left.size > lim ||
+		kd.right != nil && kd.right.size > lim
+}
+
+// rebuild returns a balanced subtree with the nodes of kd, splitting
+// first on the same dimension as kd.
+func (t *KdTree) rebuild(kd *kdNode, tight bool) *kdNode {
+	nodes := make([]*kdNode, 0, kd.size)
+	walk(kd, func(n *kdNode) { nodes = append(nodes, n) })
+	kd = nk2(nodes, kd.split, -1, t.Split)
+	t.refit(kd, tight)
+	return kd
+}
+
+// refit recomputes the tight bounds, if tight is set, and the attribute
+// ranges, label sets, cell ID ranges, and quantile samples, if indexed,
+// of a rebuilt subtree.
+func (t *KdTree) refit(kd *kdNode, tight bool) {
+	if tight {
+		tighten(kd)
+	}
+	if t.attrs != nil {
+		indexAttrs(kd, t.attrs)
+	}
+	if t.label != nil {
+		indexLabels(kd, t.label)
+	}
+	if t.cell != nil {
+		indexCells(kd, t.cell)
+	}
+	if t.sample > 0 {
+		indexSamples(kd, t.sample, nil)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// binaryVersion identifies the encoding written by KdTree.MarshalBinary.
+// Version 1, without Split and MaxRebuild, is still read.
+const binaryVersion = 2
+
+var errBinary = errors.New("kdtree: invalid binary encoding")
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p Point) MarshalBinary() ([]byte, error) {
+	return appendPoint(nil, p), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (p *Point) UnmarshalBinary(b []byte) error {
+	d := decoder{b: b}
+	*p = d.point()
+	return d.end()
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (hr HyperRect) MarshalBinary() ([]byte, error) {
+	return appendPoint(appendPoint(nil, hr.Min), hr.Max), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (hr *HyperRect) UnmarshalBinary(b []byte) error {
+	d := decoder{b: b}
+	hr.Min = d.point()
+	hr.Max = d.point()
+	return d.end()
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// The encoding holds the structure of the tree, its points and their
+// indexes, tombstones, Bounds, and the settings Brute, Alpha, MaxDead,
+// MaxRebuild, Split, and FixedBounds.  Tight bounding boxes and the count
+// of tombstones are recomputed when decoding.
+// Data associated with points, Metrics, and Tracer are not encoded.
+func (t KdTree) MarshalBinary() ([]byte, error) {
+	b := []byte{binaryVersion}
+	b = appendPoint(appendPoint(b, t.Bounds.Min), t.Bounds.Max)
+	var flags byte
+	if t.Brute {
+		flags |= 1
+	}
+	if t.FixedBounds {
+		flags |= 2
+	}
+	if t.tight() {
+		flags |= 4
+	}
+	b = append(b, flags)
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(t.Alpha))
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(t.MaxDead))
+	b = binary.AppendUvarint(b, uint64(t.MaxRebuild))
+	b = binary.AppendUvarint(b, uint64(t.Split))
+	b = binary.AppendUvarint(b, uint64(t.next))
+	return appendNode(b, t.n), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (t *KdTree) UnmarshalBinary(b []byte) error {
+	d := decoder{b: b}
+	version := d.byte()
+	if version != 1 && version != binaryVersion {
+		return errBinary
+	}
+	var n KdTree
+	n.Bounds.Min = d.point()
+	n.Bounds.Max = d.point()
+	flags := d.byte()
+	n.Brute = flags&1 != 0
+	n.FixedBounds = flags&2 != 0
+	n.Alpha = math.Float64frombits(d.uint64())
+	n.MaxDead = math.Float64frombits(d.uint64())
+	if version > 1 {
+		n.MaxRebuild = int(d.uvarint())
+		n.Split = SplitRule(d.uvarint())
+	}
+	n.next = int(d.uvarint())
+	if version == 1 {
+		d.uvarint() // count of tombstones, recounted below
+	}
+	n.n = d.node()
+	if err := d.end(); err != nil {
+		return err
+	}
+	var ok bool
+	if n.dead, ok = n.checkDecoded(); !ok {
+		return errBinary
+	}
+	if flags&4 != 0 {
+		n.Tighten()
+	}
+	n.gen = t.gen + 1
+	*t = n
+	return nil
+}
+
+// checkDecoded checks the settings and nodes of a decoded tree for
+// consistency, returning the number of tombstones.  Every point must have
+// the dimension of Bounds, or of the root if Bounds is empty, and an
+// index less than next.
+func (t KdTree) checkDecoded() (dead int, ok bool) {
+	if t.Split != SplitCycle && t.Split != SplitWidest ||
+		len(t.Bounds.Min) != len(t.Bounds.Max) {
+		return 0, false
+	}
+	if t.n == nil {
+		return 0, true
+	}
+	dim := len(t.Bounds.Min)
+	if dim == 0 {
+		dim = len(t.n.domElt)
+	}
+	for stack := []*kdNode{t.n}; len(stack) > 0; {
+		kd := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if len(kd.domElt) != dim || kd.index < 0 || kd.index >= t.next {
+			return 0, false
+		}
+		if kd.deleted {
+			dead++
+		}
+		if kd.left != nil {
+			stack = append(stack, kd.left)
+		}
+		if kd.right != nil {
+			stack = append(stack, kd.right)
+		}
+	}
+	return dead, true
+}
+
+func appendPoint(b []byte, p Point) []byte {
+	b = binary.AppendUvarint(b, uint64(len(p)))
+	for _, c := range p {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(c))
+	}
+	return b
+}
+
+// appendNode appends the subtree at kd in preorder.  Each node is a tag
+// byte, 0 for nil, 1 for a node, or 2 for a tombstone, followed for nodes
+// by the split, index, and point.
+func appendNode(b []byte, kd *kdNode) []byte {
+	if kd == nil {
+		return append(b, 0)
+	}
+	kd.force()
+	if kd.deleted {
+		b = append(b, 2)
+	} else {
+		b = append(b, 1)
+	}
+	b = binary.AppendUvarint(b, uint64(kd.split))
+	b = binary.AppendUvarint(b, uint64(kd.index))
+	b = appendPoint(b, kd.domElt)
+	b = appendNode(b, kd.left)
+	return appendNode(b, kd.right)
+}
+
+// decoder reads an encoding, recording the first error.  Once an error
+// occurs, reads return zero values.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) fail() {
+	d.err = errBinary
+	d.b = nil
+}
+
+func (d *decoder) end() error {
+	if d.err == nil && len(d.b) > 0 {
+		d.fail()
+	}
+	return d.err
+}
+
+func (d *decoder) byte() byte {
+	if len(d.b) < 1 {
+		d.fail()
+		return 0
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+func (d *decoder) uint64() uint64 {
+	if len(d.b) < 8 {
+		d.fail()
+		return 0
+	}
+	u := binary.LittleEndian.Uint64(d.b)
+	d.b = d.b[8:]
+	return u
+}
+
+func (d *decoder) uvarint() uint64 {
+	u, n := binary.Uvarint(d.b)
+	if n <= 0 || u > math.MaxInt32 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return u
+}
+
+func (d *decoder) point() Point {
+	n := d.uvarint()
+	if n > uint64(len(d.b)/8) {
+		d.fail()
+	}
+	if n == 0 || d.err != nil {
+		return nil
+	}
+	p := make(Point, n)
+	for i := range p {
+		p[i] = math.Float64frombits(d.uint64())
+	}
+	return p
+}
+
+func (d *decoder) node() *kdNode {
+	tag := d.byte()
+	if tag == 0 || d.err != nil {
+		return nil
+	}
+	if tag > 2 {
+		d.fail()
+		return nil
+	}
+	kd := &kdNode{}
+	kd.deleted = tag == 2
+	kd.split = int(d.uvarint())
+	kd.index = int(d.uvarint())
+	kd.domElt = d.point()
+	if d.err == nil && kd.split >= len(kd.domElt) {
+		d.fail()
+	}
+	kd.left = d.node()
+	kd.right = d.node()
+	kd.size = 1
+	if kd.left != nil {
+		kd.size += kd.left.size
+	}
+	if kd.right != nil {
+		kd.size += kd.right.size
+	}
+	return kd
+}
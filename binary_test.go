@@ -0,0 +1,102 @@
+package kdtree
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+func TestBinary(t *testing.T) {
+	pts := randomPts(3, 500)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	kd.Brute = false
+	kd.Alpha = .6
+	kd.MaxRebuild = 40
+	kd.Split = SplitWidest
+	kd.Tighten()
+	for _, p := range pts[:20] {
+		kd.Remove(p)
+	}
+	b, err := kd.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got KdTree
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got.Alpha != .6 || got.Brute || !got.tight() || got.dead != 20 ||
+		got.next != len(pts) || got.MaxRebuild != 40 ||
+		got.Split != SplitWidest {
+		t.Fatal("settings not restored")
+	}
+	checkSizes(t, got.n)
+	for _, p := range randomPts(3, 50) {
+		_, want, _ := kd.Nearest(p)
+		if _, d, _ := got.Nearest(p); d != want {
+			t.Fatal("Nearest", d, "expected", want)
+		}
+	}
+	for i := 0; i < len(b); i += 97 {
+		if err := new(KdTree).UnmarshalBinary(b[:i]); err == nil {
+			t.Fatal("no error for truncated encoding of", i, "bytes")
+		}
+	}
+}
+
+func TestGob(t *testing.T) {
+	kd := New(randomPts(2, 100), HyperRect{Point{0, 0}, Point{1, 1}})
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(kd); err != nil {
+		t.Fatal(err)
+	}
+	var got KdTree
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.n.size != 100 {
+		t.Fatal("decoded", got.n.size, "points")
+	}
+	hr := HyperRect{Point{1, 2}, Point{3, 4}}
+	b, _ := hr.MarshalBinary()
+	var hr2 HyperRect
+	if err := hr2.UnmarshalBinary(b); err != nil || hr2.Max[1] != 4 {
+		t.Fatal(hr2, err)
+	}
+}
+
+func TestBinaryInvalid(t *testing.T) {
+	enc := func(kd KdTree) []byte {
+		b, err := kd.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	valid := func() KdTree {
+		return New([]Point{{0, 0}, {1, 1}, {.5, .2}},
+			HyperRect{Point{0, 0}, Point{1, 1}})
+	}
+	kd := valid()
+	kd.n.left.index = kd.next // index not less than next
+	if new(KdTree).UnmarshalBinary(enc(kd)) == nil {
+		t.Error("no error for index out of range")
+	}
+	kd = valid()
+	kd.n.right.domElt = Point{1, 1, 1}
+	if new(KdTree).UnmarshalBinary(enc(kd)) == nil {
+		t.Error("no error for point of wrong dimension")
+	}
+	kd = valid()
+	kd.Split = 5
+	if new(KdTree).UnmarshalBinary(enc(kd)) == nil {
+		t.Error("no error for unknown split rule")
+	}
+	// the count of tombstones is taken from the nodes
+	kd = valid()
+	kd.n.deleted = true
+	var got KdTree
+	if err := got.UnmarshalBinary(enc(kd)); err != nil || got.dead != 1 {
+		t.Error(err, got.dead)
+	}
+}
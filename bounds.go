@@ -0,0 +1,38 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "fmt"
+
+// BoundsError is returned when a point outside the bounds of a tree with
+// FixedBounds set would be added to the tree.
+type BoundsError struct {
+	P      Point
+	Bounds HyperRect
+}
+
+func (e *BoundsError) Error() string {
+	return fmt.Sprintf("kdtree: point %v outside bounds %v", e.P, e.Bounds)
+}
+
+// grow grows t.Bounds as needed to contain p, or returns a *BoundsError if
+// p is outside and t.FixedBounds is set.
+//
+// The bounds are copied before being changed, as their slices may be
+// shared with the caller of New.
+func (t *KdTree) grow(p Point) error {
+	if t.Bounds.Min == nil {
+		t.Bounds = HyperRect{append(Point{}, p...), append(Point{}, p...)}
+		return nil
+	}
+	if t.Bounds.Contains(p) {
+		return nil
+	}
+	if t.FixedBounds {
+		return &BoundsError{p, t.Bounds}
+	}
+	t.Bounds = t.Bounds.Copy()
+	t.Bounds.extend(HyperRect{p, p})
+	return nil
+}
@@ -0,0 +1,38 @@
+package kdtree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGrowBounds(t *testing.T) {
+	hr := HyperRect{Point{0, 0}, Point{1, 1}}
+	kd := New(randomPts(2, 200), hr)
+	kd.Brute = false
+	far := Point{3, -2}
+	if err := kd.Insert(far); err != nil {
+		t.Fatal(err)
+	}
+	if hr.Max[0] != 1 || hr.Min[1] != 0 {
+		t.Error("Insert modified caller's bounds", hr)
+	}
+	if kd.Bounds.Max[0] != 3 || kd.Bounds.Min[1] != -2 {
+		t.Error("Expected bounds grown to contain", far, "found", kd.Bounds)
+	}
+	if nn, _, _ := kd.Nearest(Point{3.1, -2.1}); !equal(nn, far) {
+		t.Error("Expected nn", far, "found", nn)
+	}
+	kd.InsertAll([]Point{{-5, .5}, {.5, 7}})
+	checkNearest(t, kd, 203)
+
+	kd.FixedBounds = true
+	err := kd.Insert(Point{10, 10})
+	var be *BoundsError
+	if !errors.As(err, &be) || !equal(be.P, Point{10, 10}) {
+		t.Error("Expected *BoundsError, found", err)
+	}
+	if err = kd.InsertAll([]Point{{.5, .5}, {-10, 0}}); err == nil {
+		t.Error("Expected *BoundsError from InsertAll")
+	}
+	checkNearest(t, kd, 203)
+}
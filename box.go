@@ -0,0 +1,59 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "iter"
+
+// Box is a hyperrectangle whose faces may each be open, excluding points
+// lying on them.
+//
+// MinOpen[d] excludes points with coordinate d equal to Min[d], MaxOpen[d]
+// those equal to Max[d].  Nil slices leave all faces on that side closed,
+// so a Box with neither is the same as its HyperRect.
+type Box struct {
+	HyperRect
+	MinOpen, MaxOpen []bool
+}
+
+// HalfOpen returns the box [hr.Min, hr.Max) closed at the minimum and open
+// at the maximum in every dimension.  Boxes that tile space this way
+// each hold a point on a shared face exactly once.
+func HalfOpen(hr HyperRect) Box {
+	open := make([]bool, len(hr.Max))
+	for i := range open {
+		open[i] = true
+	}
+	return Box{HyperRect: hr, MaxOpen: open}
+}
+
+// Contains reports whether p lies within b.
+func (b Box) Contains(p Point) bool {
+	for d, c := range p {
+		if c < b.Min[d] || c > b.Max[d] ||
+			c == b.Min[d] && b.MinOpen != nil && b.MinOpen[d] ||
+			c == b.Max[d] && b.MaxOpen != nil && b.MaxOpen[d] {
+			return false
+		}
+	}
+	return true
+}
+
+// InBox returns the points of t within b, in no particular order.
+func (t KdTree) InBox(b Box) []Point {
+	return collect(t.InBoxSeq(b))
+}
+
+// InBoxSeq returns an iterator over the points of t within b, as
+// InRangeSeq.
+func (t KdTree) InBoxSeq(b Box) iter.Seq[Point] {
+	return func(yield func(Point) bool) { t.InBoxFunc(b, yield) }
+}
+
+// InBoxFunc calls f for each point of t within b, stopping early if f
+// returns false.
+func (t KdTree) InBoxFunc(b Box, f func(Point) bool) {
+	t.rangeSearch(b.HyperRect, b.Contains, func(kd *kdNode) bool {
+		return f(kd.domElt)
+	})
+}
@@ -0,0 +1,42 @@
+package kdtree
+
+import "testing"
+
+func TestInBox(t *testing.T) {
+	// a grid with points on the tile boundaries
+	var pts []Point
+	for x := 0; x <= 10; x++ {
+		for y := 0; y <= 10; y++ {
+			pts = append(pts, Point{float64(x) / 10, float64(y) / 10})
+		}
+	}
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	// tile [0,1.1)^2 into 4 half open boxes
+	n := 0
+	for _, x := range []float64{0, .5} {
+		for _, y := range []float64{0, .5} {
+			b := HalfOpen(HyperRect{Point{x, y}, Point{x + .5, y + .5}})
+			if x == .5 {
+				b.Max[0] = 1.1
+			}
+			if y == .5 {
+				b.Max[1] = 1.1
+			}
+			n += len(kd.InBox(b))
+		}
+	}
+	if n != len(pts) {
+		t.Error("tiles hold", n, "points, expected", len(pts))
+	}
+	// closed box equals InRange
+	hr := HyperRect{Point{.2, .3}, Point{.6, .5}}
+	if a, b := len(kd.InBox(Box{HyperRect: hr})), len(kd.InRange(hr)); a != b {
+		t.Error("closed box", a, "InRange", b)
+	}
+	// open on all faces excludes the boundary
+	open := []bool{true, true}
+	if got := len(kd.InBox(Box{hr, open, open})); got != 3 {
+		t.Error("open box holds", got, "points, expected 3")
+	}
+}
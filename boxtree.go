@@ -0,0 +1,182 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"sort"
+)
+
+// Intersects reports whether hr and r share any point.  Boxes that only
+// touch at a boundary intersect.
+func (hr HyperRect) Intersects(r HyperRect) bool {
+	for dim, min := range hr.Min {
+		if min > r.Max[dim] || hr.Max[dim] < r.Min[dim] {
+			return false
+		}
+	}
+	return true
+}
+
+// BoxTree indexes axis-aligned boxes for intersection queries.
+//
+// It is a k-d tree over the centers of the boxes.  Each node also stores
+// the bounding box of all the boxes in its subtree, so that a query can
+// prune subtrees by their full extent rather than by their centers.
+// Queries return indexes into the slice of boxes passed to NewBoxTree.
+type BoxTree struct {
+	root *boxNode
+}
+
+type boxNode struct {
+	box         HyperRect
+	i           int
+	split       int
+	left, right *boxNode
+	bounds      HyperRect
+}
+
+// NewBoxTree builds a BoxTree of boxes.
+func NewBoxTree(boxes []HyperRect) *BoxTree {
+	nodes := make([]*boxNode, len(boxes))
+	for i, b := range boxes {
+		nodes[i] = &boxNode{box: b, i: i}
+	}
+	center := func(n *boxNode, s int) float64 {
+		return (n.box.Min[s] + n.box.Max[s]) / 2
+	}
+	var build func([]*boxNode, int) *boxNode
+	build = func(nodes []*boxNode, split int) *boxNode {
+		if len(nodes) == 0 {
+			return nil
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return center(nodes[i], split) < center(nodes[j], split)
+		})
+		m := len(nodes) / 2
+		n := nodes[m]
+		s2 := split + 1
+		if s2 == len(n.box.Min) {
+			s2 = 0
+		}
+		n.split = split
+		n.left = build(nodes[:m], s2)
+		n.right = build(nodes[m+1:], s2)
+		n.bounds = n.box.Copy()
+		for _, c := range []*boxNode{n.left, n.right} {
+			if c != nil {
+				n.bounds.extend(c.bounds)
+			}
+		}
+		return n
+	}
+	return &BoxTree{build(nodes, 0)}
+}
+
+// Intersecting returns the indexes of the boxes that intersect q.
+func (t *BoxTree) Intersecting(q HyperRect) (r []int) {
+	t.IntersectingFunc(q, func(i int) bool {
+		r = append(r, i)
+		return true
+	})
+	return
+}
+
+// IntersectingFunc calls f with the index of each box that intersects q,
+// stopping early if f returns false.
+func (t *BoxTree) IntersectingFunc(q HyperRect, f func(int) bool) {
+	t.search(q.Intersects, f)
+}
+
+// search calls f with the index of each box for which match returns
+// true, pruning subtrees whose bounds do not match.
+func (t *BoxTree) search(match func(HyperRect) bool, f func(int) bool) {
+	var search func(*boxNode) bool
+	search = func(n *boxNode) bool {
+		if n == nil || !match(n.bounds) {
+			return true
+		}
+		if match(n.box) && !f(n.i) {
+			return false
+		}
+		return search(n.left) && search(n.right)
+	}
+	search(t.root)
+}
+
+// Containing returns the indexes of the boxes that contain p.
+func (t *BoxTree) Containing(p Point) []int {
+	return t.Intersecting(HyperRect{p, p})
+}
+
+// Stabbing returns the indexes of the boxes that contain q in each of
+// the dimensions stab and are within distance r of q in the others.
+//
+// This serves points with extents.  Store each as a box with Min == Max
+// in its positional dimensions and the extent, such as a time interval,
+// in the others.  Then Stabbing(q, []int{2}, r), with a time in q[2], finds
+// the points within r of (q[0], q[1]) whose intervals contain that time.
+func (t *BoxTree) Stabbing(q Point, stab []int, r float64) (res []int) {
+	t.StabbingFunc(q, stab, r, func(i int) bool {
+		res = append(res, i)
+		return true
+	})
+	return
+}
+
+// StabbingFunc calls f with the index of each box found by Stabbing,
+// stopping early if f returns false.
+func (t *BoxTree) StabbingFunc(q Point, stab []int, r float64,
+	f func(int) bool) {
+	isStab := make([]bool, len(q))
+	for _, d := range stab {
+		isStab[d] = true
+	}
+	r2 := r * r
+	t.search(func(b HyperRect) bool {
+		var sum float64
+		for d, c := range q {
+			var g float64
+			if c < b.Min[d] {
+				g = b.Min[d] - c
+			} else if c > b.Max[d] {
+				g = c - b.Max[d]
+			}
+			if isStab[d] {
+				if g > 0 {
+					return false
+				}
+			} else {
+				sum += g * g
+			}
+		}
+		return sum <= r2
+	}, f)
+}
+
+// nearest returns the index of the box whose item is nearest p, where
+// sqd gives the squared distance from p to the item of box i.  Items
+// must lie within their boxes.  It returns -1 for an empty tree.
+func (t *BoxTree) nearest(p Point, sqd func(i int) float64) (int, float64) {
+	best, bestSqd := -1, math.Inf(1)
+	var search func(*boxNode)
+	search = func(n *boxNode) {
+		if n == nil || n.bounds.Sqd(p) >= bestSqd {
+			return
+		}
+		if n.box.Sqd(p) < bestSqd {
+			if d := sqd(n.i); d < bestSqd {
+				best, bestSqd = n.i, d
+			}
+		}
+		near, far := n.left, n.right
+		if far != nil && (near == nil || far.bounds.Sqd(p) < near.bounds.Sqd(p)) {
+			near, far = far, near
+		}
+		search(near)
+		search(far)
+	}
+	search(t.root)
+	return best, bestSqd
+}
@@ -0,0 +1,73 @@
+package kdtree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestBoxTree(t *testing.T) {
+	boxes := make([]HyperRect, 500)
+	for i := range boxes {
+		min := randomPt(2)
+		boxes[i] = HyperRect{min,
+			Point{min[0] + rand.Float64()*.1, min[1] + rand.Float64()*.1}}
+	}
+	bt := NewBoxTree(boxes)
+	for i := 0; i < 50; i++ {
+		min := randomPt(2)
+		q := HyperRect{min, Point{min[0] + .05, min[1] + .05}}
+		var want []int
+		for j, b := range boxes {
+			if b.Intersects(q) {
+				want = append(want, j)
+			}
+		}
+		got := bt.Intersecting(q)
+		sort.Ints(got)
+		if len(got) != len(want) {
+			t.Fatal("Expected", want, "found", got)
+		}
+		for j := range got {
+			if got[j] != want[j] {
+				t.Fatal("Expected", want, "found", got)
+			}
+		}
+	}
+	if got := bt.Containing(boxes[7].Max); len(got) == 0 {
+		t.Error("Expected a box containing its own corner")
+	}
+}
+
+// 2D points with time intervals
+func TestStabbing(t *testing.T) {
+	items := make([]HyperRect, 1000)
+	for i := range items {
+		p := randomPt(2)
+		t0 := rand.Float64() * 10
+		items[i] = HyperRect{Point{p[0], p[1], t0},
+			Point{p[0], p[1], t0 + rand.Float64()}}
+	}
+	bt := NewBoxTree(items)
+	for i := 0; i < 50; i++ {
+		q := Point{rand.Float64(), rand.Float64(), rand.Float64() * 10}
+		var want []int
+		for j, b := range items {
+			pos := Point{b.Min[0], b.Min[1]}
+			if b.Min[2] <= q[2] && q[2] <= b.Max[2] &&
+				pos.Sqd(Point{q[0], q[1]}) <= .04 {
+				want = append(want, j)
+			}
+		}
+		got := bt.Stabbing(q, []int{2}, .2)
+		sort.Ints(got)
+		if len(got) != len(want) {
+			t.Fatal("Expected", want, "found", got)
+		}
+		for j := range got {
+			if got[j] != want[j] {
+				t.Fatal("Expected", want, "found", got)
+			}
+		}
+	}
+}
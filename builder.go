@@ -0,0 +1,212 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// Builder collects points one at a time for construction of a KdTree.
+//
+// The zero value is an empty Builder ready to use.  A Builder must not be
+// used by more than one goroutine at a time, but goroutines may each add
+// points to their own shard obtained from Shard.
+type Builder struct {
+	pts    []Point
+	data   []interface{}
+	bounds HyperRect
+
+	mu     sync.Mutex
+	shards []*Builder
+}
+
+// Shard returns a new Builder whose points are included when b is built.
+//
+// Each producer of points can add to its own shard without locking, so
+// parallel parsers are not serialized through one Builder or channel.
+// Shard may be called concurrently.  Shards must not be added to once b's
+// Build has been called.  Points of b itself are numbered first, then
+// those of each shard in the order the shards were created.
+func (b *Builder) Shard() *Builder {
+	s := &Builder{}
+	b.mu.Lock()
+	b.shards = append(b.shards, s)
+	b.mu.Unlock()
+	return s
+}
+
+// merge moves the points of the shards of b into b.
+func (b *Builder) merge() {
+	b.mu.Lock()
+	shards := b.shards
+	b.shards = nil
+	b.mu.Unlock()
+	n := len(b.pts)
+	for _, s := range shards {
+		s.merge()
+		n += len(s.pts)
+	}
+	if len(shards) == 0 || n == len(b.pts) {
+		return
+	}
+	pts := make([]Point, 0, n)
+	data := make([]interface{}, 0, n)
+	var bounds HyperRect
+	for _, s := range append([]*Builder{b}, shards...) {
+		switch {
+		case s.pts == nil:
+			continue
+		case bounds.Min == nil:
+			bounds = s.bounds.Copy()
+		default:
+			bounds.extend(s.bounds)
+		}
+		pts = append(pts, s.pts...)
+		data = append(data, s.data...)
+	}
+	b.pts, b.data, b.bounds = pts, data, bounds
+}
+
+// Add adds point p with associated data payload, which may be nil.
+func (b *Builder) Add(p Point, payload interface{}) {
+	if b.pts == nil {
+		b.bounds = HyperRect{append(Point{}, p...), append(Point{}, p...)}
+	} else {
+		b.bounds.extend(HyperRect{p, p})
+	}
+	b.pts = append(b.pts, p)
+	b.data = append(b.data, payload)
+}
+
+// Len returns the number of points added, including those of shards.
+// Producers adding to shards must have finished.
+func (b *Builder) Len() int {
+	b.merge()
+	return len(b.pts)
+}
+
+// Bounds returns the bounding box of the points added so far, including
+// those of shards.  It is the zero HyperRect if no points have been added.
+// Producers adding to shards must have finished.
+func (b *Builder) Bounds() HyperRect {
+	b.merge()
+	if b.pts == nil {
+		return HyperRect{}
+	}
+	return b.bounds.Copy()
+}
+
+// Build constructs a balanced tree of the points added, as NewWith.
+// Bounds are those of the points unless given with WithBounds in opts.
+//
+// Points added to shards are included.  Producers adding to shards must
+// have finished.
+//
+// Build leaves b empty, ready for a new set of points.
+func (b *Builder) Build(opts ...Option) KdTree {
+	b.merge()
+	opts = append([]Option{WithData(b.data)}, opts...)
+	if b.pts != nil {
+		opts = append([]Option{WithBounds(b.bounds)}, opts...)
+	}
+	t := NewWith(b.pts, opts...)
+	b.pts, b.data, b.bounds = nil, nil, HyperRect{}
+	return t
+}
+
+// NewFromChannel constructs a tree from the points received on ch, as
+// NewWith, returning when ch is closed.  Points are numbered in the
+// order received.
+//
+// Work overlaps with a producer such as a concurrent parser.  As points
+// arrive they are prepared as configured by opts, their bounds are
+// accumulated, and each batch of channelBatch points is sorted by the
+// first dimension in its own goroutine.  When ch is closed the sorted
+// batches are merged, so that the first partitioning of the build, on
+// the first dimension, finds the points already in order.
+func NewFromChannel(ch <-chan Point, opts ...Option) KdTree {
+	o := newOptions(opts)
+	var bounds HyperRect
+	var runs [][]*kdNode
+	var wg sync.WaitGroup
+	n := 0
+	batch := make([]Point, 0, channelBatch)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		pts := o.prepare(batch)
+		if bounds.Min == nil {
+			bounds = HyperRect{append(Point{}, pts[0]...), append(Point{}, pts[0]...)}
+		}
+		for _, p := range pts {
+			bounds.extend(HyperRect{p, p})
+		}
+		var data []interface{}
+		if n < len(o.data) {
+			data = o.data[n:min(n+len(pts), len(o.data))]
+		}
+		run := newNodes(pts, data, n)
+		runs = append(runs, run)
+		n += len(run)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sortRun(run)
+		}()
+		batch = make([]Point, 0, channelBatch)
+	}
+	for p := range ch {
+		if batch = append(batch, p); len(batch) == channelBatch {
+			flush()
+		}
+	}
+	flush()
+	wg.Wait()
+	return o.build(mergeRuns(runs), bounds)
+}
+
+// channelBatch is the number of points NewFromChannel sorts together.
+const channelBatch = 8192
+
+// sortRun sorts nodes by the first coordinate, then by index.
+func sortRun(nodes []*kdNode) {
+	slices.SortFunc(nodes, compareRun)
+}
+
+func compareRun(a, b *kdNode) int {
+	if c := cmp.Compare(a.domElt[0], b.domElt[0]); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.index, b.index)
+}
+
+// mergeRuns merges runs sorted by sortRun.
+func mergeRuns(runs [][]*kdNode) []*kdNode {
+	for len(runs) > 1 {
+		var next [][]*kdNode
+		for i := 0; i+1 < len(runs); i += 2 {
+			a, b := runs[i], runs[i+1]
+			m := make([]*kdNode, 0, len(a)+len(b))
+			for len(a) > 0 && len(b) > 0 {
+				if compareRun(b[0], a[0]) < 0 {
+					m, b = append(m, b[0]), b[1:]
+				} else {
+					m, a = append(m, a[0]), a[1:]
+				}
+			}
+			next = append(next, append(append(m, a...), b...))
+		}
+		if len(runs)%2 == 1 {
+			next = append(next, runs[len(runs)-1])
+		}
+		runs = next
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+	return runs[0]
+}
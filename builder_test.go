@@ -0,0 +1,107 @@
+package kdtree
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	var b Builder
+	pts := randomPts(2, 300)
+	for i, p := range pts {
+		b.Add(p, i)
+	}
+	if b.Len() != len(pts) {
+		t.Fatal("Len", b.Len())
+	}
+	kd := b.Build()
+	kd.Brute = false
+	checkNearest(t, kd, len(pts))
+	for _, p := range pts {
+		if !kd.Bounds.Contains(p) {
+			t.Fatal("bounds", kd.Bounds, "do not contain", p)
+		}
+	}
+	n := kd.KNearestNeighbors(pts[7], 1)
+	if n[0].Index != 7 || n[0].Data != 7 {
+		t.Error("got", n[0])
+	}
+	if b.Len() != 0 {
+		t.Error("Builder not reset")
+	}
+	// an empty builder gives a tree that grows on insert
+	kd = b.Build()
+	kd.Insert(Point{.5, .5})
+	if kd.Bounds.Min == nil || kd.n == nil {
+		t.Error("insert into empty built tree failed")
+	}
+}
+
+func TestNewFromChannel(t *testing.T) {
+	pts := randomPts(3, 500)
+	ch := make(chan Point)
+	go func() {
+		for _, p := range pts {
+			ch <- p
+		}
+		close(ch)
+	}()
+	kd := NewFromChannel(ch, WithTighten())
+	kd.Brute = false
+	checkNearest(t, kd, len(pts))
+	// several batches, numbered in order received
+	pts = randomPts(2, 3*channelBatch+100)
+	ch = make(chan Point, 100)
+	go func() {
+		for _, p := range pts {
+			ch <- p
+		}
+		close(ch)
+	}()
+	kd = NewFromChannel(ch)
+	kd.Brute = false
+	checkNearest(t, kd, len(pts))
+	checkSizes(t, kd.n)
+	for _, i := range []int{0, channelBatch, len(pts) - 1} {
+		if n := kd.KNearestNeighbors(pts[i], 1); n[0].Index != i {
+			t.Fatal("point", i, "numbered", n[0].Index)
+		}
+	}
+	empty := make(chan Point)
+	close(empty)
+	if e := NewFromChannel(empty); e.n != nil {
+		t.Error("tree from empty channel")
+	}
+}
+
+func TestBuilderShard(t *testing.T) {
+	var b Builder
+	pts := randomPts(2, 1000)
+	b.Add(pts[0], 0)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		s := b.Shard()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1 + w; i < len(pts); i += 4 {
+				s.Add(pts[i], i)
+			}
+		}()
+	}
+	wg.Wait()
+	if b.Len() != len(pts) {
+		t.Fatal("Len", b.Len())
+	}
+	kd := b.Build()
+	kd.Brute = false
+	checkNearest(t, kd, len(pts))
+	for i, p := range pts {
+		if !kd.Bounds.Contains(p) {
+			t.Fatal("bounds", kd.Bounds, "do not contain", p)
+		}
+		if d, _ := kd.Lookup(p); d != i {
+			t.Fatal("point", i, "has data", d)
+		}
+	}
+}
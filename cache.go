@@ -0,0 +1,156 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"container/list"
+	"encoding/binary"
+	"math"
+	"sync"
+	"unsafe"
+)
+
+// Cache is a least recently used cache of query results in front of a
+// tree, for request streams that repeat effectively identical queries.
+//
+// Query points are quantized to a grid of the cache's resolution, and a
+// query hits the cache if an earlier query of the same kind and
+// parameters fell in the same grid cell.  The results are then those of
+// the earlier query, distances included.  Resolution 0 caches only
+// queries at exactly equal points.
+//
+// Changes to the tree through its methods, such as Insert, Delete,
+// Remove, and Update, or assigning a different tree to it, including a
+// view of it such as by Where, InCells, or InWindow, invalidate the
+// cache.  A Cache may be used by multiple goroutines at once, but as for
+// the tree itself, not concurrently with changes to the tree.  Results
+// are shared between callers and must not be modified.
+type Cache struct {
+	t   *KdTree
+	res float64
+
+	mu      sync.Mutex
+	cap     int
+	lru     *list.List // of *cacheEntry, most recent first
+	entries map[cacheKey]*list.Element
+	state   cacheState
+	hits    uint64
+	misses  uint64
+}
+
+// cacheState identifies the tree the cached results are of.  Views are
+// identified by their restrictions, which are allocated anew for each.
+type cacheState struct {
+	gen     uint64
+	root    *kdNode
+	filter  *AttrRange
+	nfilter int
+	cells   *CellRange
+	ncells  int
+	window  *timeWindow
+}
+
+func (c *Cache) current() cacheState {
+	t := c.t
+	return cacheState{t.gen, t.n, unsafe.SliceData(t.filter), len(t.filter),
+		unsafe.SliceData(t.cells), len(t.cells), t.window}
+}
+
+type cacheKey struct {
+	kind byte
+	k    int
+	r    float64
+	p    string // quantized coordinates
+}
+
+type cacheEntry struct {
+	key cacheKey
+	n   Neighbors
+}
+
+// NewCache returns a Cache of up to capacity results of queries on t,
+// with query points quantized to multiples of resolution.
+func NewCache(t *KdTree, capacity int, resolution float64) *Cache {
+	return &Cache{t: t, res: resolution, cap: max(capacity, 1),
+		lru: list.New(), entries: map[cacheKey]*list.Element{}}
+}
+
+// Nearest returns the nearest neighbor of p and the square of its
+// distance, as KdTree.Nearest, or nil and +Inf if the tree is empty.
+func (c *Cache) Nearest(p Point) (Point, float64) {
+	n := c.KNearestNeighbors(p, 1)
+	if len(n) == 0 {
+		return nil, math.Inf(1)
+	}
+	return n[0].Point, n[0].Sqd
+}
+
+// KNearestNeighbors returns the k nearest neighbors of p, as
+// KdTree.KNearestNeighbors.
+func (c *Cache) KNearestNeighbors(p Point, k int) Neighbors {
+	return c.query(c.key('k', p, k, 0), func() Neighbors {
+		return c.t.KNearestNeighbors(p, k)
+	})
+}
+
+// InRadiusNeighbors returns the points within distance r of p, as
+// KdTree.InRadiusNeighbors.
+func (c *Cache) InRadiusNeighbors(p Point, r float64) Neighbors {
+	return c.query(c.key('r', p, 0, r), func() Neighbors {
+		return c.t.InRadiusNeighbors(p, r)
+	})
+}
+
+// Stats returns the numbers of cache hits and misses.
+func (c *Cache) Stats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+func (c *Cache) key(kind byte, p Point, k int, r float64) cacheKey {
+	b := make([]byte, 0, 8*len(p))
+	for _, x := range Snap(p, c.res) {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(x))
+	}
+	return cacheKey{kind, k, r, string(b)}
+}
+
+// query returns the cached result for key, or runs search and caches its
+// result.
+func (c *Cache) query(key cacheKey, search func() Neighbors) Neighbors {
+	c.mu.Lock()
+	if s := c.current(); c.state != s {
+		// the tree changed
+		c.lru.Init()
+		clear(c.entries)
+		c.state = s
+	}
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		c.hits++
+		c.mu.Unlock()
+		return e.Value.(*cacheEntry).n
+	}
+	c.misses++
+	s := c.state
+	c.mu.Unlock()
+	n := search()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != s {
+		return n
+	}
+	if e, ok := c.entries[key]; ok {
+		// another goroutine searched meanwhile
+		c.lru.MoveToFront(e)
+		return n
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key, n})
+	if c.lru.Len() > c.cap {
+		old := c.lru.Remove(c.lru.Back()).(*cacheEntry)
+		delete(c.entries, old.key)
+	}
+	return n
+}
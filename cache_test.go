@@ -0,0 +1,68 @@
+package kdtree
+
+import "testing"
+
+func TestCache(t *testing.T) {
+	pts := randomPts(2, 1000)
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	c := NewCache(&kd, 2, .01)
+	q := Point{.5, .5}
+	want := kd.KNearestNeighbors(q, 3)
+	got := c.KNearestNeighbors(q, 3)
+	if got[0].Index != want[0].Index {
+		t.Fatal("got", got, "expected", want)
+	}
+	// a nearby query hits the cache with the earlier result
+	got = c.KNearestNeighbors(Point{.501, .499}, 3)
+	if h, m := c.Stats(); h != 1 || m != 1 || got[0].Sqd != want[0].Sqd {
+		t.Fatal("hits", h, "misses", m)
+	}
+	// different parameters miss
+	c.KNearestNeighbors(q, 4)
+	c.InRadiusNeighbors(q, .1)
+	if h, m := c.Stats(); h != 1 || m != 3 {
+		t.Fatal("hits", h, "misses", m)
+	}
+	// capacity 2 evicted the first query
+	c.KNearestNeighbors(q, 3)
+	if _, m := c.Stats(); m != 4 {
+		t.Fatal("misses", m)
+	}
+	// a change to the tree invalidates
+	kd.Insert(q)
+	if p, d := c.Nearest(q); d != 0 || !equal(p, q) {
+		t.Fatal("stale", p, d)
+	}
+	kd.Remove(q)
+	if _, d := c.Nearest(q); d == 0 {
+		t.Fatal("stale after Remove")
+	}
+	kd = New(pts[:1], kd.Bounds)
+	if n := c.InRadiusNeighbors(q, 1); len(n) != 1 {
+		t.Fatal("stale after assignment", len(n))
+	}
+}
+
+func TestCacheView(t *testing.T) {
+	pts := randomPts(2, 1000)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = float64(i)
+	}
+	kd := NewWithData(pts, data, HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.IndexAttrs(func(d interface{}) float64 { return d.(float64) })
+	c := NewCache(&kd, 10, .01)
+	q := Point{.5, .5}
+	c.KNearestNeighbors(q, 3)
+	// assigning a view of the tree invalidates
+	kd = kd.Where(AttrRange{0, 0, 9})
+	for _, n := range c.KNearestNeighbors(q, 3) {
+		if n.Index > 9 {
+			t.Fatal("stale after Where", n.Index)
+		}
+	}
+	if h, m := c.Stats(); h != 0 || m != 2 {
+		t.Fatal("hits", h, "misses", m)
+	}
+}
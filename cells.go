@@ -0,0 +1,206 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CellFunc returns the coarse cell ID, such as an S2 cell ID or a geohash
+// converted by GeohashID, tagging the point with the associated data.
+type CellFunc func(data interface{}) uint64
+
+// CellRange is the range of cell IDs Min through Max inclusive.  In
+// hierarchical schemes such as S2 and geohash, the IDs of the cells
+// within a coarser cell form such a range; see S2CellRange and
+// GeohashRange.
+type CellRange struct {
+	Min, Max uint64
+}
+
+// IndexCells tags points with the cell IDs given by f and keeps for each
+// subtree the range of its IDs, so that InCells views skip subtrees
+// holding no point in the cells.
+//
+// This lets a coarse inverted index, such as of S2 cells or geohashes,
+// choose candidate cells for a query, leaving this tree to do the exact
+// distance work within just those cells.
+//
+// The ranges are kept up to date as points are added and subtrees are
+// rebuilt, separately from attributes indexed with IndexAttrs, which
+// does not affect them.  As with
+// IndexAttrs, deleted points leave ranges valid but possibly loose.  The
+// cost is memory for two IDs per interior node.  IndexCells(nil) drops
+// the index.
+func (t *KdTree) IndexCells(f CellFunc) {
+	t.cell = f
+	if t.n != nil {
+		indexCells(t.n, f)
+	}
+}
+
+// indexCells sets the ranges of cell IDs given by f for interior nodes of
+// the subtree at kd, or clears them if f is nil.  Nodes are taken from an
+// explicit stack in preorder, then ranged in reverse so that children
+// come before their parents.
+func indexCells(kd *kdNode, f CellFunc) {
+	var pre []*kdNode
+	for stack := []*kdNode{kd}; len(stack) > 0; {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n.force()
+		pre = append(pre, n)
+		for _, c := range []*kdNode{n.left, n.right} {
+			if c != nil {
+				stack = append(stack, c)
+			}
+		}
+	}
+	for i := len(pre) - 1; i >= 0; i-- {
+		n := pre[i]
+		n.cellIDs = nil
+		if f == nil || n.left == nil && n.right == nil {
+			continue
+		}
+		for _, c := range []*kdNode{n.left, n.right} {
+			if c == nil {
+				continue
+			}
+			if c.cellIDs != nil {
+				n.extendCellRange(f, *c.cellIDs)
+			} else {
+				n.extendCells(f, c.rangeElt)
+			}
+		}
+	}
+}
+
+// extendCells extends the range of cell IDs of kd to include the cell of
+// data.
+func (kd *kdNode) extendCells(f CellFunc, data interface{}) {
+	id := f(data)
+	kd.extendCellRange(f, CellRange{id, id})
+}
+
+// extendCellRange extends the range of cell IDs of kd to include r.
+func (kd *kdNode) extendCellRange(f CellFunc, r CellRange) {
+	if kd.cellIDs == nil {
+		id := f(kd.rangeElt)
+		kd.cellIDs = &CellRange{id, id}
+	}
+	kd.cellIDs.Min = min(kd.cellIDs.Min, r.Min)
+	kd.cellIDs.Max = max(kd.cellIDs.Max, r.Max)
+}
+
+// InCells returns a view of t in which queries see only points in any of
+// cells, as well as any restriction of t itself.  t must have cells
+// indexed with IndexCells.  As for Where, all queries of the view honor
+// the restriction.
+func (t KdTree) InCells(cells ...CellRange) KdTree {
+	c := append([]CellRange(nil), cells...)
+	slices.SortFunc(c, func(a, b CellRange) int {
+		switch {
+		case a.Min < b.Min:
+			return -1
+		case a.Min > b.Min:
+			return 1
+		}
+		return 0
+	})
+	// merge overlapping ranges so that they are ordered by Max as well.
+	m := c[:0]
+	for _, r := range c {
+		if r.Min > r.Max {
+			continue
+		}
+		if len(m) > 0 && r.Min <= m[len(m)-1].Max {
+			m[len(m)-1].Max = max(m[len(m)-1].Max, r.Max)
+			continue
+		}
+		m = append(m, r)
+	}
+	if t.cells != nil {
+		// intersect with the restriction already present.
+		var both []CellRange
+		for _, r := range m {
+			for _, s := range t.cells {
+				if lo, hi := max(r.Min, s.Min), min(r.Max, s.Max); lo <= hi {
+					both = append(both, CellRange{lo, hi})
+				}
+			}
+		}
+		m = both
+	}
+	if m == nil {
+		m = []CellRange{}
+	}
+	t.cells = m
+	return t
+}
+
+// inCells reports whether data is in the cells of the view.
+func (t KdTree) inCells(data interface{}) bool {
+	id := t.cell(data)
+	i := sort.Search(len(t.cells), func(i int) bool { return t.cells[i].Max >= id })
+	return i < len(t.cells) && t.cells[i].Min <= id
+}
+
+// cellsMayMatch reports whether the subtree at kd may hold a point in the
+// cells of the view, by its range of IDs.
+func (t KdTree) cellsMayMatch(kd *kdNode) bool {
+	r := kd.cellIDs
+	if r == nil {
+		return true
+	}
+	i := sort.Search(len(t.cells), func(i int) bool { return t.cells[i].Max >= r.Min })
+	return i < len(t.cells) && t.cells[i].Min <= r.Max
+}
+
+// restricted reports whether t is a view restricted by Where, InCells, or
+// InWindow.
+func (t KdTree) restricted() bool {
+	return t.filter != nil || t.cells != nil || t.window != nil
+}
+
+// S2CellRange returns the range of the IDs of the leaf cells within the
+// S2 cell id, which includes the IDs of all cells within it at any level.
+func S2CellRange(id uint64) CellRange {
+	lsb := id & -id
+	return CellRange{id - (lsb - 1), id + (lsb - 1)}
+}
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+var errGeohash = errors.New("kdtree: invalid geohash")
+
+// GeohashID returns a cell ID for a geohash of up to 12 characters, its 5
+// bits per character placed at the top of a uint64, so that the IDs of
+// geohashes with a common prefix form the range given by GeohashRange.
+func GeohashID(hash string) (uint64, error) {
+	if len(hash) > 12 {
+		return 0, errGeohash
+	}
+	var id uint64
+	for i := 0; i < len(hash); i++ {
+		v := strings.IndexByte(geohashAlphabet, hash[i])
+		if v < 0 {
+			return 0, errGeohash
+		}
+		id |= uint64(v) << (59 - 5*i)
+	}
+	return id, nil
+}
+
+// GeohashRange returns the range of IDs from GeohashID of geohashes
+// starting with prefix.
+func GeohashRange(prefix string) (CellRange, error) {
+	id, err := GeohashID(prefix)
+	if err != nil {
+		return CellRange{}, err
+	}
+	return CellRange{id, id | (1<<(64-5*len(prefix)) - 1)}, nil
+}
@@ -0,0 +1,138 @@
+package kdtree
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestInCells(t *testing.T) {
+	pts := randomPts(2, 3000)
+	data := make([]interface{}, len(pts))
+	for i, p := range pts {
+		// geohash-like cells: the first character by x, the second by y
+		h := string([]byte{geohashAlphabet[int(p[0]*32)%32],
+			geohashAlphabet[int(p[1]*32)%32]})
+		id, err := GeohashID(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data[i] = id
+	}
+	kd := NewWith(append([]Point{}, pts...), WithData(data),
+		WithBounds(HyperRect{Point{0, 0}, Point{1, 1}}))
+	kd.Brute = false
+	kd.IndexCells(func(d interface{}) uint64 { return d.(uint64) })
+	b, _ := GeohashRange("b")
+	c, _ := GeohashRange("c")
+	v := kd.InCells(c, b)
+	in := func(i int) bool { x := int(pts[i][0] * 32); return x == 10 || x == 11 }
+	p := randomPt(2)
+	var want []float64
+	for i, q := range pts {
+		if in(i) {
+			want = append(want, q.Sqd(p))
+		}
+	}
+	sort.Float64s(want)
+	got := v.KNearestNeighbors(p, 5)
+	if len(got) != 5 {
+		t.Fatal("got", len(got), "results")
+	}
+	for i, n := range got {
+		if !in(n.Index) || n.Sqd != want[i] {
+			t.Fatal("result", i, n, "expected distance^2", want[i])
+		}
+	}
+	if _, sqd, _ := v.Nearest(p); sqd != want[0] {
+		t.Fatal("Nearest", sqd, "expected", want[0])
+	}
+	hr := HyperRect{Point{0, .2}, Point{1, .8}}
+	n := 0
+	for i, q := range pts {
+		if in(i) && hr.Contains(q) {
+			n++
+		}
+	}
+	if got := len(v.InRange(hr)); got != n {
+		t.Fatal("InRange", got, "expected", n)
+	}
+	// a further restriction intersects
+	for _, q := range v.InCells(c).InRange(hr) {
+		if int(q[0]*32) != 11 {
+			t.Fatal(q, "outside cell c")
+		}
+	}
+	if p, _, _ := v.InCells(CellRange{0, 1}).Nearest(p); p != nil {
+		t.Fatal("found", p, "in empty cells")
+	}
+	// attributes indexed later leave the cell index intact
+	kd.IndexAttrs(func(interface{}) float64 { return 0 })
+	for _, p := range randomPts(2, 200) {
+		kd.InsertWithData(p, uint64(0))
+	}
+	if got := len(kd.InCells(c, b).InRange(hr)); got != n {
+		t.Fatal("after IndexAttrs, InRange", got, "expected", n)
+	}
+	checkCellRanges(t, kd)
+}
+
+// checkCellRanges checks that interior nodes hold the cell IDs of their
+// subtrees.
+func checkCellRanges(t *testing.T, kd KdTree) {
+	walk(kd.n, func(n *kdNode) {
+		if n.left == nil && n.right == nil {
+			return
+		}
+		walk(n, func(c *kdNode) {
+			if id := kd.cell(c.rangeElt); n.cellIDs == nil ||
+				id < n.cellIDs.Min || id > n.cellIDs.Max {
+				t.Fatal("cell", id, "outside range", n.cellIDs)
+			}
+		})
+	})
+}
+
+func TestCellRanges(t *testing.T) {
+	if r := S2CellRange(1 << 63); r != (CellRange{1, 1<<64 - 1}) {
+		t.Fatal("S2 root face range", r)
+	}
+	if r := S2CellRange(5); r != (CellRange{5, 5}) {
+		t.Fatal("S2 leaf range", r)
+	}
+	r, err := GeohashRange("u4pruydqqvj")
+	id, _ := GeohashID("u4pruydqqvj8")
+	if err != nil || id < r.Min || id > r.Max {
+		t.Fatal(r, err, id)
+	}
+	if _, err := GeohashID("a"); err == nil {
+		t.Fatal("accepted invalid geohash")
+	}
+}
+
+func TestInCellsQueries(t *testing.T) {
+	pts := randomPts(2, 2000)
+	data := make([]interface{}, len(pts))
+	for i, p := range pts {
+		h := string([]byte{geohashAlphabet[int(p[0]*32)%32],
+			geohashAlphabet[int(p[1]*32)%32]})
+		id, err := GeohashID(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data[i] = id
+	}
+	prepare := func(kd *KdTree) {
+		kd.IndexCells(func(d interface{}) uint64 { return d.(uint64) })
+		kd.IndexRadius(func(interface{}) float64 { return .2 })
+	}
+	kd := NewWith(append([]Point{}, pts...), WithData(data),
+		WithBounds(HyperRect{Point{0, 0}, Point{1, 1}}))
+	kd.Brute = false
+	prepare(&kd)
+	b, _ := GeohashRange("b")
+	c, _ := GeohashRange("c")
+	checkView(t, kd.InCells(c, b), pts, data, func(i int) bool {
+		x := int(pts[i][0] * 32)
+		return x == 10 || x == 11
+	}, prepare)
+}
@@ -0,0 +1,66 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// NewChecked constructs a KdTree as New after validating its arguments.
+//
+// It returns an error if pts is empty, if the points and bounds do not all
+// have the same number of dimensions, if any coordinate is NaN or
+// infinite, if bounds.Min exceeds bounds.Max in some dimension, or, as a
+// *BoundsError, if a point lies outside bounds.  New in any of these
+// cases returns a tree that gives wrong answers or panics when queried.
+func NewChecked(pts []Point, bounds HyperRect) (KdTree, error) {
+	if err := check(pts, bounds); err != nil {
+		return KdTree{}, err
+	}
+	return New(pts, bounds), nil
+}
+
+func check(pts []Point, bounds HyperRect) error {
+	if len(pts) == 0 {
+		return errors.New("kdtree: no points")
+	}
+	dim := len(bounds.Min)
+	if dim == 0 {
+		return errors.New("kdtree: bounds have no dimensions")
+	}
+	if len(bounds.Max) != dim {
+		return fmt.Errorf("kdtree: bounds Min has %d dimensions, Max has %d",
+			dim, len(bounds.Max))
+	}
+	for d := range bounds.Min {
+		if !finite(bounds.Min[d]) || !finite(bounds.Max[d]) {
+			return fmt.Errorf("kdtree: bounds %v not finite", bounds)
+		}
+		if bounds.Min[d] > bounds.Max[d] {
+			return fmt.Errorf("kdtree: bounds %v empty in dimension %d",
+				bounds, d)
+		}
+	}
+	for i, p := range pts {
+		if len(p) != dim {
+			return fmt.Errorf("kdtree: point %d has %d dimensions, expected %d",
+				i, len(p), dim)
+		}
+		for _, c := range p {
+			if !finite(c) {
+				return fmt.Errorf("kdtree: point %d %v not finite", i, p)
+			}
+		}
+		if !bounds.Contains(p) {
+			return &BoundsError{p, bounds}
+		}
+	}
+	return nil
+}
+
+func finite(c float64) bool {
+	return !math.IsNaN(c) && !math.IsInf(c, 0)
+}
@@ -0,0 +1,38 @@
+package kdtree
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNewChecked(t *testing.T) {
+	b := HyperRect{Point{0, 0}, Point{1, 1}}
+	kd, err := NewChecked(randomPts(2, 100), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kd.n.size != 100 {
+		t.Fatal("size", kd.n.size)
+	}
+	for name, tc := range map[string]struct {
+		pts    []Point
+		bounds HyperRect
+	}{
+		"empty":      {nil, b},
+		"dimensions": {[]Point{{.5, .5}, {.5}}, b},
+		"NaN":        {[]Point{{.5, math.NaN()}}, b},
+		"Inf bounds": {[]Point{{.5, .5}}, HyperRect{Point{0, 0}, Point{1, math.Inf(1)}}},
+		"inverted":   {[]Point{{.5, .5}}, HyperRect{Point{1, 0}, Point{0, 1}}},
+		"bounds dim": {[]Point{{.5, .5}}, HyperRect{Point{0, 0}, Point{1}}},
+	} {
+		if _, err := NewChecked(tc.pts, tc.bounds); err == nil {
+			t.Error(name, ": expected error")
+		}
+	}
+	_, err = NewChecked([]Point{{.5, .5}, {2, .5}}, b)
+	var be *BoundsError
+	if !errors.As(err, &be) || be.P[0] != 2 {
+		t.Error("expected BoundsError, got", err)
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ReadXYZ constructs a tree, as NewWith, from a point cloud in the XYZ
+// text format read from r: a point per line, as three coordinates
+// separated by spaces, tabs, or commas.  Further numbers on a line, such
+// as color or intensity, are associated with the point as a []float64.
+// Blank lines and lines starting with # are skipped.
+func ReadXYZ(r io.Reader, opts ...Option) (KdTree, error) {
+	sc := bufio.NewScanner(r)
+	var b Builder
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || s[0] == '#' {
+			continue
+		}
+		f := strings.FieldsFunc(s, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+		if len(f) < 3 {
+			return KdTree{}, fmt.Errorf("kdtree: XYZ line %d: too few values", line)
+		}
+		v := make([]float64, len(f))
+		for i, s := range f {
+			var err error
+			if v[i], err = strconv.ParseFloat(s, 64); err != nil {
+				return KdTree{}, fmt.Errorf("kdtree: XYZ line %d: %w", line, err)
+			}
+		}
+		var data interface{}
+		if len(v) > 3 {
+			data = v[3:]
+		}
+		b.Add(Point(v[:3:3]), data)
+	}
+	if err := sc.Err(); err != nil {
+		return KdTree{}, err
+	}
+	return b.Build(opts...), nil
+}
+
+// WriteXYZ writes n in the XYZ format read by ReadXYZ, with the values of
+// data that is a []float64 following the coordinates of each point.
+func WriteXYZ(w io.Writer, n Neighbors) error {
+	bw := bufio.NewWriter(w)
+	var b []byte
+	for _, nb := range n {
+		b = b[:0]
+		for i, c := range nb.Point {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = strconv.AppendFloat(b, c, 'g', -1, 64)
+		}
+		v, _ := nb.Data.([]float64)
+		for _, c := range v {
+			b = append(b, ' ')
+			b = strconv.AppendFloat(b, c, 'g', -1, 64)
+		}
+		b = append(b, '\n')
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+var errPLY = errors.New("kdtree: invalid PLY")
+
+// plyProp is a scalar property of a PLY element, or a list property if
+// count is not empty.
+type plyProp struct {
+	name, typ, count string
+}
+
+type plyElement struct {
+	name  string
+	n     int
+	props []plyProp
+}
+
+// plySize is the size in bytes of each PLY scalar type.
+var plySize = map[string]int{
+	"char": 1, "int8": 1, "uchar": 1, "uint8": 1,
+	"short": 2, "int16": 2, "ushort": 2, "uint16": 2,
+	"int": 4, "int32": 4, "uint": 4, "uint32": 4,
+	"float": 4, "float32": 4, "double": 8, "float64": 8,
+}
+
+// ReadPLY constructs a tree, as NewWith, from the vertices of a point
+// cloud in the PLY format read from r, in ASCII or binary of either byte
+// order.  Vertex properties x, y, and z give the point.  The values of
+// any other scalar vertex properties, such as color or intensity, are
+// associated with the point as a []float64, in the order given by fields.
+// Elements other than vertex, such as faces, are skipped.
+func ReadPLY(r io.Reader, opts ...Option) (t KdTree, fields []string, err error) {
+	br := bufio.NewReader(r)
+	format, elems, err := readPLYHeader(br)
+	if err != nil {
+		return KdTree{}, nil, err
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if format == "binary_big_endian" {
+		order = binary.BigEndian
+	}
+	var b Builder
+	for _, e := range elems {
+		if e.name != "vertex" {
+			if err := skipPLYElement(br, format, order, e); err != nil {
+				return KdTree{}, nil, err
+			}
+			continue
+		}
+		xyz := [3]int{-1, -1, -1}
+		var rest []int
+		for i, p := range e.props {
+			switch {
+			case p.count != "":
+				return KdTree{}, nil, fmt.Errorf("kdtree: PLY vertex list property %s", p.name)
+			case p.name == "x":
+				xyz[0] = i
+			case p.name == "y":
+				xyz[1] = i
+			case p.name == "z":
+				xyz[2] = i
+			default:
+				rest = append(rest, i)
+				fields = append(fields, p.name)
+			}
+		}
+		if xyz[0] < 0 || xyz[1] < 0 || xyz[2] < 0 {
+			return KdTree{}, nil, fmt.Errorf("kdtree: PLY vertex without x, y, z")
+		}
+		v := make([]float64, len(e.props))
+		for i := 0; i < e.n; i++ {
+			if err := readPLYValues(br, format, order, e.props, v); err != nil {
+				return KdTree{}, nil, fmt.Errorf("kdtree: PLY vertex %d: %w", i, err)
+			}
+			p := Point{v[xyz[0]], v[xyz[1]], v[xyz[2]]}
+			var data interface{}
+			if len(rest) > 0 {
+				d := make([]float64, len(rest))
+				for j, k := range rest {
+					d[j] = v[k]
+				}
+				data = d
+			}
+			b.Add(p, data)
+		}
+		break
+	}
+	return b.Build(opts...), fields, nil
+}
+
+func readPLYHeader(br *bufio.Reader) (format string, elems []plyElement, err error) {
+	line := func() ([]string, error) {
+		s, err := br.ReadString('\n')
+		if err != nil {
+			return nil, errPLY
+		}
+		return strings.Fields(s), nil
+	}
+	f, err := line()
+	if err != nil || len(f) != 1 || f[0] != "ply" {
+		return "", nil, errPLY
+	}
+	for {
+		if f, err = line(); err != nil {
+			return "", nil, err
+		}
+		if len(f) == 0 {
+			continue
+		}
+		switch f[0] {
+		case "format":
+			if len(f) < 2 {
+				return "", nil, errPLY
+			}
+			format = f[1]
+		case "element":
+			if len(f) != 3 {
+				return "", nil, errPLY
+			}
+			n, err := strconv.Atoi(f[2])
+			if err != nil || n < 0 {
+				return "", nil, errPLY
+			}
+			elems = append(elems, plyElement{name: f[1], n: n})
+		case "property":
+			if len(elems) == 0 {
+				return "", nil, errPLY
+			}
+			var p plyProp
+			switch {
+			case len(f) == 3:
+				p = plyProp{name: f[2], typ: f[1]}
+			case len(f) == 5 && f[1] == "list":
+				p = plyProp{name: f[4], typ: f[3], count: f[2]}
+				if plySize[p.count] == 0 {
+					return "", nil, errPLY
+				}
+			default:
+				return "", nil, errPLY
+			}
+			if plySize[p.typ] == 0 {
+				return "", nil, errPLY
+			}
+			e := &elems[len(elems)-1]
+			e.props = append(e.props, p)
+		case "end_header":
+			switch format {
+			case "ascii", "binary_little_endian", "binary_big_endian":
+				return format, elems, nil
+			}
+			return "", nil, errPLY
+		}
+		// comment and obj_info lines are ignored
+	}
+}
+
+// readPLYValues reads the scalar properties of an element into v.
+func readPLYValues(br *bufio.Reader, format string, order binary.ByteOrder,
+	props []plyProp, v []float64) error {
+	if format == "ascii" {
+		s, err := br.ReadString('\n')
+		if err != nil && (err != io.EOF || s == "") {
+			return err
+		}
+		f := strings.Fields(s)
+		if len(f) < len(props) {
+			return errPLY
+		}
+		for i := range props {
+			if v[i], err = strconv.ParseFloat(f[i], 64); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var buf [8]byte
+	for i, p := range props {
+		x, err := readPLYBinary(br, order, p.typ, buf[:])
+		if err != nil {
+			return err
+		}
+		v[i] = x
+	}
+	return nil
+}
+
+// readPLYBinary reads a binary scalar of type typ.
+func readPLYBinary(r io.Reader, order binary.ByteOrder, typ string, buf []byte) (float64, error) {
+	b := buf[:plySize[typ]]
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, err
+	}
+	switch typ {
+	case "char", "int8":
+		return float64(int8(b[0])), nil
+	case "uchar", "uint8":
+		return float64(b[0]), nil
+	case "short", "int16":
+		return float64(int16(order.Uint16(b))), nil
+	case "ushort", "uint16":
+		return float64(order.Uint16(b)), nil
+	case "int", "int32":
+		return float64(int32(order.Uint32(b))), nil
+	case "uint", "uint32":
+		return float64(order.Uint32(b)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(order.Uint32(b))), nil
+	}
+	return math.Float64frombits(order.Uint64(b)), nil
+}
+
+// skipPLYElement reads past the data of element e.
+func skipPLYElement(br *bufio.Reader, format string, order binary.ByteOrder,
+	e plyElement) error {
+	var buf [8]byte
+	for i := 0; i < e.n; i++ {
+		if format == "ascii" {
+			if _, err := br.ReadString('\n'); err != nil {
+				return errPLY
+			}
+			continue
+		}
+		for _, p := range e.props {
+			n := 1
+			if p.count != "" {
+				c, err := readPLYBinary(br, order, p.count, buf[:])
+				if err != nil {
+					return errPLY
+				}
+				n = int(c)
+			}
+			if _, err := br.Discard(n * plySize[p.typ]); err != nil {
+				return errPLY
+			}
+		}
+	}
+	return nil
+}
+
+// WritePLY writes n as the vertices of a PLY point cloud, in binary
+// little endian if bin is true, otherwise in ASCII.  Points must be
+// 3D.  Vertex properties after x, y, and z are named by fields, with
+// values from data that is a []float64, as associated by ReadPLY.
+// Missing values are written as zero.  All values are written as
+// double.
+func WritePLY(w io.Writer, n Neighbors, fields []string, bin bool) error {
+	bw := bufio.NewWriter(w)
+	format := "ascii"
+	if bin {
+		format = "binary_little_endian"
+	}
+	fmt.Fprintf(bw, "ply\nformat %s 1.0\nelement vertex %d\n", format, len(n))
+	for _, f := range append([]string{"x", "y", "z"}, fields...) {
+		fmt.Fprintf(bw, "property double %s\n", f)
+	}
+	bw.WriteString("end_header\n")
+	var b []byte
+	put := func(i int, c float64) {
+		if bin {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(c))
+			return
+		}
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = strconv.AppendFloat(b, c, 'g', -1, 64)
+	}
+	for _, nb := range n {
+		if len(nb.Point) != 3 {
+			return fmt.Errorf("kdtree: PLY point with %d dimensions", len(nb.Point))
+		}
+		b = b[:0]
+		for i, c := range nb.Point {
+			put(i, c)
+		}
+		v, _ := nb.Data.([]float64)
+		for i := range fields {
+			c := 0.
+			if i < len(v) {
+				c = v[i]
+			}
+			put(3+i, c)
+		}
+		if !bin {
+			b = append(b, '\n')
+		}
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
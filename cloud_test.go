@@ -0,0 +1,82 @@
+package kdtree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestXYZ(t *testing.T) {
+	in := "# x y z intensity\n0 0 0 5\n1,2,3,6\n\n4\t5\t6\t7\n"
+	kd, err := ReadXYZ(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := kd.KNearestNeighbors(Point{1, 2, 3.1}, 1)
+	if !equal(n[0].Point, Point{1, 2, 3}) || !slices.Equal(n[0].Data.([]float64), []float64{6}) {
+		t.Fatal("nearest", n)
+	}
+	var b bytes.Buffer
+	if err := WriteXYZ(&b, n); err != nil || b.String() != "1 2 3 6\n" {
+		t.Fatal(b.String(), err)
+	}
+	if _, err := ReadXYZ(strings.NewReader("1 2\n")); err == nil {
+		t.Fatal("accepted short line")
+	}
+}
+
+func TestPLY(t *testing.T) {
+	pts := randomPts(3, 100)
+	n := make(Neighbors, len(pts))
+	for i, p := range pts {
+		n[i] = Neighbor{Point: p, Index: i, Data: []float64{float64(i), 255}}
+	}
+	fields := []string{"intensity", "red"}
+	for _, bin := range []bool{false, true} {
+		var b bytes.Buffer
+		if err := WritePLY(&b, n, fields, bin); err != nil {
+			t.Fatal(err)
+		}
+		kd, f, err := ReadPLY(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(f, fields) {
+			t.Fatal("fields", f)
+		}
+		for i, p := range pts {
+			nb := kd.KNearestNeighbors(p, 1)[0]
+			if !equal(nb.Point, p) || nb.Data.([]float64)[0] != float64(i) {
+				t.Fatal("binary", bin, "point", i, nb)
+			}
+		}
+	}
+}
+
+func TestPLYBigEndian(t *testing.T) {
+	// big endian floats and uchar color, with elements before and after
+	// the vertices to be skipped.
+	var b bytes.Buffer
+	b.WriteString("ply\nformat binary_big_endian 1.0\ncomment test\n" +
+		"element camera 1\nproperty int id\n" +
+		"element vertex 2\nproperty float x\nproperty float y\n" +
+		"property float z\nproperty uchar red\n" +
+		"element face 1\nproperty list uchar int vertex_indices\nend_header\n")
+	binary.Write(&b, binary.BigEndian, int32(9))
+	for _, v := range [][3]float32{{1, 2, 3}, {-1, .5, 0}} {
+		binary.Write(&b, binary.BigEndian, v)
+		b.WriteByte(200)
+	}
+	b.Write([]byte{2, 0, 0, 0, 0, 0, 0, 0, 1})
+	kd, f, err := ReadPLY(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb := kd.KNearestNeighbors(Point{-1, .5, 0}, 2)
+	if len(nb) != 2 || !equal(nb[0].Point, Point{-1, .5, 0}) ||
+		nb[0].Data.([]float64)[0] != 200 || f[0] != "red" {
+		t.Fatal(nb, f)
+	}
+}
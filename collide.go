@@ -0,0 +1,134 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "slices"
+
+// CollisionPairs returns the pairs of indexes of points of t that may
+// collide, those at distance less than the sum of their radii as given
+// by radius from their data.  In each pair the lesser index is first,
+// and pairs are ordered by first then second index.
+func (t KdTree) CollisionPairs(radius func(data interface{}) float64) (pairs [][2]int) {
+	t.CollisionPairsFunc(radius, func(i, j int) bool {
+		if i > j {
+			i, j = j, i
+		}
+		pairs = append(pairs, [2]int{i, j})
+		return true
+	})
+	slices.SortFunc(pairs, func(a, b [2]int) int {
+		if a[0] != b[0] {
+			return a[0] - b[0]
+		}
+		return a[1] - b[1]
+	})
+	return
+}
+
+// CollisionPairsFunc calls f with the indexes of each pair of points that
+// may collide, as CollisionPairs, in no particular order, stopping early
+// if f returns false.
+//
+// This is a broad phase for collision detection.  Unlike a self join at a
+// fixed distance, which must use the largest radius for every point, it
+// keeps for each subtree its bounding box and greatest radius, and
+// traverses pairs of subtrees together, pruning pairs whose boxes are
+// farther apart than the sum of their greatest radii.
+func (t KdTree) CollisionPairsFunc(radius func(data interface{}) float64,
+	f func(i, j int) bool) {
+	if t.n == nil {
+		return
+	}
+	c := collider{t: t, aug: map[*kdNode]collAug{}, radius: radius, f: f}
+	c.augment(t.n)
+	c.self(t.n)
+}
+
+// collAug is the augmentation of a subtree, the bounding box and the
+// greatest radius of its points.
+type collAug struct {
+	box HyperRect
+	r   float64
+}
+
+type collider struct {
+	t      KdTree
+	aug    map[*kdNode]collAug
+	radius func(data interface{}) float64
+	f      func(i, j int) bool
+}
+
+func (c *collider) augment(kd *kdNode) collAug {
+	kd.force()
+	a := collAug{
+		HyperRect{append(Point{}, kd.domElt...), append(Point{}, kd.domElt...)},
+		c.radius(kd.rangeElt),
+	}
+	for _, ch := range []*kdNode{kd.left, kd.right} {
+		if ch != nil {
+			ca := c.augment(ch)
+			a.box.extend(ca.box)
+			a.r = max(a.r, ca.r)
+		}
+	}
+	c.aug[kd] = a
+	return a
+}
+
+// collide tests the points of a and b, reporting them if they collide.
+func (c *collider) collide(a, b *kdNode) bool {
+	if !c.t.sees(a) || !c.t.sees(b) {
+		return true
+	}
+	r := c.radius(a.rangeElt) + c.radius(b.rangeElt)
+	if a.domElt.Sqd(b.domElt) < r*r {
+		return c.f(a.index, b.index)
+	}
+	return true
+}
+
+// apart reports whether no point of subtree a can collide with any of
+// subtree b.
+func (c *collider) apart(a, b *kdNode) bool {
+	aa, ba := c.aug[a], c.aug[b]
+	r := aa.r + ba.r
+	return boxSqd(aa.box, ba.box) >= r*r
+}
+
+// self reports colliding pairs within the subtree at kd.
+func (c *collider) self(kd *kdNode) bool {
+	for _, ch := range []*kdNode{kd.left, kd.right} {
+		if ch != nil && !(c.point(kd, ch) && c.self(ch)) {
+			return false
+		}
+	}
+	if kd.left != nil && kd.right != nil {
+		return c.cross(kd.left, kd.right)
+	}
+	return true
+}
+
+// point reports the points of subtree b colliding with the point of p.
+func (c *collider) point(p, b *kdNode) bool {
+	if b == nil {
+		return true
+	}
+	ba := c.aug[b]
+	if r := c.radius(p.rangeElt) + ba.r; ba.box.Sqd(p.domElt) >= r*r {
+		return true
+	}
+	return c.collide(p, b) && c.point(p, b.left) && c.point(p, b.right)
+}
+
+// cross reports colliding pairs of a point of subtree a and a point of
+// subtree b, splitting the larger subtree as the traversal descends.
+func (c *collider) cross(a, b *kdNode) bool {
+	if a == nil || b == nil || c.apart(a, b) {
+		return true
+	}
+	if a.size < b.size {
+		a, b = b, a
+	}
+	return c.point(a, b) && c.cross(a.left, b) && c.cross(a.right, b)
+}
@@ -0,0 +1,42 @@
+package kdtree
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestCollisionPairs(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	pts := randomPts(2, 1000)
+	radii := make([]interface{}, len(pts))
+	for i := range radii {
+		// mostly small radii with a few large ones
+		r := .005 * rng.Float64()
+		if i%100 == 0 {
+			r = .05
+		}
+		radii[i] = r
+	}
+	kd := NewWith(append([]Point{}, pts...), WithData(radii))
+	kd.Remove(pts[7])
+	radius := func(d interface{}) float64 { return d.(float64) }
+	var want [][2]int
+	for i, p := range pts {
+		for j := i + 1; j < len(pts); j++ {
+			r := radii[i].(float64) + radii[j].(float64)
+			if i != 7 && j != 7 && p.Sqd(pts[j]) < r*r {
+				want = append(want, [2]int{i, j})
+			}
+		}
+	}
+	got := kd.CollisionPairs(radius)
+	if len(want) == 0 || !slices.Equal(got, want) {
+		t.Fatal("got", len(got), "pairs, want", len(want))
+	}
+	n := 0
+	kd.CollisionPairsFunc(radius, func(i, j int) bool { n++; return n < 3 })
+	if n != 3 {
+		t.Fatal("did not stop early:", n)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "fmt"
+
+// NewFromColumns constructs a tree, as NewWith, from coordinates stored
+// by column.  cols[d][i] is coordinate d of point i.  All columns must
+// have the same length.
+//
+// Columnar data such as an Apache Arrow record can be passed without
+// conversion, using the Float64Values of the Float64 arrays:
+//
+//	cols := make([][]float64, len(colIdx))
+//	for d, c := range colIdx {
+//		cols[d] = rec.Column(c).(*array.Float64).Float64Values()
+//	}
+//	t, err := kdtree.NewFromColumns(cols)
+//
+// The tree stores each point's coordinates together, so they are copied,
+// but into a single allocation rather than a slice per point.  Point i is
+// given index i.
+func NewFromColumns(cols [][]float64, opts ...Option) (KdTree, error) {
+	if len(cols) == 0 {
+		return NewWith(nil, opts...), nil
+	}
+	n := len(cols[0])
+	for d, c := range cols {
+		if len(c) != n {
+			return KdTree{}, fmt.Errorf(
+				"kdtree: column %d has %d values, column 0 has %d",
+				d, len(c), n)
+		}
+	}
+	dim := len(cols)
+	coords := make([]float64, n*dim)
+	pts := make([]Point, n)
+	for i := range pts {
+		p := coords[i*dim : (i+1)*dim : (i+1)*dim]
+		for d, c := range cols {
+			p[d] = c[i]
+		}
+		pts[i] = p
+	}
+	return NewWith(pts, opts...), nil
+}
@@ -0,0 +1,26 @@
+package kdtree
+
+import "testing"
+
+func TestNewFromColumns(t *testing.T) {
+	pts := randomPts(3, 400)
+	cols := make([][]float64, 3)
+	for _, p := range pts {
+		for d, c := range p {
+			cols[d] = append(cols[d], c)
+		}
+	}
+	kd, err := NewFromColumns(cols, WithBounds(HyperRect{Point{0, 0, 0}, Point{1, 1, 1}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kd.Brute = false
+	checkNearest(t, kd, len(pts))
+	n := kd.KNearestNeighbors(pts[42], 1)
+	if n[0].Index != 42 || n[0].Sqd != 0 {
+		t.Error("got", n[0])
+	}
+	if _, err := NewFromColumns([][]float64{{1, 2}, {1}}); err == nil {
+		t.Error("expected error for ragged columns")
+	}
+}
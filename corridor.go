@@ -0,0 +1,102 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// InCorridor returns the points of t within distance r of the polyline
+// path, such as a planned route, as Neighbors sorted by distance from the
+// path.  Sqd is the square of the distance to the nearest segment.  Each
+// point is returned once, however many segments it is near.  A path of
+// one point is a radius query.
+//
+// The tree is traversed once for the whole path.  At each subtree,
+// segments that cannot come within r of the subtree's box are dropped,
+// and a subtree is pruned when no segment remains.
+func (t KdTree) InCorridor(path []Point, r float64) (n Neighbors) {
+	if t.n == nil || len(path) == 0 {
+		return nil
+	}
+	segs := make([]Segment, max(len(path)-1, 1))
+	ext := make([]HyperRect, len(segs))
+	for i := range segs {
+		if len(path) == 1 {
+			segs[i] = Segment{path[0], path[0]}
+		} else {
+			segs[i] = Segment{path[i], path[i+1]}
+		}
+		// the bounds of the segment grown by r
+		ext[i] = segs[i].Bounds()
+		for d := range ext[i].Min {
+			ext[i].Min[d] -= r
+			ext[i].Max[d] += r
+		}
+	}
+	r2 := r * r
+	all := make([]int, len(segs))
+	for i := range all {
+		all[i] = i
+	}
+	c := newCellStack(t, all)
+	for {
+		kd, active, ok := c.pop()
+		if !ok {
+			break
+		}
+		if t.excludes(kd) {
+			continue
+		}
+		box := c.cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		active = nearBox(segs, ext, active, box, r)
+		if len(active) == 0 {
+			continue
+		}
+		if t.sees(kd) {
+			d := math.Inf(1)
+			for _, i := range active {
+				d = math.Min(d, segs[i].Sqd(kd.domElt))
+			}
+			if d <= r2 {
+				n = append(n, kd.neighbor(d))
+			}
+		}
+		c.push(kd, kd.right, active)
+		c.push(kd, kd.left, active)
+	}
+	n.Sort()
+	return
+}
+
+// nearBox returns those of the segments listed in active that may come
+// within r of box, in a new slice.
+//
+// A segment is dropped if its bounding box, grown by r, misses box, or if
+// its distance from the center of box, less the half diagonal of box,
+// exceeds r.  Both are lower bounds on the distance from the segment to
+// the box.
+func nearBox(segs []Segment, ext []HyperRect, active []int, box HyperRect,
+	r float64) []int {
+	center := make(Point, len(box.Min))
+	half := 0.
+	for d := range center {
+		center[d] = (box.Min[d] + box.Max[d]) / 2
+		w := (box.Max[d] - box.Min[d]) / 2
+		half += w * w
+	}
+	half = math.Sqrt(half)
+	var near []int
+	for _, i := range active {
+		if !ext[i].Intersects(box) {
+			continue
+		}
+		if math.Sqrt(segs[i].Sqd(center))-half > r {
+			continue
+		}
+		near = append(near, i)
+	}
+	return near
+}
@@ -0,0 +1,54 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInCorridor(t *testing.T) {
+	pts := randomPts(2, 3000)
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	kd.Tighten()
+	path := []Point{{.1, .1}, {.5, .2}, {.5, .8}, {.45, .2}, {.9, .9}}
+	r := .05
+	got := kd.InCorridor(path, r)
+	want := 0
+	for _, p := range pts {
+		for i := range path[1:] {
+			if (Segment{path[i], path[i+1]}).Sqd(p) <= r*r {
+				want++
+				break
+			}
+		}
+	}
+	if len(got) != want || want == 0 {
+		t.Fatal("got", len(got), "expected", want)
+	}
+	seen := map[int]bool{}
+	for i, n := range got {
+		if seen[n.Index] {
+			t.Fatal("duplicate", n.Index)
+		}
+		seen[n.Index] = true
+		if i > 0 && n.Sqd < got[i-1].Sqd {
+			t.Fatal("not sorted")
+		}
+	}
+	if got, want := kd.InCorridor(path[:1], r), kd.InRadius(path[0], r); len(got) != len(want) {
+		t.Error("single point path got", len(got), "expected", len(want))
+	}
+}
+
+func TestInCorridorDeep(t *testing.T) {
+	kd, pts := deepTree(3000)
+	want := 0
+	for _, p := range pts {
+		if math.Abs(p[1]-.5) <= .1 {
+			want++
+		}
+	}
+	if got := len(kd.InCorridor([]Point{{0, .5}, {1, .5}}, .1)); got != want {
+		t.Fatal("found", got, "points, expected", want)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// CoverTree is a cover tree (Beygelzimer, Kakade, and Langford) indexing
+// items of any type under a caller-supplied metric.
+//
+// Where a KdTree splits space along coordinate axes, a cover tree adapts
+// to the intrinsic dimension of the data, so it remains effective for data
+// lying near a low dimensional manifold in a high dimensional space.  Each
+// node has a level i and covers its descendants within 2^i.  This is the
+// simplified variant of Izbicki and Shelton, where each node also records
+// maxdist, the greatest distance to any of its descendants, which is the
+// basis for pruning searches.  Items at distance zero from one already
+// in the tree are kept with it rather than in nodes of their own.
+//
+// Queries follow the conventions of VPTree.
+type CoverTree[T any] struct {
+	root *coverNode[T]
+	dist func(a, b T) float64
+}
+
+type coverNode[T any] struct {
+	item     T
+	dups     []T // items at distance zero from item
+	level    int
+	maxdist  float64
+	children []*coverNode[T]
+}
+
+var errCoverDist = errors.New("kdtree: cover tree distance not finite")
+
+func (n *coverNode[T]) covdist() float64 {
+	return math.Ldexp(1, n.level)
+}
+
+// NewCoverTree builds a CoverTree of items under the metric dist.  It
+// returns an error if Insert does for any item.
+func NewCoverTree[T any](items []T, dist func(a, b T) float64) (*CoverTree[T], error) {
+	t := &CoverTree[T]{dist: dist}
+	for _, it := range items {
+		if err := t.Insert(it); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Insert adds x to t.  It returns an error, leaving x out, if a distance
+// from x to an item of t is infinite or NaN, as no level would cover it.
+func (t *CoverTree[T]) Insert(x T) error {
+	if t.root == nil {
+		t.root = &coverNode[T]{item: x}
+		return nil
+	}
+	d := t.dist(t.root.item, x)
+	if !finite(d) {
+		return errCoverDist
+	}
+	// raising the level of the root keeps its descendants covered.
+	for d > t.root.covdist() {
+		t.root.level++
+	}
+	for p := t.root; ; {
+		if d == 0 {
+			p.dups = append(p.dups, x)
+			return nil
+		}
+		// maxdist may grow before a later distance fails, which leaves
+		// it a looser bound but still a bound.
+		if d > p.maxdist {
+			p.maxdist = d
+		}
+		var next *coverNode[T]
+		for _, c := range p.children {
+			dc := t.dist(c.item, x)
+			if !finite(dc) {
+				return errCoverDist
+			}
+			if dc <= c.covdist() {
+				next, d = c, dc
+				break
+			}
+		}
+		if next == nil {
+			p.children = append(p.children,
+				&coverNode[T]{item: x, level: p.level - 1})
+			return nil
+		}
+		p = next
+	}
+}
+
+// Nearest finds the item nearest q, as VPTree.Nearest.
+func (t *CoverTree[T]) Nearest(q T) (best T, dist float64, nv int) {
+	nn, d, nv := t.KNearest(q, 1)
+	if len(nn) == 0 {
+		return best, math.Inf(1), nv
+	}
+	return nn[0], d[0], nv
+}
+
+// KNearest finds the k items nearest q, in order of increasing distance.
+func (t *CoverTree[T]) KNearest(q T, k int) (nn []T, dist []float64, nv int) {
+	if k <= 0 || t.root == nil {
+		return
+	}
+	tau := math.Inf(1)
+	type child struct {
+		n *coverNode[T]
+		d float64
+	}
+	// keep offers the items of node n, at distance d from q.
+	keep := func(n *coverNode[T], d float64) {
+		if d >= tau {
+			return
+		}
+		nn, dist, tau = keepK(nn, dist, k, n.item, d)
+		for _, x := range n.dups {
+			if d >= tau {
+				return
+			}
+			nn, dist, tau = keepK(nn, dist, k, x, d)
+		}
+	}
+	var search func(*coverNode[T])
+	search = func(p *coverNode[T]) {
+		cs := make([]child, len(p.children))
+		for i, c := range p.children {
+			cs[i] = child{c, t.dist(q, c.item)}
+		}
+		sort.Slice(cs, func(i, j int) bool { return cs[i].d < cs[j].d })
+		for _, c := range cs {
+			// descendants of c are at least c.d - maxdist from q
+			if c.d-c.n.maxdist >= tau {
+				continue
+			}
+			nv++
+			keep(c.n, c.d)
+			search(c.n)
+		}
+	}
+	nv++
+	keep(t.root, t.dist(q, t.root.item))
+	search(t.root)
+	return
+}
@@ -0,0 +1,86 @@
+package kdtree
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// points on a curve in 10 dimensions
+func TestCoverTree(t *testing.T) {
+	curve := func(s float64) Point {
+		p := make(Point, 10)
+		for i := range p {
+			p[i] = math.Sin(s * float64(i+1))
+		}
+		return p
+	}
+	dist := func(a, b Point) float64 { return math.Sqrt(a.Sqd(b)) }
+	var pts []Point
+	for i := 0; i < 1000; i++ {
+		pts = append(pts, curve(rand.Float64()*3))
+	}
+	pts = append(pts, pts[0]) // a duplicate
+	ct, err := NewCoverTree(pts, dist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		p := curve(rand.Float64() * 3)
+		all := make([]float64, len(pts))
+		for j, q := range pts {
+			all[j] = dist(p, q)
+		}
+		sort.Float64s(all)
+		_, d, nv := ct.KNearest(p, 4)
+		for j := range d {
+			if d[j] != all[j] {
+				t.Fatal("Expected distances", all[:4], "found", d)
+			}
+		}
+		if nv >= len(pts) {
+			t.Error("Expected pruning, visited", nv)
+		}
+	}
+	if _, d, _ := ct.Nearest(pts[0]); d != 0 {
+		t.Error("Expected distance 0 to a stored item, found", d)
+	}
+}
+
+func TestCoverTreeInsert(t *testing.T) {
+	dist := func(a, b float64) float64 { return math.Abs(a - b) }
+	ct, err := NewCoverTree([]float64{0, 1, 2}, dist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []float64{math.Inf(1), math.NaN()} {
+		if ct.Insert(x) == nil {
+			t.Error("inserted", x)
+		}
+	}
+	// duplicates are kept with their item, not chained below it
+	for i := 0; i < 1000; i++ {
+		if err := ct.Insert(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var depth func(n *coverNode[float64]) int
+	depth = func(n *coverNode[float64]) int {
+		d := 0
+		for _, c := range n.children {
+			d = max(d, depth(c))
+		}
+		return d + 1
+	}
+	if d := depth(ct.root); d > 3 {
+		t.Error("depth", d)
+	}
+	nn, d, _ := ct.KNearest(1, 5)
+	if len(nn) != 5 || d[4] != 0 {
+		t.Error(nn, d)
+	}
+	if nn, _, _ := ct.KNearest(3, 4); len(nn) != 4 || nn[0] != 2 || nn[1] != 1 {
+		t.Error(nn)
+	}
+}
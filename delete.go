@@ -0,0 +1,102 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// Delete removes a point with the coordinates of p from t.  It returns
+// false if there is no such point.
+//
+// The node of the point is refilled with the point of greatest coordinate
+// in its split dimension from its left subtree, the deletion repeating
+// down the tree until a leaf is removed.  (If there is no left subtree the
+// right subtree takes its place first.)  Bounding boxes stored by Tighten
+// are not shrunk.  They remain valid but may become loose.  Afterward,
+// a subtree left unbalanced according to t.Alpha is rebuilt.
+func (t *KdTree) Delete(p Point) bool {
+	var path []**kdNode
+	link := &t.n
+	for kd := t.n; ; kd = *link {
+		if kd == nil {
+			return false
+		}
+		kd.force()
+		path = append(path, link)
+		if !kd.deleted && equal(kd.domElt, p) {
+			break
+		}
+		if p[kd.split] <= kd.domElt[kd.split] {
+			link = &kd.left
+		} else {
+			link = &kd.right
+		}
+	}
+	t.gen++
+	for _, l := range path {
+		(*l).size--
+	}
+	remove(link)
+	t.balancePath(path)
+	return true
+}
+
+// remove removes the node *link, whose size and the sizes of its
+// ancestors are already decremented.
+func remove(link **kdNode) {
+	kd := *link
+	if kd.left == nil && kd.right == nil {
+		*link = nil
+		return
+	}
+	if kd.left == nil {
+		kd.left, kd.right = kd.right, nil
+	}
+	m := maxNode(kd.left, kd.split)
+	kd.elt = m.elt
+	// descend to m by comparison, which finds it because the left subtree
+	// holds coordinates <= and the right subtree coordinates >.
+	link = &kd.left
+	for n := kd.left; n != m; n = *link {
+		n.size--
+		if m.domElt[n.split] <= n.domElt[n.split] {
+			link = &n.left
+		} else {
+			link = &n.right
+		}
+	}
+	m.size--
+	remove(link)
+}
+
+// maxNode returns the node of the subtree at kd with the greatest
+// coordinate in dimension s.
+func maxNode(kd *kdNode, s int) *kdNode {
+	kd.force()
+	m := kd
+	if kd.split == s {
+		if kd.right != nil {
+			m = maxNode(kd.right, s)
+		}
+		return m
+	}
+	for _, c := range []*kdNode{kd.left, kd.right} {
+		if c != nil {
+			if cm := maxNode(c, s); cm.domElt[s] > m.domElt[s] {
+				m = cm
+			}
+		}
+	}
+	return m
+}
+
+// equal reports whether p and q have the same coordinates.
+func equal(p, q Point) bool {
+	if len(p) != len(q) {
+		return false
+	}
+	for i, c := range p {
+		if q[i] != c {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package kdtree
+
+import (
+	"testing"
+	"time"
+)
+
+// checkSizes verifies the size field of every node.
+func checkSizes(t *testing.T, kd *kdNode) int {
+	if kd == nil {
+		return 0
+	}
+	n := 1 + checkSizes(t, kd.left) + checkSizes(t, kd.right)
+	if kd.size != n {
+		t.Fatal("node size", kd.size, "expected", n)
+	}
+	return n
+}
+
+func TestDelete(t *testing.T) {
+	pts := randomPts(2, 400)
+	// duplicates exercise the <= side of the splits
+	pts = append(pts, pts[:50]...)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Tighten()
+	kd.Brute = false
+	for i, p := range pts[:300] {
+		if !kd.Delete(p) {
+			t.Fatal("Delete", i, p, "returned false")
+		}
+	}
+	if kd.Delete(Point{2, 2}) {
+		t.Error("Expected false deleting a point not in the tree")
+	}
+	checkSizes(t, kd.n)
+	checkNearest(t, kd, len(pts)-300)
+	for _, p := range pts[300:] {
+		kd.Delete(p)
+	}
+	if kd.n != nil {
+		t.Error("Expected empty tree")
+	}
+}
+
+func TestAlpha(t *testing.T) {
+	for _, alpha := range []float64{0, 2} {
+		kd := KdTree{Bounds: HyperRect{Point{0}, Point{1}}, Alpha: alpha}
+		for i := 0; i < 500; i++ {
+			kd.Insert(Point{float64(i) / 500})
+		}
+		checkSizes(t, kd.n)
+		h := height(kd.n)
+		switch {
+		case alpha == 0 && h > 25:
+			t.Error("Expected rebalancing, found height", h)
+		case alpha == 2 && h != 500:
+			t.Error("Expected no rebalancing, found height", h)
+		}
+		checkNearest(t, kd, 500)
+		kd.Rebalance()
+		if h = height(kd.n); h != 9 {
+			t.Error("Expected height 9 after Rebalance, found", h)
+		}
+	}
+}
+
+func TestMaxRebuild(t *testing.T) {
+	kd := KdTree{Bounds: HyperRect{Point{0}, Point{1}}, MaxRebuild: 64}
+	sizes := []int{}
+	kd.Metrics = rebuildSizes(func(size int) { sizes = append(sizes, size) })
+	for i := 0; i < 2000; i++ {
+		kd.Insert(Point{float64(i) / 2000})
+	}
+	for _, s := range sizes {
+		if s > 64 {
+			t.Fatal("rebuilt subtree of", s, "nodes")
+		}
+	}
+	checkSizes(t, kd.n)
+	checkNearest(t, kd, 2000)
+	// depth grows, but much less than with no rebalancing
+	if h := height(kd.n); h < 12 || h > 200 {
+		t.Error("height", h)
+	}
+}
+
+// rebuildSizes is a Metrics reporting the sizes of rebuilds to f.
+type rebuildSizes func(size int)
+
+func (f rebuildSizes) Query(string, int, time.Duration) {}
+func (f rebuildSizes) Rebuild(reason string, size int)  { f(size) }
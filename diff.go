@@ -0,0 +1,92 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "slices"
+
+// Diff compares the points of a and b.  added are the points of b not in
+// a, removed are those of a not in b.  Points are compared by coordinates
+// as multisets, so a point stored twice in b and once in a is reported
+// once in added.
+//
+// The trees are traversed together, pairing the nodes at the same place
+// in each.  A subtree both trees hold, as copies of one KdTree value do,
+// is passed over whole, and paired nodes holding equal points cancel.  Only the points left over are sorted and
+// merged, so trees built alike and changed little compare in time about
+// proportional to their size, or less where they share subtrees.  Trees
+// shaped differently cost O(n log n).
+func Diff(a, b KdTree) (added, removed []Point) {
+	// shared subtrees are the same only if neither tree is a view.
+	share := !a.restricted() && !b.restricted()
+	var pa, pb []Point
+	rest := func(t KdTree, kd *kdNode, pts *[]Point) {
+		t.yieldAll(kd, func(n *kdNode) bool {
+			*pts = append(*pts, n.domElt)
+			return true
+		})
+	}
+	type pair struct{ a, b *kdNode }
+	for stack := []pair{{a.n, b.n}}; len(stack) > 0; {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch {
+		case p.a == p.b && share:
+			continue
+		case p.a == nil:
+			rest(b, p.b, &pb)
+			continue
+		case p.b == nil:
+			rest(a, p.a, &pa)
+			continue
+		}
+		p.a.force()
+		p.b.force()
+		sa, sb := a.sees(p.a), b.sees(p.b)
+		if !sa || !sb || !equal(p.a.domElt, p.b.domElt) {
+			if sa {
+				pa = append(pa, p.a.domElt)
+			}
+			if sb {
+				pb = append(pb, p.b.domElt)
+			}
+		}
+		stack = append(stack, pair{p.a.left, p.b.left}, pair{p.a.right, p.b.right})
+	}
+	slices.SortFunc(pa, compare)
+	slices.SortFunc(pb, compare)
+	for len(pa) > 0 && len(pb) > 0 {
+		switch c := compare(pa[0], pb[0]); {
+		case c < 0:
+			removed = append(removed, pa[0])
+			pa = pa[1:]
+		case c > 0:
+			added = append(added, pb[0])
+			pb = pb[1:]
+		default:
+			pa, pb = pa[1:], pb[1:]
+		}
+	}
+	removed = append(removed, pa...)
+	added = append(added, pb...)
+	return
+}
+
+// compare orders points lexicographically by coordinate.
+func compare(p, q Point) int {
+	for i := range p {
+		if i == len(q) {
+			return 1
+		}
+		if p[i] != q[i] {
+			if p[i] < q[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	if len(p) < len(q) {
+		return -1
+	}
+	return 0
+}
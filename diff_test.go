@@ -0,0 +1,62 @@
+package kdtree
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	pts := randomPts(2, 200)
+	a := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	b := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	if added, removed := Diff(a, b); len(added)+len(removed) != 0 {
+		t.Fatal("identical trees differ:", added, removed)
+	}
+	for _, p := range pts[:10] {
+		b.Delete(p)
+	}
+	add := append(randomPts(2, 5), pts[20]) // one duplicate
+	b.InsertAll(add)
+	added, removed := Diff(a, b)
+	if len(added) != len(add) || len(removed) != 10 {
+		t.Fatal("added", len(added), "removed", len(removed))
+	}
+	in := func(p Point, s []Point) bool {
+		for _, q := range s {
+			if equal(p, q) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range pts[:10] {
+		if !in(p, removed) {
+			t.Error(p, "not reported removed")
+		}
+	}
+	for _, p := range add {
+		if !in(p, added) {
+			t.Error(p, "not reported added")
+		}
+	}
+}
+
+func TestDiffShared(t *testing.T) {
+	pts := randomPts(2, 500)
+	a := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	b := a
+	if added, removed := Diff(a, b); len(added)+len(removed) != 0 {
+		t.Fatal("copies differ:", added, removed)
+	}
+	// a view shares the nodes but not all the points
+	a.IndexAttrs(func(d interface{}) float64 { return 0 })
+	if added, removed := Diff(a, a.Where(AttrRange{0, 1, 1})); len(added) != 0 ||
+		len(removed) != len(pts) {
+		t.Fatal("view: added", len(added), "removed", len(removed))
+	}
+	// trees built alike, with points moved and removed
+	b = New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	b.Update(pts[3], Point{pts[3][0] + 1e-9, pts[3][1]})
+	b.Remove(pts[4])
+	added, removed := Diff(a, b)
+	if len(added) != 1 || len(removed) != 2 || !equal(added[0], Point{pts[3][0] + 1e-9, pts[3][1]}) {
+		t.Fatal("added", added, "removed", removed)
+	}
+}
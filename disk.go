@@ -0,0 +1,220 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// The disk format holds a tree as fixed size node records in preorder, so
+// that a node can be read by position without reading the whole file.
+//
+// A header of diskMagic, the dimension as a uint32, the number of nodes as
+// a uint64, and the bounds, Min then Max, is followed by the node records.
+// Each record is the split as a uint32, flags as a uint32, the index as a
+// uint64, the record number of the right child as a uint64, and the
+// coordinates.  The left child, if any, is the next record.  All values
+// are little endian.
+const diskMagic = "KDT\x01"
+
+// node record flags
+const (
+	diskLeft = 1 << iota
+	diskRight
+	diskDeleted
+)
+
+func diskHeaderSize(dim int) int64 { return 16 + 16*int64(dim) }
+func diskRecordSize(dim int) int64 { return 24 + 8*int64(dim) }
+
+var errDisk = errors.New("kdtree: invalid disk format")
+
+// diskWriter writes the disk format.
+type diskWriter struct {
+	w   *bufio.Writer
+	buf []byte
+	dim int
+}
+
+func newDiskWriter(w io.Writer, dim int, n int64, bounds HyperRect) (*diskWriter, error) {
+	d := &diskWriter{w: bufio.NewWriter(w), dim: dim}
+	b := append([]byte(diskMagic), make([]byte, 12)...)
+	binary.LittleEndian.PutUint32(b[4:], uint32(dim))
+	binary.LittleEndian.PutUint64(b[8:], uint64(n))
+	for _, p := range []Point{bounds.Min, bounds.Max} {
+		for _, c := range p {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(c))
+		}
+	}
+	_, err := d.w.Write(b)
+	return d, err
+}
+
+// node writes a node record.  right is the record number of the right
+// child.
+func (d *diskWriter) node(e elt, split int, left, hasRight bool,
+	right int64) error {
+	var flags uint32
+	if left {
+		flags |= diskLeft
+	}
+	if hasRight {
+		flags |= diskRight
+	}
+	if e.deleted {
+		flags |= diskDeleted
+	}
+	b := binary.LittleEndian.AppendUint32(d.buf[:0], uint32(split))
+	b = binary.LittleEndian.AppendUint32(b, flags)
+	b = binary.LittleEndian.AppendUint64(b, uint64(e.index))
+	b = binary.LittleEndian.AppendUint64(b, uint64(right))
+	for _, c := range e.domElt {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(c))
+	}
+	d.buf = b
+	_, err := d.w.Write(b)
+	return err
+}
+
+// subtree writes the subtree at kd as records numbered from first.
+func (d *diskWriter) subtree(kd *kdNode, first int64) error {
+	kd.force()
+	right := first + 1
+	if kd.left != nil {
+		right += int64(kd.left.size)
+	}
+	err := d.node(kd.elt, kd.split, kd.left != nil, kd.right != nil, right)
+	if err == nil && kd.left != nil {
+		err = d.subtree(kd.left, first+1)
+	}
+	if err == nil && kd.right != nil {
+		err = d.subtree(kd.right, right)
+	}
+	return err
+}
+
+func (d *diskWriter) flush() error { return d.w.Flush() }
+
+// WriteDisk writes t in the disk format read by ReadDisk and OpenDisk.
+// Data associated with points is not written.
+func (t KdTree) WriteDisk(w io.Writer) error {
+	n := int64(0)
+	if t.n != nil {
+		n = int64(t.n.size)
+	}
+	d, err := newDiskWriter(w, len(t.Bounds.Min), n, t.Bounds)
+	if err == nil && t.n != nil {
+		err = d.subtree(t.n, 0)
+	}
+	if err == nil {
+		err = d.flush()
+	}
+	return err
+}
+
+// ReadDisk reads a tree in the disk format, as written by WriteDisk or
+// BuildExternal, into memory.
+func ReadDisk(r io.Reader) (KdTree, error) {
+	br := bufio.NewReader(r)
+	dim, n, bounds, err := readDiskHeader(br)
+	if err != nil {
+		return KdTree{}, err
+	}
+	rec := make([]byte, diskRecordSize(dim))
+	slab := make([]kdNode, n)
+	var t KdTree
+	next := 0
+	// records are in preorder; a stack holds nodes awaiting a right child.
+	var stack []*kdNode
+	var parent *kdNode // node awaiting its left child
+	for i := range slab {
+		if _, err := io.ReadFull(br, rec); err != nil {
+			return KdTree{}, errDisk
+		}
+		kd := &slab[i]
+		flags := decodeDiskNode(rec, kd, dim)
+		if kd.split >= dim {
+			return KdTree{}, errDisk
+		}
+		next = max(next, kd.index+1)
+		switch {
+		case i == 0:
+			t.n = kd
+		case parent != nil:
+			parent.left = kd
+		case len(stack) > 0:
+			stack[len(stack)-1].right = kd
+			stack = stack[:len(stack)-1]
+		default:
+			return KdTree{}, errDisk
+		}
+		parent = nil
+		if flags&diskRight != 0 {
+			stack = append(stack, kd)
+		}
+		if flags&diskLeft != 0 {
+			parent = kd
+		}
+		if kd.deleted {
+			t.dead++
+		}
+	}
+	if parent != nil || len(stack) > 0 {
+		return KdTree{}, errDisk
+	}
+	setSizes(t.n)
+	t.Bounds = bounds
+	t.Brute = UseBrute(n, dim)
+	t.next = next
+	return t, nil
+}
+
+func readDiskHeader(r io.Reader) (dim, n int, bounds HyperRect, err error) {
+	h := make([]byte, 16)
+	if _, err = io.ReadFull(r, h); err != nil || string(h[:4]) != diskMagic {
+		return 0, 0, bounds, errDisk
+	}
+	dim = int(binary.LittleEndian.Uint32(h[4:]))
+	n64 := binary.LittleEndian.Uint64(h[8:])
+	if dim == 0 || dim > 1<<16 || n64 > math.MaxInt/2 {
+		return 0, 0, bounds, errDisk
+	}
+	b := make([]byte, 16*dim)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return 0, 0, bounds, errDisk
+	}
+	bounds = HyperRect{make(Point, dim), make(Point, dim)}
+	for i := 0; i < dim; i++ {
+		bounds.Min[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[8*i:]))
+		bounds.Max[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[8*(dim+i):]))
+	}
+	return dim, int(n64), bounds, nil
+}
+
+// decodeDiskNode sets the point, index, split, and deleted flag of kd
+// from record rec, and returns the flags.
+func decodeDiskNode(rec []byte, kd *kdNode, dim int) uint32 {
+	kd.split = int(binary.LittleEndian.Uint32(rec))
+	flags := binary.LittleEndian.Uint32(rec[4:])
+	kd.index = int(binary.LittleEndian.Uint64(rec[8:]))
+	kd.deleted = flags&diskDeleted != 0
+	kd.domElt = make(Point, dim)
+	for i := range kd.domElt {
+		kd.domElt[i] = math.Float64frombits(binary.LittleEndian.Uint64(rec[24+8*i:]))
+	}
+	return flags
+}
+
+// setSizes sets the sizes of the subtree at kd and returns its size.
+func setSizes(kd *kdNode) int {
+	if kd == nil {
+		return 0
+	}
+	kd.size = 1 + setSizes(kd.left) + setSizes(kd.right)
+	return kd.size
+}
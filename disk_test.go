@@ -0,0 +1,64 @@
+package kdtree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestDisk(t *testing.T) {
+	pts := randomPts(3, 1000)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	kd.Remove(pts[3])
+	var buf bytes.Buffer
+	if err := kd.WriteDisk(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadDisk(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Brute = false
+	checkSizes(t, got.n)
+	if got.dead != 1 || got.Contains(pts[3]) {
+		t.Error("tombstone not kept")
+	}
+	for _, p := range randomPts(3, 50) {
+		want := kd.KNearestNeighbors(p, 2)
+		n := got.KNearestNeighbors(p, 2)
+		if n[0].Index != want[0].Index || n[1].Index != want[1].Index {
+			t.Fatal("got", n, "expected", want)
+		}
+	}
+}
+
+func TestBuildExternal(t *testing.T) {
+	pts := randomPts(2, 5000)
+	pts = append(pts, pts[:100]...) // duplicates
+	var in bytes.Buffer
+	for _, p := range pts {
+		binary.Write(&in, binary.LittleEndian, []float64(p))
+	}
+	var out bytes.Buffer
+	// force several levels of partitioning through files
+	err := BuildExternal(&in, 2, &out, ExternalOptions{TempDir: t.TempDir(), MemLimit: 24 * 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kd, err := ReadDisk(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kd.Brute = false
+	checkSizes(t, kd.n)
+	checkNearest(t, kd, len(pts))
+	for i, p := range pts[:200] {
+		if d, _ := kd.Lookup(p); !kd.Contains(p) || d != nil {
+			t.Fatal("point", i, "not found")
+		}
+	}
+	n := kd.KNearestNeighbors(pts[4321], 1)
+	if n[0].Index != 4321 {
+		t.Error("index", n[0].Index, "expected 4321")
+	}
+}
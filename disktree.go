@@ -0,0 +1,245 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"container/list"
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+	"sync"
+)
+
+// DiskTree queries a tree in the disk format, as written by WriteDisk or
+// BuildExternal, without loading it into memory.
+//
+// Node records are read through r in pages of DiskPageRecords records.
+// Up to a configured number of bytes of decoded pages are kept in a least
+// recently used cache.  An *os.File works as r, giving a disk backed
+// index where memory mapping is unavailable.
+//
+// A DiskTree may be used by multiple goroutines at once.  If reading
+// fails, queries return what they had found and Err reports the error.
+type DiskTree struct {
+	r      io.ReaderAt
+	dim    int
+	n      int64
+	bounds HyperRect
+
+	mu    sync.Mutex
+	lru   *list.List // of *diskPage, most recent first
+	pages map[int64]*list.Element
+	max   int
+	err   error
+}
+
+// DiskPageRecords is the number of node records in a page read by a
+// DiskTree.
+const DiskPageRecords = 64
+
+// diskNode is a decoded node record.
+type diskNode struct {
+	pt    Point
+	index int
+	split int
+	flags uint32
+	right int64
+}
+
+type diskPage struct {
+	no    int64
+	nodes []diskNode
+}
+
+// OpenDisk reads the header of a tree in the disk format from r and
+// returns a DiskTree caching up to about cacheBytes of pages.  At least
+// one page is always cached.
+func OpenDisk(r io.ReaderAt, cacheBytes int) (*DiskTree, error) {
+	dim, n, bounds, err := readDiskHeader(io.NewSectionReader(r, 0, math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+	page := DiskPageRecords * (diskRecordSize(dim) + 8*int64(dim))
+	return &DiskTree{
+		r:      r,
+		dim:    dim,
+		n:      int64(n),
+		bounds: bounds,
+		lru:    list.New(),
+		pages:  map[int64]*list.Element{},
+		max:    max(1, int(int64(cacheBytes)/page)),
+	}, nil
+}
+
+// Len returns the number of nodes in t, including removed points.
+func (t *DiskTree) Len() int { return int(t.n) }
+
+// Bounds returns the bounds of t.
+func (t *DiskTree) Bounds() HyperRect { return t.bounds.Copy() }
+
+// Err returns the first error reading t, if any.
+func (t *DiskTree) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// node returns record i, or false if it cannot be read.
+func (t *DiskTree) node(i int64) (*diskNode, bool) {
+	no := i / DiskPageRecords
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.pages[no]; ok {
+		t.lru.MoveToFront(e)
+		return &e.Value.(*diskPage).nodes[i%DiskPageRecords], true
+	}
+	if t.err != nil {
+		return nil, false
+	}
+	p, err := t.readPage(no)
+	if err != nil {
+		t.err = err
+		return nil, false
+	}
+	t.pages[no] = t.lru.PushFront(p)
+	if t.lru.Len() > t.max {
+		old := t.lru.Remove(t.lru.Back()).(*diskPage)
+		delete(t.pages, old.no)
+	}
+	return &p.nodes[i%DiskPageRecords], true
+}
+
+func (t *DiskTree) readPage(no int64) (*diskPage, error) {
+	rs := diskRecordSize(t.dim)
+	first := no * DiskPageRecords
+	nr := min(DiskPageRecords, t.n-first)
+	buf := make([]byte, nr*rs)
+	if _, err := t.r.ReadAt(buf, diskHeaderSize(t.dim)+first*rs); err != nil {
+		return nil, err
+	}
+	p := &diskPage{no: no, nodes: make([]diskNode, nr)}
+	var kd kdNode
+	for i := range p.nodes {
+		rec := buf[int64(i)*rs:]
+		flags := decodeDiskNode(rec, &kd, t.dim)
+		if kd.split >= t.dim {
+			return nil, errDisk
+		}
+		p.nodes[i] = diskNode{pt: kd.domElt, index: kd.index, split: kd.split,
+			flags: flags, right: int64(binary.LittleEndian.Uint64(rec[16:]))}
+	}
+	return p, nil
+}
+
+// Nearest finds the nearest neighbor of p, as KdTree.Nearest.
+func (t *DiskTree) Nearest(p Point) (best Point, bestSqd float64, nv int) {
+	n, nv := t.KNearestNeighbors(p, 1)
+	if len(n) == 0 {
+		return nil, math.Inf(1), nv
+	}
+	return n[0].Point, n[0].Sqd, nv
+}
+
+// KNearest finds the k nearest neighbors of p, as KdTree.KNearest.
+func (t *DiskTree) KNearest(p Point, k int) (nn []Point, sqd []float64, nv int) {
+	n, nv := t.KNearestNeighbors(p, k)
+	sqd = make([]float64, len(n))
+	for i, nb := range n {
+		sqd[i] = nb.Sqd
+	}
+	return n.Points(), sqd, nv
+}
+
+// KNearestNeighbors finds the k nearest neighbors of p, nearest first,
+// and returns them with a count of the nodes visited.
+func (t *DiskTree) KNearestNeighbors(p Point, k int) (Neighbors, int) {
+	if t.n == 0 || k <= 0 {
+		return nil, 0
+	}
+	h := NewKHeap(k)
+	off := make([]float64, len(p))
+	nv := 0
+	t.knn(0, p, off, initOff(off, p, t.bounds), h, &nv)
+	return h.Results(), nv
+}
+
+// knn searches the subtree at record i, whose cell is offset from target
+// by off, with rd the sum of squared offsets.
+func (t *DiskTree) knn(i int64, target Point, off []float64, rd float64,
+	h *KHeap, nv *int) {
+	n, ok := t.node(i)
+	if !ok {
+		return
+	}
+	*nv++
+	s := n.split
+	d := target[s] - n.pt[s]
+	near, far := i+1, n.right
+	hasNear, hasFar := n.flags&diskLeft != 0, n.flags&diskRight != 0
+	if d > 0 {
+		near, far = far, near
+		hasNear, hasFar = hasFar, hasNear
+	}
+	if hasNear {
+		t.knn(near, target, off, rd, h, nv)
+	}
+	if n.flags&diskDeleted == 0 {
+		h.Push(Neighbor{Point: n.pt, Index: n.index, Sqd: n.pt.Sqd(target)})
+	}
+	if rd2 := rd - off[s]*off[s] + d*d; hasFar && rd2 <= h.Worst() {
+		save := off[s]
+		off[s] = d
+		t.knn(far, target, off, rd2, h, nv)
+		off[s] = save
+	}
+}
+
+// InRange returns the points of t within hr.
+func (t *DiskTree) InRange(hr HyperRect) (pts []Point) {
+	t.InRangeFunc(hr, func(p Point) bool {
+		pts = append(pts, p)
+		return true
+	})
+	return
+}
+
+// InRangeFunc calls f with each point of t within hr, stopping early if f
+// returns false.
+func (t *DiskTree) InRangeFunc(hr HyperRect, f func(Point) bool) {
+	if t.n > 0 {
+		t.inRange(0, hr, func(p Point) bool { return !hr.Contains(p) || f(p) })
+	}
+}
+
+// InRadius returns the points of t within distance r of p.
+func (t *DiskTree) InRadius(p Point, r float64) (pts []Point) {
+	box := toleranceBox(p, slices.Repeat([]float64{r}, len(p)))
+	t.inRange(0, box, func(q Point) bool {
+		if q.Sqd(p) <= r*r {
+			pts = append(pts, q)
+		}
+		return true
+	})
+	return
+}
+
+// inRange calls f with the points of the subtree at record i that may lie
+// in hr.  It returns false if f does.
+func (t *DiskTree) inRange(i int64, hr HyperRect, f func(Point) bool) bool {
+	n, ok := t.node(i)
+	if !ok {
+		return false
+	}
+	s := n.split
+	if n.flags&diskDeleted == 0 && !f(n.pt) {
+		return false
+	}
+	if n.flags&diskLeft != 0 && hr.Min[s] <= n.pt[s] &&
+		!t.inRange(i+1, hr, f) {
+		return false
+	}
+	return n.flags&diskRight == 0 || hr.Max[s] <= n.pt[s] ||
+		t.inRange(n.right, hr, f)
+}
@@ -0,0 +1,56 @@
+package kdtree
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDiskTree(t *testing.T) {
+	pts := randomPts(3, 3000)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	kd.Remove(pts[7])
+	name := filepath.Join(t.TempDir(), "tree")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = kd.WriteDisk(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if f, err = os.Open(name); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// a small cache, so pages are evicted and reread
+	dt, err := OpenDisk(f, 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dt.Len() != len(pts) {
+		t.Fatal("Len", dt.Len())
+	}
+	for _, p := range append(randomPts(3, 100), pts[7]) {
+		want, _, _ := kd.KNearest(p, 4)
+		got, sqd, _ := dt.KNearest(p, 4)
+		if !slices.EqualFunc(got, want, equal) || sqd[0] != want[0].Sqd(p) {
+			t.Fatal("KNearest", p, "got", got, "expected", want)
+		}
+		if n, _, _ := dt.Nearest(p); !equal(n, want[0]) {
+			t.Fatal("Nearest", p, "got", n, "expected", want[0])
+		}
+		hr := HyperRect{Point{p[0] - .1, p[1] - .1, p[2] - .1},
+			Point{p[0] + .1, p[1] + .1, p[2] + .1}}
+		if got, want := dt.InRange(hr), kd.InRange(hr); len(got) != len(want) {
+			t.Fatal("InRange got", len(got), "expected", len(want))
+		}
+		if got, want := dt.InRadius(p, .1), kd.InRadius(p, .1); len(got) != len(want) {
+			t.Fatal("InRadius got", len(got), "expected", len(want))
+		}
+	}
+	if err := dt.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+)
+
+// ExternalOptions configures BuildExternal.
+type ExternalOptions struct {
+	// TempDir is the directory for temporary files, os.TempDir if empty.
+	TempDir string
+	// MemLimit is about the most memory, in bytes, used to hold points.
+	// Zero means 256 MiB.
+	MemLimit int64
+}
+
+// externalSample is the number of points sampled to choose a pivot.
+const externalSample = 1001
+
+// BuildExternal builds a tree over more points than fit in memory and
+// writes it to w in the disk format read by ReadDisk and OpenDisk.
+//
+// Points are read from r as raw little endian float64 coordinates, dim
+// per point, and given indexes in the order read.  Sets of points larger
+// than opts.MemLimit are partitioned through temporary files: a pivot is
+// chosen as the median of a sample in the split dimension, and the
+// points are streamed into files for the two sides.  Pivots are only
+// approximate medians, so the top levels of the tree are nearly rather
+// than exactly balanced.  Sets that fit in memory are built as by New.
+func BuildExternal(r io.Reader, dim int, w io.Writer, opts ExternalOptions) error {
+	if dim <= 0 {
+		return fmt.Errorf("kdtree: invalid dimension %d", dim)
+	}
+	if opts.MemLimit <= 0 {
+		opts.MemLimit = 256 << 20
+	}
+	b := &extBuild{dim: dim, opts: opts, rng: rand.New(rand.NewSource(1))}
+	// copy the input with indexes, finding count and bounds.
+	in, n, bounds, err := b.ingest(r)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(in.Name())
+	defer in.Close()
+	b.w, err = newDiskWriter(w, dim, n, bounds)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		if err = b.build(in, n, 0, 0); err != nil {
+			return err
+		}
+	}
+	return b.w.flush()
+}
+
+type extBuild struct {
+	dim  int
+	opts ExternalOptions
+	rng  *rand.Rand
+	w    *diskWriter
+}
+
+// a temporary record is the index as a uint64 then the coordinates.
+func (b *extBuild) recSize() int64 { return 8 + 8*int64(b.dim) }
+
+func (b *extBuild) temp() (*os.File, error) {
+	return os.CreateTemp(b.opts.TempDir, "kdtree")
+}
+
+func (b *extBuild) ingest(r io.Reader) (f *os.File, n int64,
+	bounds HyperRect, err error) {
+	if f, err = b.temp(); err != nil {
+		return
+	}
+	bw := bufio.NewWriter(f)
+	br := bufio.NewReader(r)
+	coords := make([]byte, 8*b.dim)
+	p := make(Point, b.dim)
+	for ; ; n++ {
+		if _, err = io.ReadFull(br, coords); err != nil {
+			if err == io.EOF {
+				err = nil
+				break
+			}
+			err = fmt.Errorf("kdtree: reading point %d: %w", n, err)
+			break
+		}
+		for i := range p {
+			p[i] = math.Float64frombits(binary.LittleEndian.Uint64(coords[8*i:]))
+		}
+		if n == 0 {
+			bounds = HyperRect{append(Point{}, p...), append(Point{}, p...)}
+		} else {
+			bounds.extend(HyperRect{p, p})
+		}
+		binary.Write(bw, binary.LittleEndian, uint64(n))
+		bw.Write(coords)
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, 0, bounds, err
+	}
+	return f, n, bounds, nil
+}
+
+// records calls fn with each of the n records of f.
+func (b *extBuild) records(f *os.File, n int64, fn func(e elt, rec []byte) error) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	br := bufio.NewReader(f)
+	rec := make([]byte, b.recSize())
+	p := make(Point, b.dim)
+	for i := int64(0); i < n; i++ {
+		if _, err := io.ReadFull(br, rec); err != nil {
+			return err
+		}
+		for d := range p {
+			p[d] = math.Float64frombits(binary.LittleEndian.Uint64(rec[8+8*d:]))
+		}
+		e := elt{domElt: p, index: int(binary.LittleEndian.Uint64(rec))}
+		if err := fn(e, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// build writes the subtree of the n records of f, splitting first on
+// split, as disk records numbered from first.
+func (b *extBuild) build(f *os.File, n int64, split int, first int64) error {
+	if n*b.recSize() <= b.opts.MemLimit {
+		nodes := make([]*kdNode, 0, n)
+		slab := make([]kdNode, n)
+		err := b.records(f, n, func(e elt, _ []byte) error {
+			kd := &slab[len(nodes)]
+			kd.elt = e
+			kd.domElt = append(Point{}, e.domElt...)
+			nodes = append(nodes, kd)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return b.w.subtree(nk2(nodes, split, -1, SplitCycle), first)
+	}
+	// choose the pivot, the median of a reservoir sample.
+	sample := make([]elt, 0, externalSample)
+	seen := 0
+	err := b.records(f, n, func(e elt, _ []byte) error {
+		seen++
+		if len(sample) < externalSample {
+			e.domElt = append(Point{}, e.domElt...)
+			sample = append(sample, e)
+		} else if j := b.rng.Intn(seen); j < externalSample {
+			sample[j].domElt = append(sample[j].domElt[:0], e.domElt...)
+			sample[j].index = e.index
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sampleNodes := make([]*kdNode, len(sample))
+	for i := range sample {
+		sampleNodes[i] = &kdNode{elt: sample[i]}
+	}
+	sortDim(sampleNodes, split)
+	pivot := sampleNodes[len(sampleNodes)/2].elt
+	v := pivot.domElt[split]
+	// partition
+	left, err := b.temp()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(left.Name())
+	defer left.Close()
+	right, err := b.temp()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(right.Name())
+	defer right.Close()
+	lw, rw := bufio.NewWriter(left), bufio.NewWriter(right)
+	var nl, nr int64
+	err = b.records(f, n, func(e elt, rec []byte) error {
+		switch {
+		case e.index == pivot.index:
+			return nil
+		case e.domElt[split] <= v:
+			nl++
+			_, err := lw.Write(rec)
+			return err
+		}
+		nr++
+		_, err := rw.Write(rec)
+		return err
+	})
+	if err == nil {
+		err = lw.Flush()
+	}
+	if err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	s2 := split + 1
+	if s2 == b.dim {
+		s2 = 0
+	}
+	if err = b.w.node(pivot, split, nl > 0, nr > 0, first+1+nl); err != nil {
+		return err
+	}
+	if nl > 0 {
+		if err = b.build(left, nl, s2, first+1); err != nil {
+			return err
+		}
+	}
+	if nr > 0 {
+		err = b.build(right, nr, s2, first+1+nl)
+	}
+	return err
+}
@@ -0,0 +1,244 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// Forest is a set of randomized k-d trees over the same points, searched
+// jointly for approximate nearest neighbors.
+//
+// This follows the randomized kd-forest of FLANN (Muja and Lowe).  Each
+// tree chooses the split dimension at each node at random from the few
+// dimensions of greatest variance, so the trees partition space
+// differently.  A search descends all trees best bin first from a shared
+// priority queue and stops after a budget of nodes, so that a region
+// poorly covered by one tree is likely well covered by another.  For
+// high dimensional data, where a single exact search must visit most of
+// the tree, this gives good neighbors at a small fraction of the cost.
+type Forest struct {
+	trees []*kdNode
+}
+
+// forestDims is the number of highest variance dimensions from which
+// forest trees choose split dimensions.
+const forestDims = 5
+
+// NewForest builds a Forest of m trees over pts.  seed seeds the random
+// choices of split dimensions, making construction reproducible.
+func NewForest(pts []Point, m int, seed int64) *Forest {
+	rng := rand.New(rand.NewSource(seed))
+	f := &Forest{}
+	for i := 0; i < m; i++ {
+		f.trees = append(f.trees, nkRand(newNodes(pts, nil, 0), rng))
+	}
+	return f
+}
+
+// nkRand links nodes into a subtree as nk2 does, but splitting on a
+// dimension chosen at random from those of greatest variance.
+func nkRand(nodes []*kdNode, rng *rand.Rand) *kdNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+	k := len(nodes[0].domElt)
+	mean := make([]float64, k)
+	for _, n := range nodes {
+		for i, c := range n.domElt {
+			mean[i] += c
+		}
+	}
+	v := make([]float64, k)
+	for _, n := range nodes {
+		for i, c := range n.domElt {
+			d := c - mean[i]/float64(len(nodes))
+			v[i] += d * d
+		}
+	}
+	dims := make([]int, k)
+	for i := range dims {
+		dims[i] = i
+	}
+	sort.Slice(dims, func(i, j int) bool { return v[dims[i]] > v[dims[j]] })
+	if len(dims) > forestDims {
+		dims = dims[:forestDims]
+	}
+	split := dims[rng.Intn(len(dims))]
+	sortDim(nodes, split)
+	m := pivotIndex(nodes, split)
+	kd := nodes[m]
+	kd.split = split
+	kd.size = len(nodes)
+	kd.left = nkRand(nodes[:m], rng)
+	kd.right = nkRand(nodes[m+1:], rng)
+	return kd
+}
+
+// Nearest finds an approximate nearest neighbor of p, as KNearest with
+// k = 1.
+func (f *Forest) Nearest(p Point, budget int) (best Point, bestSqd float64,
+	nv int) {
+	nn, sqd, nv := f.KNearest(p, 1, budget)
+	if len(nn) == 0 {
+		return nil, math.Inf(1), nv
+	}
+	return nn[0], sqd[0], nv
+}
+
+// KNearest finds approximate k nearest neighbors of p, visiting about
+// budget nodes over all trees.  If budget <= 0 the search runs to
+// completion and is exact.
+//
+// Results are nearest first.  nv is the number of nodes visited.
+func (f *Forest) KNearest(p Point, k, budget int) (nn []Point, sqd []float64,
+	nv int) {
+	h, _, nv := f.search(p, k, budget)
+	for _, e := range h.e {
+		nn = append(nn, e.Point)
+		sqd = append(sqd, e.Sqd)
+	}
+	return
+}
+
+// KNearestEps finds approximate k nearest neighbors of p as KNearest,
+// and reports eps, a measure of the quality of the result actually
+// achieved.
+//
+// The distance to the k-th neighbor found is within a factor of 1+eps of
+// the distance to the true k-th nearest neighbor.  eps is zero when the
+// search completed within the budget, so the result is exact.  It is
+// computed from the least distance to any branch left unsearched, so it
+// is a guaranteed bound and is often pessimistic.
+func (f *Forest) KNearestEps(p Point, k, budget int) (n Neighbors, eps float64,
+	nv int) {
+	h, lb, nv := f.search(p, k, budget)
+	n = Neighbors(h.e)
+	if len(n) == 0 {
+		return
+	}
+	if w := n[len(n)-1].Sqd; lb < w {
+		if lb <= 0 {
+			return n, math.Inf(1), nv
+		}
+		eps = math.Sqrt(w/lb) - 1
+	}
+	return
+}
+
+// search leaves the results of KNearest sorted in h.  lb is the least
+// squared distance of a point not visited, +Inf if the search completed.
+func (f *Forest) search(p Point, k, budget int) (h KHeap, lb float64, nv int) {
+	h.Reset(k)
+	lb = math.Inf(1)
+	if k <= 0 {
+		return
+	}
+	var q branchQueue
+	for _, kd := range f.trees {
+		if kd != nil {
+			q.push(branch{kd, 0})
+		}
+	}
+	for len(q) > 0 && (budget <= 0 || nv < budget) {
+		b := q.pop()
+		if b.rd > h.Worst() {
+			q = q[:0]
+			break
+		}
+		for kd := b.kd; kd != nil; {
+			if budget > 0 && nv >= budget {
+				// the rest of this descent goes unsearched.
+				q.push(branch{kd, b.rd})
+				break
+			}
+			nv++
+			if d := kd.domElt.Sqd(p); d < h.Worst() && !h.has(kd.index) {
+				h.Push(kd.neighbor(d))
+			}
+			s := kd.split
+			d := p[s] - kd.domElt[s]
+			near, far := kd.left, kd.right
+			if d > 0 {
+				near, far = far, near
+			}
+			// the bound for far is the larger of the bound for this
+			// node and the distance to the splitting plane.
+			if far != nil {
+				rd := b.rd
+				if d*d > rd {
+					rd = d * d
+				}
+				if rd <= h.Worst() {
+					q.push(branch{far, rd})
+				}
+			}
+			kd = near
+		}
+	}
+	if len(q) > 0 {
+		lb = q[0].rd
+	}
+	slices.SortFunc(h.e, compareNeighbors)
+	return
+}
+
+func (h *KHeap) has(i int) bool {
+	for _, e := range h.e {
+		if e.Index == i {
+			return true
+		}
+	}
+	return false
+}
+
+// branch is an unexplored subtree and a lower bound on the squared
+// distance to its points.
+type branch struct {
+	kd *kdNode
+	rd float64
+}
+
+// branchQueue is a min-heap of branches by rd.
+type branchQueue []branch
+
+func (q *branchQueue) push(b branch) {
+	*q = append(*q, b)
+	h := *q
+	for i := len(h) - 1; i > 0; {
+		up := (i - 1) / 2
+		if h[up].rd <= h[i].rd {
+			break
+		}
+		h[up], h[i] = h[i], h[up]
+		i = up
+	}
+}
+
+func (q *branchQueue) pop() branch {
+	h := *q
+	b := h[0]
+	last := len(h) - 1
+	h[0] = h[last]
+	h = h[:last]
+	for i := 0; ; {
+		c := 2*i + 1
+		if c >= len(h) {
+			break
+		}
+		if c+1 < len(h) && h[c+1].rd < h[c].rd {
+			c++
+		}
+		if h[i].rd <= h[c].rd {
+			break
+		}
+		h[i], h[c] = h[c], h[i]
+		i = c
+	}
+	*q = h
+	return b
+}
@@ -0,0 +1,64 @@
+package kdtree
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestForest(t *testing.T) {
+	const dim = 20
+	pts := randomPts(dim, 2000)
+	min, max := make(Point, dim), make(Point, dim)
+	for i := range max {
+		max[i] = 1
+	}
+	ref := New(append([]Point{}, pts...), HyperRect{min, max})
+	f := NewForest(pts, 4, 1)
+	found := 0
+	for i := 0; i < 20; i++ {
+		p := randomPt(dim)
+		_, want, _ := ref.KNearest(p, 5)
+		sort.Float64s(want)
+		// unlimited budget is exact
+		_, got, _ := f.KNearest(p, 5, 0)
+		sort.Float64s(got)
+		for j := range want {
+			if got[j] != want[j] {
+				t.Fatal("Expected distances^2", want, "found", got)
+			}
+		}
+		_, sqd, nv := f.Nearest(p, 200)
+		if nv > 200+4*dim {
+			t.Error("Expected about 200 nodes visited, found", nv)
+		}
+		if sqd == want[0] {
+			found++
+		}
+	}
+	if found < 5 {
+		t.Error("Expected approximate search to often find the nearest,",
+			"found", found, "of 20")
+	}
+}
+
+func TestForestEps(t *testing.T) {
+	pts := randomPts(8, 5000)
+	f := NewForest(pts, 4, 1)
+	kd := New(append([]Point{}, pts...), HyperRect{make(Point, 8), Point{1, 1, 1, 1, 1, 1, 1, 1}})
+	for i := 0; i < 20; i++ {
+		p := randomPt(8)
+		if _, eps, _ := f.KNearestEps(p, 5, 0); eps != 0 {
+			t.Fatal("exact search reported eps", eps)
+		}
+		n, eps, nv := f.KNearestEps(p, 5, 200)
+		if nv > 200 {
+			t.Fatal("visited", nv, "nodes, budget 200")
+		}
+		_, want, _ := kd.KNearest(p, 5)
+		got := math.Sqrt(n[4].Sqd)
+		if bound := math.Sqrt(want[4]) * (1 + eps); got > bound*(1+1e-12) {
+			t.Fatal("distance", got, "exceeds reported bound", bound)
+		}
+	}
+}
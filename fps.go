@@ -0,0 +1,96 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// FarthestPointSample selects n points of t by farthest point sampling.
+// The first is the point nearest seed, and each following point is the
+// one farthest from all points selected so far.  The result is in order
+// of selection, with Sqd the square of the distance from the point to
+// the nearest point selected before it, or for the first point, to seed.
+// Fewer than n points are returned if t has fewer distinct points.
+//
+// Each point keeps its squared distance to the nearest selected point,
+// and each subtree the greatest such distance.  Selecting a point updates
+// only subtrees whose boxes are nearer to it than their greatest
+// distance, and the next point is found by descending along greatest
+// distances, so that each step is typically much less than a pass over
+// all points.
+func (t KdTree) FarthestPointSample(n int, seed Point) Neighbors {
+	if n <= 0 {
+		return nil
+	}
+	first := t.KNearestNeighbors(seed, 1)
+	if len(first) == 0 {
+		return nil
+	}
+	// d holds the distances of points and far the greatest distances of
+	// subtrees, by index of point and of subtree root.
+	d := make([]float64, t.next)
+	far := make([]float64, t.next)
+	for i := range d {
+		d[i], far[i] = math.Inf(1), math.Inf(1)
+	}
+	// update lowers distances by those to p.  Subtrees are entered in
+	// preorder and their greatest distances found after, children first,
+	// by going back through the subtrees entered.
+	var entered []*kdNode
+	update := func(p Point) {
+		entered = entered[:0]
+		c := newCellStack(t, 0.)
+		for {
+			kd, _, ok := c.pop()
+			if !ok {
+				break
+			}
+			box := c.cell
+			if kd.bounds != nil {
+				box = *kd.bounds
+			}
+			if t.excludes(kd) {
+				far[kd.index] = -1 // no point in view
+				continue
+			}
+			if box.Sqd(p) >= far[kd.index] {
+				continue
+			}
+			if t.sees(kd) {
+				d[kd.index] = math.Min(d[kd.index], kd.domElt.Sqd(p))
+			}
+			entered = append(entered, kd)
+			c.push(kd, kd.right, 0)
+			c.push(kd, kd.left, 0)
+		}
+		for i := len(entered) - 1; i >= 0; i-- {
+			kd := entered[i]
+			m := -1. // no point in view
+			if t.sees(kd) {
+				m = d[kd.index]
+			}
+			for _, c := range []*kdNode{kd.left, kd.right} {
+				if c != nil {
+					m = math.Max(m, far[c.index])
+				}
+			}
+			far[kd.index] = m
+		}
+	}
+	r := first
+	update(r[0].Point)
+	for len(r) < n && far[t.n.index] > 0 {
+		// descend to the point of greatest distance.
+		kd := t.n
+		for !t.sees(kd) || d[kd.index] != far[kd.index] {
+			if kd.left != nil && far[kd.left.index] == far[kd.index] {
+				kd = kd.left
+			} else {
+				kd = kd.right
+			}
+		}
+		r = append(r, kd.neighbor(d[kd.index]))
+		update(kd.domElt)
+	}
+	return r
+}
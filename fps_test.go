@@ -0,0 +1,56 @@
+package kdtree
+
+import "testing"
+
+func TestFarthestPointSample(t *testing.T) {
+	pts := randomPts(2, 3000)
+	kd := NewWith(append([]Point{}, pts...))
+	kd.Remove(pts[11])
+	seed := Point{.5, .5}
+	got := kd.FarthestPointSample(50, seed)
+	// brute force selection
+	var sel []int
+	best, bd := -1, 0.
+	for i, p := range pts {
+		if d := p.Sqd(seed); i != 11 && (best < 0 || d < bd) {
+			best, bd = i, d
+		}
+	}
+	sel = append(sel, best)
+	d := make([]float64, len(pts))
+	for len(sel) < 50 {
+		best, bd = -1, -1
+		for i, p := range pts {
+			d[i] = p.Sqd(pts[sel[0]])
+			for _, j := range sel[1:] {
+				d[i] = min(d[i], p.Sqd(pts[j]))
+			}
+			if i != 11 && d[i] > bd {
+				best, bd = i, d[i]
+			}
+		}
+		sel = append(sel, best)
+	}
+	if len(got) != 50 {
+		t.Fatal("got", len(got), "points")
+	}
+	for i, n := range got {
+		if n.Index != sel[i] {
+			t.Fatal("point", i, "index", n.Index, "expected", sel[i])
+		}
+	}
+	// all distinct points of a small tree
+	few := NewWith([]Point{{0, 0}, {1, 1}, {1, 1}, {2, 0}})
+	if n := len(few.FarthestPointSample(10, Point{0, 0})); n != 3 {
+		t.Fatal("sampled", n, "of 3 distinct points")
+	}
+}
+
+func TestFarthestPointSampleDeep(t *testing.T) {
+	kd, pts := deepTree(3000)
+	got := kd.FarthestPointSample(3, Point{0, 0})
+	if len(got) != 3 || !equal(got[0].Point, pts[0]) ||
+		!equal(got[1].Point, pts[len(pts)-1]) {
+		t.Fatal(got)
+	}
+}
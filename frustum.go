@@ -0,0 +1,77 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// Frustum returns the view frustum of a perspective camera in 3D as a
+// Polytope of six half-spaces, for use with InPolytope and related
+// queries.  The camera is at eye looking along dir, with up giving the
+// upward direction of the view.  fovY is the vertical field of view in
+// radians, aspect the ratio of width to height, and near and far the
+// distances along dir of the near and far clipping planes.
+//
+// The search prunes subtrees with boxes entirely outside any plane and
+// reports those entirely inside all six without testing their points.
+func Frustum(eye, dir, up Point, fovY, aspect, near, far float64) Polytope {
+	f := unit(dir)
+	r := unit(cross(f, up))
+	u := cross(r, f)
+	ty := math.Tan(fovY / 2)
+	tx := aspect * ty
+	side := func(n Point, t float64) HalfSpace {
+		// the plane through eye containing the edge of the view
+		s := Point{n[0] + t*f[0], n[1] + t*f[1], n[2] + t*f[2]}
+		return HalfSpace{s, dot(s, eye)}
+	}
+	neg := func(p Point) Point { return Point{-p[0], -p[1], -p[2]} }
+	return Polytope{
+		{f, dot(f, eye) + near},
+		{neg(f), -dot(f, eye) - far},
+		side(r, tx),
+		side(neg(r), tx),
+		side(u, ty),
+		side(neg(u), ty),
+	}
+}
+
+// FrustumMatrix returns the frustum of a combined view and projection
+// matrix m, in row major order, following the OpenGL convention that
+// clip coordinates of points in view lie within -w and w.
+func FrustumMatrix(m [16]float64) Polytope {
+	row := func(i int) [4]float64 {
+		return [4]float64{m[4*i], m[4*i+1], m[4*i+2], m[4*i+3]}
+	}
+	w := row(3)
+	pt := make(Polytope, 0, 6)
+	for i := 0; i < 3; i++ {
+		r := row(i)
+		for _, s := range []float64{1, -1} {
+			// w + s*r >= 0
+			pt = append(pt, HalfSpace{
+				Point{w[0] + s*r[0], w[1] + s*r[1], w[2] + s*r[2]},
+				-(w[3] + s*r[3]),
+			})
+		}
+	}
+	return pt
+}
+
+func cross(a, b Point) Point {
+	return Point{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+// unit returns p scaled to length 1.
+func unit(p Point) Point {
+	l := math.Sqrt(dot(p, p))
+	u := make(Point, len(p))
+	for i, c := range p {
+		u[i] = c / l
+	}
+	return u
+}
@@ -0,0 +1,56 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFrustum(t *testing.T) {
+	pts := randomPts(3, 5000)
+	for _, p := range pts {
+		for i := range p {
+			p[i] = 20*p[i] - 10
+		}
+	}
+	kd := NewWith(append([]Point{}, pts...))
+	fovY, aspect, near, far := math.Pi/3, 1.5, 1., 8.
+	// the OpenGL camera at the origin looking down -z, whose view
+	// matrix is the identity.
+	fr := Frustum(Point{0, 0, 0}, Point{0, 0, -1}, Point{0, 1, 0},
+		fovY, aspect, near, far)
+	c := 1 / math.Tan(fovY/2)
+	m := FrustumMatrix([16]float64{
+		c / aspect, 0, 0, 0,
+		0, c, 0, 0,
+		0, 0, (far + near) / (near - far), 2 * far * near / (near - far),
+		0, 0, -1, 0,
+	})
+	in := func(p Point) bool {
+		z := -p[2]
+		return z >= near && z <= far &&
+			math.Abs(p[1]) <= z/c && math.Abs(p[0]) <= z*aspect/c
+	}
+	want := 0
+	for _, p := range pts {
+		if in(p) {
+			want++
+		}
+		if fr.Contains(p) != in(p) || m.Contains(p) != in(p) {
+			t.Fatal(p, "frustum", fr.Contains(p), "matrix", m.Contains(p),
+				"expected", in(p))
+		}
+	}
+	if got := len(kd.InPolytope(fr)); got != want || want == 0 {
+		t.Fatal("InPolytope", got, "expected", want)
+	}
+	// the same camera moved and turned
+	eye := Point{1, 2, 3}
+	moved := Frustum(eye, Point{1, 0, 0}, Point{0, 0, 1}, fovY, aspect, near, far)
+	for _, p := range pts {
+		// camera coordinates: right is -y, up is z, forward is x
+		q := Point{-(p[1] - eye[1]), p[2] - eye[2], -(p[0] - eye[0])}
+		if moved.Contains(p) != in(q) {
+			t.Fatal(p, "moved frustum", moved.Contains(p))
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// EarthRadius is the mean radius of the Earth in meters, used by GeoTree.
+const EarthRadius = 6371008.8
+
+// LatLon is a geographic position, latitude and longitude in degrees.
+type LatLon struct {
+	Lat, Lon float64
+}
+
+// GeoNeighbor is a position found by a GeoTree query, with its index and
+// data as numbered and given when it was added, and its great circle
+// distance in meters from the query position.
+type GeoNeighbor struct {
+	LatLon
+	Index  int
+	Data   interface{}
+	Meters float64
+}
+
+// GeoTree indexes geographic positions and answers queries in meters.
+//
+// Latitude and longitude are not a Euclidean space, and treating them as
+// Point coordinates gives wrong neighbors, most of all at high latitudes
+// and across the antimeridian.  GeoTree instead holds each position as a
+// point on a sphere of radius EarthRadius in three dimensions.  Straight
+// line distance through the sphere grows with great circle distance, so
+// nearest neighbors by one are nearest by the other, and results are
+// exact for the spherical model.
+type GeoTree struct {
+	t  KdTree
+	ll []LatLon // positions by index
+}
+
+// NewGeoTree builds a GeoTree of pts, with associated data if data is not
+// nil.
+func NewGeoTree(pts []LatLon, data []interface{}) *GeoTree {
+	ps := make([]Point, len(pts))
+	for i, ll := range pts {
+		ps[i] = geoPoint(ll)
+	}
+	opts := []Option{WithBounds(geoBounds())}
+	if data != nil {
+		opts = append(opts, WithData(data))
+	}
+	return &GeoTree{t: NewWith(ps, opts...), ll: append([]LatLon(nil), pts...)}
+}
+
+// Len returns the number of positions in g.
+func (g *GeoTree) Len() int { return len(g.ll) }
+
+// Insert adds position ll with associated data.
+func (g *GeoTree) Insert(ll LatLon, data interface{}) {
+	g.t.InsertWithData(geoPoint(ll), data)
+	g.ll = append(g.ll, ll)
+}
+
+// NearestLatLon returns the position of g nearest to lat, lon.  ok is
+// false if g is empty.
+func (g *GeoTree) NearestLatLon(lat, lon float64) (n GeoNeighbor, ok bool) {
+	nn := g.KNearestLatLon(lat, lon, 1)
+	if len(nn) == 0 {
+		return n, false
+	}
+	return nn[0], true
+}
+
+// KNearestLatLon returns the k positions of g nearest to lat, lon,
+// nearest first.
+func (g *GeoTree) KNearestLatLon(lat, lon float64, k int) []GeoNeighbor {
+	return g.neighbors(g.t.KNearestNeighbors(geoPoint(LatLon{lat, lon}), k), math.Inf(1))
+}
+
+// WithinMeters returns the positions of g within great circle distance m
+// of lat, lon, nearest first.
+func (g *GeoTree) WithinMeters(lat, lon, m float64) []GeoNeighbor {
+	if m < 0 {
+		return nil
+	}
+	// the chord subtending an arc of m.
+	r := 2 * EarthRadius * math.Sin(math.Min(m, math.Pi*EarthRadius)/(2*EarthRadius))
+	// a little slack for rounding; neighbors beyond m are dropped.
+	r = r*(1+1e-12) + 1e-9
+	return g.neighbors(g.t.InRadiusNeighbors(geoPoint(LatLon{lat, lon}), r), m)
+}
+
+// neighbors converts n, which is sorted, to GeoNeighbors within m meters.
+func (g *GeoTree) neighbors(n Neighbors, m float64) []GeoNeighbor {
+	var r []GeoNeighbor
+	for _, nb := range n {
+		d := chordMeters(math.Sqrt(nb.Sqd))
+		if d > m {
+			break
+		}
+		r = append(r, GeoNeighbor{g.ll[nb.Index], nb.Index, nb.Data, d})
+	}
+	return r
+}
+
+// geoPoint is the point on the sphere of radius EarthRadius at ll.
+func geoPoint(ll LatLon) Point {
+	lat := ll.Lat * math.Pi / 180
+	lon := ll.Lon * math.Pi / 180
+	return Point{
+		EarthRadius * math.Cos(lat) * math.Cos(lon),
+		EarthRadius * math.Cos(lat) * math.Sin(lon),
+		EarthRadius * math.Sin(lat),
+	}
+}
+
+func geoBounds() HyperRect {
+	return HyperRect{
+		Point{-EarthRadius, -EarthRadius, -EarthRadius},
+		Point{EarthRadius, EarthRadius, EarthRadius},
+	}
+}
+
+// chordMeters returns the great circle distance subtended by a chord of
+// length c.
+func chordMeters(c float64) float64 {
+	return 2 * EarthRadius * math.Asin(math.Min(1, c/(2*EarthRadius)))
+}
+
+// Haversine returns the great circle distance in meters between a and b
+// on a sphere of radius EarthRadius.
+func Haversine(a, b LatLon) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dlat := lat2 - lat1
+	dlon := (b.Lon - a.Lon) * math.Pi / 180
+	h := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dlon/2)*math.Sin(dlon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
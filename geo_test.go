@@ -0,0 +1,59 @@
+package kdtree
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestGeoTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	pts := make([]LatLon, 2000)
+	for i := range pts {
+		pts[i] = LatLon{math.Asin(2*rng.Float64()-1) * 180 / math.Pi,
+			360*rng.Float64() - 180}
+	}
+	g := NewGeoTree(pts[:1500], nil)
+	for _, ll := range pts[1500:] {
+		g.Insert(ll, nil)
+	}
+	if g.Len() != len(pts) {
+		t.Fatal("Len", g.Len())
+	}
+	// include queries near the poles and across the antimeridian
+	for _, q := range []LatLon{{0, 0}, {89.9, 10}, {-60, 179.99}, {45, -179.99}} {
+		want := make([]float64, len(pts))
+		for i, ll := range pts {
+			want[i] = Haversine(q, ll)
+		}
+		sort.Float64s(want)
+		nn := g.KNearestLatLon(q.Lat, q.Lon, 10)
+		if len(nn) != 10 {
+			t.Fatal("got", len(nn), "neighbors")
+		}
+		for i, n := range nn {
+			if math.Abs(n.Meters-want[i]) > 1e-3 ||
+				math.Abs(Haversine(q, n.LatLon)-n.Meters) > 1e-3 {
+				t.Fatal(q, "neighbor", i, n, "expected", want[i])
+			}
+		}
+		n, ok := g.NearestLatLon(q.Lat, q.Lon)
+		if !ok || n.Index != nn[0].Index {
+			t.Fatal(q, "Nearest", n, ok)
+		}
+		m := want[20] + 1
+		w := g.WithinMeters(q.Lat, q.Lon, m)
+		if len(w) != 21 {
+			t.Fatal(q, "WithinMeters", m, "found", len(w))
+		}
+		for i := 1; i < len(w); i++ {
+			if w[i].Meters < w[i-1].Meters {
+				t.Fatal("WithinMeters not sorted")
+			}
+		}
+	}
+	if _, ok := NewGeoTree(nil, nil).NearestLatLon(0, 0); ok {
+		t.Fatal("found neighbor in empty tree")
+	}
+}
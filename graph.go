@@ -0,0 +1,106 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Edge is an edge of a neighborhood graph, joining the points of indexes
+// I and J, with I < J, at distance Dist.
+type Edge struct {
+	I, J int
+	Dist float64
+}
+
+// KNNGraph returns the edges of the undirected k nearest neighbor graph
+// of t, joining each point to its k nearest neighbors, in order of I then
+// J.  An edge is listed once even if each point is among the k nearest of
+// the other.
+func (t KdTree) KNNGraph(k int) []Edge {
+	return collectEdges(func(f func(Edge) bool) { t.KNNGraphFunc(k, f) })
+}
+
+// KNNGraphFunc calls f for each edge of the k nearest neighbor graph, as
+// KNNGraph, in no particular order, stopping early if f returns false.
+//
+// Edges suit building the graphs of other packages directly, such as
+// with the SetWeightedEdge method of a gonum WeightedUndirectedGraph,
+// without an intermediate format.
+func (t KdTree) KNNGraphFunc(k int, f func(Edge) bool) {
+	// neighbor lists by index, kept to recognize edges found from both
+	// ends.
+	lists := make([][]Neighbor, t.next)
+	s := t.NewSearcher()
+	t.yieldAll(t.n, func(kd *kdNode) bool {
+		s.search(kd.domElt, k+1)
+		l := make([]Neighbor, 0, k)
+		for _, nb := range s.h.e {
+			if nb.Index != kd.index && len(l) < k {
+				l = append(l, Neighbor{Index: nb.Index, Sqd: nb.Sqd})
+			}
+		}
+		lists[kd.index] = l
+		return true
+	})
+	has := func(l []Neighbor, i int) bool {
+		return slices.ContainsFunc(l, func(nb Neighbor) bool { return nb.Index == i })
+	}
+	for i, l := range lists {
+		for _, nb := range l {
+			j := nb.Index
+			if j < i && has(lists[j], i) {
+				continue // listed from j
+			}
+			if !f(Edge{min(i, j), max(i, j), math.Sqrt(nb.Sqd)}) {
+				return
+			}
+		}
+	}
+}
+
+// RadiusGraph returns the edges of the graph joining each pair of points
+// of t within distance r of each other, in order of I then J.
+func (t KdTree) RadiusGraph(r float64) []Edge {
+	return collectEdges(func(f func(Edge) bool) { t.RadiusGraphFunc(r, f) })
+}
+
+// RadiusGraphFunc calls f for each edge of the radius graph, as
+// RadiusGraph, in no particular order, stopping early if f returns false.
+func (t KdTree) RadiusGraphFunc(r float64, f func(Edge) bool) {
+	r2 := r * r
+	t.yieldAll(t.n, func(kd *kdNode) bool {
+		p := kd.domElt
+		box := HyperRect{make(Point, len(p)), make(Point, len(p))}
+		for i, c := range p {
+			box.Min[i] = c - r
+			box.Max[i] = c + r
+		}
+		return t.rangeSearch(box, func(q Point) bool { return q.Sqd(p) <= r2 },
+			func(n *kdNode) bool {
+				// each edge from its lesser index
+				if n.index <= kd.index {
+					return true
+				}
+				return f(Edge{kd.index, n.index, math.Sqrt(n.domElt.Sqd(p))})
+			})
+	})
+}
+
+// collectEdges returns the edges given by each, sorted.
+func collectEdges(each func(func(Edge) bool)) (edges []Edge) {
+	each(func(e Edge) bool {
+		edges = append(edges, e)
+		return true
+	})
+	slices.SortFunc(edges, func(a, b Edge) int {
+		if c := cmp.Compare(a.I, b.I); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.J, b.J)
+	})
+	return
+}
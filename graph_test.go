@@ -0,0 +1,66 @@
+package kdtree
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestNeighborhoodGraphs(t *testing.T) {
+	pts := randomPts(2, 500)
+	kd := NewWith(append([]Point{}, pts...))
+	k := 4
+	// brute force k nearest lists
+	near := make([]map[int]bool, len(pts))
+	for i, p := range pts {
+		idx := make([]int, 0, len(pts)-1)
+		for j := range pts {
+			if j != i {
+				idx = append(idx, j)
+			}
+		}
+		sort.Slice(idx, func(a, b int) bool {
+			return pts[idx[a]].Sqd(p) < pts[idx[b]].Sqd(p)
+		})
+		near[i] = map[int]bool{}
+		for _, j := range idx[:k] {
+			near[i][j] = true
+		}
+	}
+	want := 0
+	for i := range pts {
+		for j := i + 1; j < len(pts); j++ {
+			if near[i][j] || near[j][i] {
+				want++
+			}
+		}
+	}
+	edges := kd.KNNGraph(k)
+	if len(edges) != want {
+		t.Fatal("kNN graph has", len(edges), "edges, want", want)
+	}
+	for i, e := range edges {
+		if e.I >= e.J || !near[e.I][e.J] && !near[e.J][e.I] ||
+			math.Abs(e.Dist-math.Sqrt(pts[e.I].Sqd(pts[e.J]))) > 1e-15 ||
+			i > 0 && e.I == edges[i-1].I && e.J <= edges[i-1].J {
+			t.Fatal("edge", e)
+		}
+	}
+	r := .05
+	want = 0
+	for i, p := range pts {
+		for j := i + 1; j < len(pts); j++ {
+			if p.Sqd(pts[j]) <= r*r {
+				want++
+			}
+		}
+	}
+	if n := len(kd.RadiusGraph(r)); n != want || want == 0 {
+		t.Fatal("radius graph has", n, "edges, want", want)
+	}
+	n := 0
+	kd.RadiusGraphFunc(r, func(Edge) bool { n++; return false })
+	if n != 1 {
+		t.Fatal("did not stop early:", n)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"iter"
+	"math"
+)
+
+// NearestSeq returns an iterator over the points of t in order of
+// increasing distance from p.
+//
+// The search is incremental: each point is found as the iteration reaches
+// it, with work proportional to the points taken so far, so the number of
+// neighbors wanted need not be known in advance.  Points at equal
+// distances come in no particular order.
+func (t KdTree) NearestSeq(p Point) iter.Seq[Neighbor] {
+	return func(yield func(Neighbor) bool) {
+		t.incremental(p, false, yield)
+	}
+}
+
+// FarthestSeq returns an iterator over the points of t in order of
+// decreasing distance from p, found incrementally as for NearestSeq.
+func (t KdTree) FarthestSeq(p Point) iter.Seq[Neighbor] {
+	return func(yield func(Neighbor) bool) {
+		t.incremental(p, true, yield)
+	}
+}
+
+// incremental is the best-first search of Hjaltason and Samet, calling
+// yield with each point in turn until it returns false.
+func (t KdTree) incremental(p Point, farthest bool, yield func(Neighbor) bool) {
+	s := t.newIncSearch(p, farthest)
+	for {
+		n, ok := s.next()
+		if !ok || !yield(n) {
+			return
+		}
+	}
+}
+
+// incSearch is the state of an incremental search.  A queue holds both
+// subtrees, keyed by the least distance from p to any point they may
+// hold, and points, keyed by their distance.  A point at the front of the
+// queue is nearer than anything left, so it is the next result.  For
+// farthest first, keys are the negated greatest distances.
+type incSearch struct {
+	t        KdTree
+	p        Point
+	farthest bool
+	q        incQueue
+	min      float64 // squared distance of points to skip, nearest first
+}
+
+func (t KdTree) newIncSearch(p Point, farthest bool) *incSearch {
+	s := &incSearch{t: t, p: p, farthest: farthest}
+	if t.n != nil {
+		s.push(t.n, t.Bounds.Copy())
+	}
+	return s
+}
+
+func (s *incSearch) push(kd *kdNode, cell HyperRect) {
+	kd.force()
+	if s.t.excludes(kd) {
+		return
+	}
+	box := cell
+	if kd.bounds != nil {
+		box = *kd.bounds
+	}
+	if s.farthest {
+		s.q.push(incEntry{kd: kd, cell: cell, key: -box.farSqd(s.p)})
+	} else {
+		s.q.push(incEntry{kd: kd, cell: cell, key: box.Sqd(s.p)})
+	}
+}
+
+// next returns the next point of the search, or false if there are no
+// more.
+func (s *incSearch) next() (Neighbor, bool) {
+	for len(s.q) > 0 {
+		e := s.q.pop()
+		kd := e.kd
+		if e.point {
+			if d := kd.domElt.Sqd(s.p); d >= s.min {
+				return kd.neighbor(d), true
+			}
+			continue
+		}
+		if s.min > 0 {
+			box := e.cell
+			if kd.bounds != nil {
+				box = *kd.bounds
+			}
+			if box.farSqd(s.p) < s.min {
+				continue // all nearer than the bound
+			}
+		}
+		if s.t.sees(kd) {
+			key := kd.domElt.Sqd(s.p)
+			if s.farthest {
+				key = -key
+			}
+			s.q.push(incEntry{kd: kd, point: true, key: key})
+		}
+		sp := kd.split
+		if kd.right != nil {
+			c := e.cell
+			if kd.left != nil {
+				c = e.cell.Copy()
+			}
+			c.Min[sp] = kd.domElt[sp]
+			s.push(kd.right, c)
+		}
+		if kd.left != nil {
+			c := e.cell
+			c.Max[sp] = kd.domElt[sp]
+			s.push(kd.left, c)
+		}
+	}
+	return Neighbor{}, false
+}
+
+// NearestCursor pages through the points of a tree in order of
+// increasing distance from a point, as NearestSeq, keeping its place
+// between calls.
+//
+// A cursor reads the tree as each page is taken, so the tree must not be
+// changed while the cursor is in use.
+type NearestCursor struct {
+	s *incSearch
+}
+
+// NewNearestCursor returns a NearestCursor over the points of t in order
+// of increasing distance from p.
+func (t KdTree) NewNearestCursor(p Point) *NearestCursor {
+	return &NearestCursor{t.newIncSearch(p, false)}
+}
+
+// Next returns the next limit points, nearest first, or fewer if fewer
+// remain.  The work is for the points of this page only; earlier pages
+// are not found again.
+func (c *NearestCursor) Next(limit int) (n Neighbors) {
+	for len(n) < limit {
+		nb, ok := c.s.next()
+		if !ok {
+			break
+		}
+		n = append(n, nb)
+	}
+	return
+}
+
+// Seek skips past all points nearer than minDist, so that the next page
+// starts with the nearest point at distance minDist or more, such as the
+// first beyond those already shown.  Subtrees entirely nearer than
+// minDist are passed over without finding their points.  Seeking to a
+// distance already passed has no effect.
+func (c *NearestCursor) Seek(minDist float64) {
+	c.s.min = math.Max(c.s.min, minDist*minDist)
+}
+
+// KNearestPage returns the neighbors of p from position offset, counting
+// from zero for the nearest, up to limit of them, nearest first.
+//
+// The search is incremental, so its work grows with offset + limit
+// rather than with the number of points in t, but the points before
+// offset are still found and passed over.  To fetch successive pages,
+// a NearestCursor avoids that.  Points at equal distances come in the
+// same order from call to call as long as t is unchanged.
+func (t KdTree) KNearestPage(p Point, offset, limit int) Neighbors {
+	c := t.NewNearestCursor(p)
+	for i := 0; i < offset; i++ {
+		if _, ok := c.s.next(); !ok {
+			return nil
+		}
+	}
+	return c.Next(limit)
+}
+
+// incEntry is a subtree with its cell, or if point is set, the point of
+// node kd.
+type incEntry struct {
+	kd    *kdNode
+	cell  HyperRect
+	point bool
+	key   float64
+}
+
+// incQueue is a min-heap of entries by key.
+type incQueue []incEntry
+
+func (q *incQueue) push(e incEntry) {
+	*q = append(*q, e)
+	h := *q
+	for i := len(h) - 1; i > 0; {
+		up := (i - 1) / 2
+		if h[up].key <= h[i].key {
+			break
+		}
+		h[up], h[i] = h[i], h[up]
+		i = up
+	}
+}
+
+func (q *incQueue) pop() incEntry {
+	h := *q
+	e := h[0]
+	last := len(h) - 1
+	h[0] = h[last]
+	h[last] = incEntry{}
+	h = h[:last]
+	for i := 0; ; {
+		c := 2*i + 1
+		if c >= len(h) {
+			break
+		}
+		if c+1 < len(h) && h[c+1].key < h[c].key {
+			c++
+		}
+		if h[i].key <= h[c].key {
+			break
+		}
+		h[i], h[c] = h[c], h[i]
+		i = c
+	}
+	*q = h
+	return e
+}
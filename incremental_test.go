@@ -0,0 +1,97 @@
+package kdtree
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestNearestSeq(t *testing.T) {
+	pts := randomPts(2, 800)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	for _, tight := range []bool{false, true} {
+		if tight {
+			kd.Tighten()
+		}
+		p := randomPt(2)
+		d := make([]float64, len(pts))
+		for i, q := range pts {
+			d[i] = q.Sqd(p)
+		}
+		sort.Float64s(d)
+		i := 0
+		for n := range kd.NearestSeq(p) {
+			if n.Sqd != d[i] {
+				t.Fatal("nearest", i, "distance^2", n.Sqd, "expected", d[i])
+			}
+			i++
+		}
+		if i != len(pts) {
+			t.Fatal("iterated", i, "points")
+		}
+		for n := range kd.FarthestSeq(p) {
+			i--
+			if n.Sqd != d[i] {
+				t.Fatal("farthest", i, "distance^2", n.Sqd, "expected", d[i])
+			}
+			if i == len(pts)-100 {
+				break
+			}
+		}
+	}
+}
+
+func TestKNearestPage(t *testing.T) {
+	pts := randomPts(2, 800)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	p := randomPt(2)
+	d := make([]float64, len(pts))
+	for i, q := range pts {
+		d[i] = q.Sqd(p)
+	}
+	sort.Float64s(d)
+	c := kd.NewNearestCursor(p)
+	for off := 0; off < len(pts); off += 30 {
+		page := c.Next(30)
+		if want := min(30, len(pts)-off); len(page) != want {
+			t.Fatal("page at", off, "has", len(page), "points")
+		}
+		same := kd.KNearestPage(p, off, 30)
+		for i, n := range page {
+			if n.Sqd != d[off+i] || same[i].Index != n.Index {
+				t.Fatal("point", off+i, n, same[i], "expected distance^2", d[off+i])
+			}
+		}
+	}
+	if n := c.Next(30); len(n) != 0 {
+		t.Fatal("points past the end:", n)
+	}
+}
+
+func TestNearestCursorSeek(t *testing.T) {
+	pts := randomPts(2, 800)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Tighten()
+	p := randomPt(2)
+	d := make([]float64, len(pts))
+	for i, q := range pts {
+		d[i] = q.Sqd(p)
+	}
+	sort.Float64s(d)
+	c := kd.NewNearestCursor(p)
+	c.Next(10)
+	// between the 300th and 301st, clear of rounding in squaring r
+	r := (math.Sqrt(d[299]) + math.Sqrt(d[300])) / 2
+	c.Seek(r)
+	page := c.Next(20)
+	for i, n := range page {
+		if n.Sqd != d[300+i] {
+			t.Fatal("point", i, "after seek", n, "expected distance^2", d[300+i])
+		}
+	}
+	// seeking backward has no effect
+	c.Seek(0)
+	if n := c.Next(1); n[0].Sqd != d[320] {
+		t.Fatal("after backward seek", n[0], "expected distance^2", d[320])
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// Insert adds p to t.
+//
+// p becomes a new leaf.  If that leaves some subtree on the path to it
+// unbalanced according to t.Alpha, the subtree is rebuilt.
+//
+// If p lies outside t.Bounds, t.Bounds is grown to contain it, or if
+// t.FixedBounds is set, Insert returns a *BoundsError and leaves t
+// unchanged.
+func (t *KdTree) Insert(p Point) error {
+	return t.InsertWithData(p, nil)
+}
+
+// InsertWithData adds p to t as Insert, associating data with p.
+func (t *KdTree) InsertWithData(p Point, data interface{}) error {
+	if err := t.grow(p); err != nil {
+		return err
+	}
+	t.insert(elt{domElt: p, rangeElt: data, index: t.next})
+	t.next++
+	return nil
+}
+
+// insert adds a node for e.
+func (t *KdTree) insert(e elt) {
+	t.gen++
+	p := e.domElt
+	tight := t.tight()
+	split := 0
+	var path []**kdNode
+	link := &t.n
+	for kd := t.n; kd != nil; kd = *link {
+		kd.force()
+		path = append(path, link)
+		kd.size++
+		if kd.bounds == nil && tight && kd.left == nil && kd.right == nil {
+			// a leaf becoming an interior node
+			kd.bounds = &HyperRect{append(Point{}, kd.domElt...),
+				append(Point{}, kd.domElt...)}
+		}
+		if kd.bounds != nil {
+			kd.bounds.extend(HyperRect{p, p})
+		}
+		if t.attrs != nil {
+			kd.extendAttrs(t.attrs, e.rangeElt)
+		}
+		if t.label != nil {
+			kd.extendLabels(t.label, e.rangeElt)
+		}
+		if t.cell != nil {
+			kd.extendCells(t.cell, e.rangeElt)
+		}
+		split = kd.split + 1
+		if split == len(p) {
+			split = 0
+		}
+		if p[kd.split] <= kd.domElt[kd.split] {
+			link = &kd.left
+		} else {
+			link = &kd.right
+		}
+	}
+	*link = &kdNode{elt: e, split: split, size: 1}
+	t.balancePath(path)
+}
+
+// InsertAll adds all of pts to t.
+//
+// The batch is partitioned down the tree by the split of each node it
+// passes.  Where a portion of the batch is at least as large as the
+// subtree it would be added to, that subtree is rebuilt balanced from
+// its own points and the new ones.  Subtrees left unbalanced according
+// to t.Alpha are also rebuilt.
+//
+// t.Bounds is grown as for Insert.  If t.FixedBounds is set and any point
+// lies outside t.Bounds, InsertAll returns a *BoundsError without
+// inserting any of pts.
+func (t *KdTree) InsertAll(pts []Point) error {
+	if t.FixedBounds {
+		for _, p := range pts {
+			if !t.Bounds.Contains(p) {
+				return &BoundsError{p, t.Bounds}
+			}
+		}
+	}
+	for _, p := range pts {
+		t.grow(p)
+	}
+	t.gen++
+	nodes := newNodes(pts, nil, t.next)
+	t.next += len(pts)
+	t.n = t.insertAll(t.n, nodes, 0, t.tight())
+	return nil
+}
+
+func (t *KdTree) insertAll(kd *kdNode, nodes []*kdNode, split int,
+	tight bool) *kdNode {
+	if len(nodes) == 0 {
+		return kd
+	}
+	if kd == nil || kd.size <= len(nodes) {
+		if kd != nil {
+			split = kd.split
+			// append must not clobber the caller's portion of nodes
+			nodes = nodes[:len(nodes):len(nodes)]
+			walk(kd, func(n *kdNode) { nodes = append(nodes, n) })
+		}
+		t.rebuilt("insert", len(nodes))
+		kd = nk2(nodes, split, -1, t.Split)
+		t.refit(kd, tight)
+		return kd
+	}
+	kd.force()
+	s := kd.split
+	m := 0
+	for i, n := range nodes {
+		if n.domElt[s] <= kd.domElt[s] {
+			nodes[m], nodes[i] = nodes[i], nodes[m]
+			m++
+		}
+	}
+	s2 := s + 1
+	if s2 == len(kd.domElt) {
+		s2 = 0
+	}
+	kd.left = t.insertAll(kd.left, nodes[:m], s2, tight)
+	kd.right = t.insertAll(kd.right, nodes[m:], s2, tight)
+	kd.size += len(nodes)
+	if kd.bounds != nil {
+		for _, n := range nodes {
+			kd.bounds.extend(HyperRect{n.domElt, n.domElt})
+		}
+	}
+	if t.attrs != nil {
+		for _, n := range nodes {
+			kd.extendAttrs(t.attrs, n.rangeElt)
+		}
+	}
+	if t.label != nil {
+		for _, n := range nodes {
+			kd.extendLabels(t.label, n.rangeElt)
+		}
+	}
+	if t.cell != nil {
+		for _, n := range nodes {
+			kd.extendCells(t.cell, n.rangeElt)
+		}
+	}
+	if t.unbalanced(kd) {
+		t.rebuilt("rebalance", kd.size)
+		kd = t.rebuild(kd, tight)
+	}
+	return kd
+}
+
+// tight reports whether t stores bounding boxes, as set by Tighten.
+func (t *KdTree) tight() bool {
+	return t.n != nil && t.n.bounds != nil
+}
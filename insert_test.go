@@ -0,0 +1,53 @@
+package kdtree
+
+import "testing"
+
+// checkNearest compares kd.Nearest to brute force over the tree's nodes.
+func checkNearest(t *testing.T, kd KdTree, n int) {
+	c := 0
+	walk(kd.n, func(*kdNode) { c++ })
+	if c != n {
+		t.Fatal("Expected", n, "points in tree, found", c)
+	}
+	for i := 0; i < 50; i++ {
+		p := randomPt(len(kd.Bounds.Min))
+		_, want, _ := bruteNearest(kd.n, p)
+		if _, got, _ := kd.Nearest(p); got != want {
+			t.Fatal("Expected distance^2", want, "found", got)
+		}
+	}
+}
+
+func TestInsert(t *testing.T) {
+	for _, tight := range []bool{false, true} {
+		kd := New(randomPts(3, 100), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+		kd.Brute = false
+		if tight {
+			kd.Tighten()
+		}
+		for _, p := range randomPts(3, 200) {
+			kd.Insert(p)
+		}
+		checkNearest(t, kd, 300)
+	}
+	var kd KdTree
+	kd.Bounds = HyperRect{Point{0, 0}, Point{1, 1}}
+	kd.Insert(Point{.5, .5})
+	checkNearest(t, kd, 1)
+}
+
+func TestInsertAll(t *testing.T) {
+	for _, tight := range []bool{false, true} {
+		kd := New(randomPts(3, 500), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+		kd.Brute = false
+		if tight {
+			kd.Tighten()
+		}
+		kd.InsertAll(randomPts(3, 50))
+		kd.InsertAll(randomPts(3, 1000))
+		checkNearest(t, kd, 1550)
+		if h := height(kd.n); h > 20 {
+			t.Error("Expected a balanced tree, found height", h)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+// Package kdgen generates synthetic point sets for testing and
+// benchmarking k-d trees.
+//
+// Each generator takes a seed and returns the same points for the same
+// arguments, so results can be compared across runs, machines, and split
+// rules.  Points lie in the unit hypercube [0,1]^dim unless noted.
+package kdgen
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/soniakeys/kdtree"
+)
+
+// Uniform returns n points placed uniformly at random.
+func Uniform(seed int64, dim, n int) []kdtree.Point {
+	r := rand.New(rand.NewSource(seed))
+	pts := make([]kdtree.Point, n)
+	for i := range pts {
+		p := make(kdtree.Point, dim)
+		for d := range p {
+			p[d] = r.Float64()
+		}
+		pts[i] = p
+	}
+	return pts
+}
+
+// Clusters returns n points in k Gaussian clusters with standard
+// deviation sigma.  Cluster centers are placed uniformly at random and
+// points are assigned to clusters at random.  Coordinates are clamped to
+// the unit hypercube.
+func Clusters(seed int64, dim, n, k int, sigma float64) []kdtree.Point {
+	r := rand.New(rand.NewSource(seed))
+	centers := make([]kdtree.Point, max(k, 1))
+	for i := range centers {
+		c := make(kdtree.Point, dim)
+		for d := range c {
+			c[d] = r.Float64()
+		}
+		centers[i] = c
+	}
+	pts := make([]kdtree.Point, n)
+	for i := range pts {
+		c := centers[r.Intn(len(centers))]
+		p := make(kdtree.Point, dim)
+		for d := range p {
+			p[d] = clamp(c[d] + sigma*r.NormFloat64())
+		}
+		pts[i] = p
+	}
+	return pts
+}
+
+// Grid returns the side^dim points of a regular grid, with side points
+// from 0 to 1 in each dimension, in row major order.  Grids have many
+// equal coordinates and exercise handling of duplicated split values.
+// The result does not depend on a seed.
+func Grid(dim, side int) []kdtree.Point {
+	if dim <= 0 || side <= 0 {
+		return nil
+	}
+	n := 1
+	for i := 0; i < dim; i++ {
+		n *= side
+	}
+	step := 0.
+	if side > 1 {
+		step = 1 / float64(side-1)
+	}
+	pts := make([]kdtree.Point, n)
+	for i := range pts {
+		p := make(kdtree.Point, dim)
+		for d, j := dim-1, i; d >= 0; d-- {
+			p[d] = float64(j%side) * step
+			j /= side
+		}
+		pts[i] = p
+	}
+	return pts
+}
+
+// Subspace returns n points lying near a random flat of dimension
+// intrinsic embedded in dim dimensions.  Points are uniform in a unit
+// cube of the flat, rotated at random and centered in the unit hypercube,
+// then perturbed by Gaussian noise of standard deviation noise in each
+// coordinate.  Coordinates may fall outside the unit hypercube.
+//
+// Such data has a low intrinsic dimension, which k-d trees exploit
+// poorly with axis-aligned splits.
+func Subspace(seed int64, dim, intrinsic, n int, noise float64) []kdtree.Point {
+	r := rand.New(rand.NewSource(seed))
+	basis := orthonormal(r, dim, min(intrinsic, dim))
+	pts := make([]kdtree.Point, n)
+	for i := range pts {
+		p := make(kdtree.Point, dim)
+		for d := range p {
+			p[d] = .5 + noise*r.NormFloat64()
+		}
+		for _, b := range basis {
+			c := r.Float64() - .5
+			for d := range p {
+				p[d] += c * b[d]
+			}
+		}
+		pts[i] = p
+	}
+	return pts
+}
+
+// Sphere returns n points on the sphere of radius .5 centered in the unit
+// hypercube, a curved manifold of dimension dim-1, uniformly distributed
+// on its surface.
+func Sphere(seed int64, dim, n int) []kdtree.Point {
+	r := rand.New(rand.NewSource(seed))
+	pts := make([]kdtree.Point, n)
+	for i := range pts {
+		p := make(kdtree.Point, dim)
+		norm := 0.
+		for norm == 0 {
+			for d := range p {
+				p[d] = r.NormFloat64()
+				norm += p[d] * p[d]
+			}
+		}
+		norm = math.Sqrt(norm)
+		for d := range p {
+			p[d] = .5 + .5*p[d]/norm
+		}
+		pts[i] = p
+	}
+	return pts
+}
+
+// orthonormal returns k random orthonormal vectors of dimension dim, by
+// Gram-Schmidt orthogonalization of Gaussian vectors.
+func orthonormal(r *rand.Rand, dim, k int) [][]float64 {
+	basis := make([][]float64, 0, k)
+	for len(basis) < k {
+		v := make([]float64, dim)
+		for d := range v {
+			v[d] = r.NormFloat64()
+		}
+		for _, b := range basis {
+			c := dot(v, b)
+			for d := range v {
+				v[d] -= c * b[d]
+			}
+		}
+		if n := math.Sqrt(dot(v, v)); n > 1e-9 {
+			for d := range v {
+				v[d] /= n
+			}
+			basis = append(basis, v)
+		}
+	}
+	return basis
+}
+
+func dot(a, b []float64) (s float64) {
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return
+}
+
+func clamp(x float64) float64 {
+	return math.Min(math.Max(x, 0), 1)
+}
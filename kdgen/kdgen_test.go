@@ -0,0 +1,51 @@
+package kdgen
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/soniakeys/kdtree"
+)
+
+func TestReproducible(t *testing.T) {
+	gens := map[string]func(seed int64) []kdtree.Point{
+		"Uniform":  func(s int64) []kdtree.Point { return Uniform(s, 3, 100) },
+		"Clusters": func(s int64) []kdtree.Point { return Clusters(s, 3, 100, 4, .05) },
+		"Subspace": func(s int64) []kdtree.Point { return Subspace(s, 5, 2, 100, 0) },
+		"Sphere":   func(s int64) []kdtree.Point { return Sphere(s, 3, 100) },
+	}
+	for name, g := range gens {
+		a, b, c := g(1), g(1), g(2)
+		if len(a) != 100 || len(a[0]) < 3 {
+			t.Fatal(name, "shape")
+		}
+		if !reflect.DeepEqual(a, b) || reflect.DeepEqual(a, c) {
+			t.Error(name, "not reproducible by seed")
+		}
+	}
+}
+
+func TestShapes(t *testing.T) {
+	g := Grid(2, 3)
+	if len(g) != 9 || !reflect.DeepEqual(g[5], kdtree.Point{.5, 1}) {
+		t.Fatal("grid", g)
+	}
+	for _, p := range Sphere(1, 4, 50) {
+		if r := p.Sqd(kdtree.Point{.5, .5, .5, .5}); math.Abs(r-.25) > 1e-12 {
+			t.Fatal("sphere radius²", r)
+		}
+	}
+	pts := Subspace(1, 6, 2, 200, 0)
+	kd := kdtree.New(pts, kdtree.HyperRect{
+		Min: kdtree.Point{-1, -1, -1, -1, -1, -1},
+		Max: kdtree.Point{2, 2, 2, 2, 2, 2}})
+	if s := kd.Stats(); s.Rank != 2 {
+		t.Error("rank", s.Rank)
+	}
+	for _, p := range Clusters(1, 2, 100, 3, .5) {
+		if p[0] < 0 || p[0] > 1 || p[1] < 0 || p[1] > 1 {
+			t.Fatal("cluster point outside unit square", p)
+		}
+	}
+}
@@ -9,8 +9,11 @@
 package kdtree
 
 import (
+	"cmp"
 	"math"
-	"sort"
+	"slices"
+	"sync/atomic"
+	"time"
 )
 
 // Point is a k-dimensional point.
@@ -40,48 +43,369 @@ func (hr HyperRect) Copy() HyperRect {
 	return HyperRect{append(Point{}, hr.Min...), append(Point{}, hr.Max...)}
 }
 
+// Sqd returns the square of the euclidean distance from p to the nearest
+// point of hr, zero if p is within hr.
+func (hr HyperRect) Sqd(p Point) float64 {
+	var sum float64
+	for dim, c := range p {
+		var d float64
+		if c < hr.Min[dim] {
+			d = hr.Min[dim] - c
+		} else if c > hr.Max[dim] {
+			d = c - hr.Max[dim]
+		}
+		sum += d * d
+	}
+	return sum
+}
+
+// Contains reports whether p lies within hr.
+func (hr HyperRect) Contains(p Point) bool {
+	for dim, c := range p {
+		if c < hr.Min[dim] || c > hr.Max[dim] {
+			return false
+		}
+	}
+	return true
+}
+
 // KdTree represents a k-d tree and associated k-d bounding box.
+//
+// Brute, when true, makes queries scan all points rather than search
+// the tree.  New sets it according to UseBrute but it may be changed
+// at any time.
+//
+// Alpha controls automatic rebalancing by Insert, InsertAll, and Delete.
+// A subtree where one child holds more than the fraction Alpha of its
+// nodes is rebuilt.  Zero means DefaultAlpha.  Values >= 1 disable
+// automatic rebalancing.
+//
+// MaxRebuild, if not zero, bounds the work of automatic rebalancing by
+// each Insert or Delete to rebuilding one subtree of at most MaxRebuild
+// nodes, spreading the cost evenly over updates rather than in occasional
+// long pauses.  Unbalanced subtrees larger than that are left, so the
+// depth of the tree may grow under adversarial orders of insertion, such
+// as sorted points, until it is rebuilt by Rebalance or, away from the
+// serving path, by AtomicTree.RebuildAsync.  Stats reports the depth.
+//
+// MaxDead controls automatic compaction by Remove.  When more than the
+// fraction MaxDead of the nodes are tombstones, the tree is compacted.
+// Zero means DefaultMaxDead.  Values >= 1 disable automatic compaction.
+//
+// Split is the rule choosing the dimension each node splits on, as set
+// by WithSplitRule.  Rebuilds of subtrees, such as by rebalancing, follow
+// it, so changing it affects only subtrees built afterward.
+//
+// Searches rely on all points lying within Bounds.  Methods that add
+// points grow Bounds as needed unless FixedBounds is set, in which case
+// they fail with a *BoundsError.
+//
+// Unsorted, when true, lets KNearest and related queries return results
+// in no particular order, saving the time to sort them.
+//
+// Metrics, if not nil, receives counts and timings of queries and
+// rebuilds.  Tracer, if not nil, is notified at the start and end of
+// each query.
+//
+// TrackQueries, when true, makes Nearest, KNearest, and range searches
+// count in each node the searches that visit it, for Reoptimize.
 type KdTree struct {
-	n      *kdNode
-	Bounds HyperRect
+	n            *kdNode
+	Bounds       HyperRect
+	Brute        bool
+	Alpha        float64
+	MaxRebuild   int
+	MaxDead      float64
+	Split        SplitRule
+	FixedBounds  bool
+	Unsorted     bool
+	Metrics      Metrics
+	Tracer       Tracer
+	TrackQueries bool
+	attrs        []AttrFunc
+	label        LabelFunc
+	filter       []AttrRange // restricts queries, as set by Where
+	timeAttr     int         // index in attrs of the time, plus one
+	timeOf       TimeFunc    // the times indexed by IndexTime
+	window       *timeWindow // restricts queries, as set by InWindow
+	cell         CellFunc
+	cells        []CellRange // restricts queries, as set by InCells
+	radiusAttr   int         // index in attrs of the radius, plus one
+	sample       int         // points sampled per subtree, for Quantiles
+	dead         int         // count of tombstones
+	next         int         // index for the next point added
+	gen          uint64      // count of changes, for caches
+}
+
+// UseBrute is the heuristic New uses to decide whether queries on a tree
+// of n points of dimension k should fall back to a linear scan.
+//
+// A k-d tree only prunes effectively when n is large compared to 2^k, and
+// for very small n the bookkeeping of the search costs more than simply
+// checking every point.  The default says brute force for fewer than
+// 32 points, for fewer than 4 * 2^k points, or for 30 or more dimensions,
+// where 4 * 2^k exceeds any practical n and would overflow the shift.
+// Replace it to tune the crossover for your data and hardware.
+var UseBrute = func(n, k int) bool {
+	return n < 32 || k >= 30 || n < 4<<uint(k)
 }
 
 // kdNode following field names in the paper.
-// rangeElt would be whatever data is associated with the point.
-// we don't bother with it for this example.
+//
+// bounds, if not nil, is the bounding box of the points of the subtree.
+// attrs, if not nil, holds the least and greatest value over the subtree
+// of each attribute indexed by IndexAttrs.
+// labels, if not nil, is the set of labels of the points of the subtree,
+// as indexed by IndexLabels.
+// cellIDs, if not nil, is the range of the cell IDs of the points of the
+// subtree, as indexed by IndexCells.
+// sample, if not nil, is a sample of the points of the subtree, as
+// indexed by IndexQuantiles.
+// size is the number of nodes in the subtree.
+// lazy, if not nil, holds the nodes of a subtree not yet built.  Such a
+// node must be forced before any other field but size is used.
 type kdNode struct {
-	domElt      Point
+	elt
 	split       int
 	left, right *kdNode
+	size        int
+	bounds      *HyperRect
+	attrs       []float64
+	labels      labelSet
+	cellIDs     *CellRange
+	sample      []Point
+	lazy        *lazySub
+	hits        uint32 // searches visiting the node, if tracked
+}
+
+// elt is the data of a node that belongs to its point rather than to its
+// position in the tree.  It moves with the point when the tree is
+// restructured.
+//
+// rangeElt is whatever data is associated with the point, as given to
+// NewWithData.  index numbers points in the order they were added to the
+// tree.  deleted marks a tombstone left by Remove.
+type elt struct {
+	domElt   Point
+	rangeElt interface{}
+	index    int
+	deleted  bool
+}
+
+// neighbor returns the point of kd as a Neighbor at squared distance sqd.
+func (e *elt) neighbor(sqd float64) Neighbor {
+	return Neighbor{e.domElt, e.index, e.rangeElt, sqd}
 }
 
 // New constructs a KdTree from a list of points and a bounding box.
 //
 // The bounds could be computed of course, but typically you know them already.
+//
+// Points are numbered by their position in pts.  Query results that
+// report indexes give these numbers.
 func New(pts []Point, bounds HyperRect) KdTree {
-	// algorithm is table 6.3 in the paper.
-	var nk2 func([]Point, int) *kdNode
-	nk2 = func(exset []Point, split int) *kdNode {
-		if len(exset) == 0 {
-			return nil
-		}
-		// pivot choosing procedure.  we find median, then find largest
-		// index of points with median value.  this satisfies the
-		// inequalities of steps 6 and 7 in the algorithm.
-		sort.Sort(part{exset, split})
-		m := len(exset) / 2
-		d := exset[m]
-		for m+1 < len(exset) && exset[m+1][split] == d[split] {
-			m++
+	return NewWith(pts, WithBounds(bounds))
+}
+
+// NewWithData constructs a KdTree as New, associating data[i] with pts[i].
+// Queries that report Neighbors give the data of each point.  data may be
+// nil or shorter than pts, leaving the remaining points without data.
+func NewWithData(pts []Point, data []interface{}, bounds HyperRect) KdTree {
+	return NewWith(pts, WithData(data), WithBounds(bounds))
+}
+
+// newNodes allocates unlinked nodes for pts and data, numbering them
+// from first.
+func newNodes(pts []Point, data []interface{}, first int) []*kdNode {
+	slab := make([]kdNode, len(pts))
+	nodes := make([]*kdNode, len(pts))
+	for i, p := range pts {
+		slab[i].domElt = p
+		slab[i].index = first + i
+		if i < len(data) {
+			slab[i].rangeElt = data[i]
+		}
+		nodes[i] = &slab[i]
+	}
+	return nodes
+}
+
+// nk2 links exset into a subtree, splitting first on dimension split, or
+// the next in which the points differ, as chosen by spreadDim, or with
+// rule SplitWidest on the dimension chosen by widestDim.  Subtrees
+// more than lazy levels down are deferred.  lazy < 0 builds the whole
+// subtree.  Links, sizes, and bounds of the nodes are reset, so nk2 also
+// serves to rebuild existing nodes.
+//
+// algorithm is table 6.3 in the paper.  The recursion of the paper is
+// replaced by a stack of subtrees still to be built, so that the deep
+// trees of heavily duplicated points cannot overflow the goroutine stack.
+func nk2(exset []*kdNode, split, lazy int, rule SplitRule) *kdNode {
+	var root *kdNode
+	type job struct {
+		nodes       []*kdNode
+		split, lazy int
+		link        **kdNode
+	}
+	stack := []job{{exset, split, lazy, &root}}
+	for len(stack) > 0 {
+		j := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		exset, split := j.nodes, j.split
+		switch {
+		case len(exset) == 0:
+			*j.link = nil
+			continue
+		case j.lazy == 0:
+			*j.link = &kdNode{size: len(exset),
+				lazy: &lazySub{nodes: exset, split: split, rule: rule}}
+			continue
+		}
+		if rule == SplitWidest {
+			split = widestDim(exset, split)
+		} else {
+			split = spreadDim(exset, split)
 		}
+		sortDim(exset, split)
+		m := pivotIndex(exset, split)
+		kd := exset[m]
+		d := kd.domElt
 		// next split
 		s2 := split + 1
 		if s2 == len(d) {
 			s2 = 0
 		}
-		return &kdNode{d, split, nk2(exset[:m], s2), nk2(exset[m+1:], s2)}
+		kd.split = split
+		kd.size = len(exset)
+		kd.bounds = nil
+		kd.attrs = nil
+		kd.labels = nil
+		kd.cellIDs = nil
+		kd.hits = 0
+		*j.link = kd
+		stack = append(stack,
+			job{exset[:m], s2, j.lazy - 1, &kd.left},
+			job{exset[m+1:], s2, j.lazy - 1, &kd.right})
+	}
+	return root
+}
+
+// pivotIndex returns the index of the pivot of nodes, sorted by
+// coordinate split.
+//
+// The pivot must satisfy the inequalities of steps 6 and 7 in the
+// algorithm, with points equal to it in coordinate split only on its
+// left.  nodes is partitioned three ways around the median value: those
+// less, those equal, and those greater.  The pivot is either the last of
+// the equal points, putting the run of them left, or the point before
+// the run, putting it right, whichever leaves the subtrees closer in
+// size.  Pivoting only at the end of the run would leave heavily
+// duplicated coordinates with lopsided subtrees.
+func pivotIndex(nodes []*kdNode, split int) int {
+	m := len(nodes) / 2
+	v := nodes[m].domElt[split]
+	lo, hi := m, m
+	for lo > 0 && nodes[lo-1].domElt[split] == v {
+		lo--
+	}
+	for hi+1 < len(nodes) && nodes[hi+1].domElt[split] == v {
+		hi++
+	}
+	// subtree sizes are hi and len-1-hi, or lo-1 and len-lo.
+	if lo > 0 && max(lo-1, len(nodes)-lo) < max(hi, len(nodes)-1-hi) {
+		return lo - 1
+	}
+	return hi
+}
+
+// spreadDim returns the first dimension, starting with split and cycling,
+// in which the points of nodes are not all equal, or split if there is no
+// such dimension.  Splitting on a dimension where all points are equal
+// would put them all on one side, so points lying in a lower dimensional
+// flat are split only in the dimensions they span.
+func spreadDim(nodes []*kdNode, split int) int {
+	dim := len(nodes[0].domElt)
+	for i := 0; i < dim; i++ {
+		d := (split + i) % dim
+		c := nodes[0].domElt[d]
+		for _, n := range nodes[1:] {
+			if n.domElt[d] != c {
+				return d
+			}
+		}
+	}
+	return split
+}
+
+// SplitRule chooses the dimension each node of a tree splits on.
+type SplitRule int
+
+const (
+	// SplitCycle splits on the dimensions in turn down the tree, as in the
+	// paper, skipping those in which the points do not differ.
+	SplitCycle SplitRule = iota
+	// SplitWidest splits each node on the dimension in which the points
+	// of its subtree spread widest, giving cells closer to cubes for data
+	// stretched along some axes, at the cost of a pass over the points
+	// per node.
+	SplitWidest
+)
+
+// widestDim returns the dimension of greatest spread of the points of
+// nodes, preferring split and then the dimensions after it on ties.
+func widestDim(nodes []*kdNode, split int) int {
+	dim := len(nodes[0].domElt)
+	best, bestSpread := split, -1.
+	for i := 0; i < dim; i++ {
+		d := (split + i) % dim
+		lo, hi := nodes[0].domElt[d], nodes[0].domElt[d]
+		for _, n := range nodes[1:] {
+			lo = math.Min(lo, n.domElt[d])
+			hi = math.Max(hi, n.domElt[d])
+		}
+		if hi-lo > bestSpread {
+			best, bestSpread = d, hi-lo
+		}
+	}
+	return best
+}
+
+// Tighten computes and stores a bounding box for each subtree of t.
+//
+// Searches normally bound subtrees by the cells that result from
+// splitting t.Bounds.  With tight boxes, searches can prune subtrees
+// that are near the target in cell terms but whose points are not.
+// This helps most on clustered data where cells have much empty space.
+// The cost is memory for two points per interior node.
+func (t KdTree) Tighten() {
+	if t.n != nil {
+		tighten(t.n)
+	}
+}
+
+// tighten sets bounds for interior nodes of the subtree at kd and returns
+// the bounding box of the subtree.
+func tighten(kd *kdNode) *HyperRect {
+	kd.force()
+	if kd.left == nil && kd.right == nil {
+		return &HyperRect{kd.domElt, kd.domElt}
+	}
+	b := HyperRect{append(Point{}, kd.domElt...), append(Point{}, kd.domElt...)}
+	for _, c := range []*kdNode{kd.left, kd.right} {
+		if c != nil {
+			b.extend(*tighten(c))
+		}
+	}
+	kd.bounds = &b
+	return &b
+}
+
+// extend grows hr as needed to contain r.
+func (hr HyperRect) extend(r HyperRect) {
+	for i := range hr.Min {
+		hr.Min[i] = math.Min(hr.Min[i], r.Min[i])
+		hr.Max[i] = math.Max(hr.Max[i], r.Max[i])
 	}
-	return KdTree{nk2(pts, 0), bounds}
 }
 
 // Nearest.  find nearest neighbor.
@@ -90,69 +414,173 @@ func New(pts []Point, bounds HyperRect) KdTree {
 //  - nearest neighbor--the point within the tree that is nearest p.
 //  - square of the distance to that point.
 //  - a count of the nodes visited in the search.
+//
+// If t.Brute is set, all points are checked and nv is the number of points
+// in the tree.
 func (t KdTree) Nearest(p Point) (best Point, bestSqd float64, nv int) {
-	return nn(t.n, p, t.Bounds, math.Inf(1))
+	if t.observed() {
+		defer t.record(t.begin("Nearest"), time.Now(), &nv)
+	}
+	if t.restricted() {
+		// the Searcher applies the restriction
+		s := t.NewSearcher()
+		nv = s.search(p, 1)
+		if len(s.h.e) == 0 {
+			return nil, math.Inf(1), nv
+		}
+		return s.h.e[0].Point, s.h.e[0].Sqd, nv
+	}
+	if t.Brute {
+		return bruteNearest(t.n, p)
+	}
+	return nn(t.n, p, t.Bounds, t.TrackQueries)
+}
+
+// bruteNearest finds the nearest neighbor by checking every node.
+func bruteNearest(kd *kdNode, p Point) (best Point, bestSqd float64, nv int) {
+	bestSqd = math.Inf(1)
+	walk(kd, func(n *kdNode) {
+		nv++
+		if n.deleted {
+			return
+		}
+		if d := n.domElt.Sqd(p); d < bestSqd {
+			best, bestSqd = n.domElt, d
+		}
+	})
+	return
+}
+
+// walk calls f for each node of the tree rooted at kd, in order.
+// An explicit stack bounds the goroutine stack used on deep trees.
+func walk(kd *kdNode, f func(*kdNode)) {
+	var stack []*kdNode
+	for kd != nil || len(stack) > 0 {
+		for ; kd != nil; kd = kd.left {
+			kd.force()
+			stack = append(stack, kd)
+		}
+		kd = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		f(kd)
+		kd = kd.right
+	}
 }
 
 // algorithm is table 6.4 from the paper, with the addition of counting
 // the number nodes visited.
-func nn(kd *kdNode, target Point, hr HyperRect,
-	maxDistSqd float64) (nearest Point, distSqd float64, nodesVisited int) {
-	if kd == nil {
-		return nil, math.Inf(1), 0
-	}
-	nodesVisited++
-	s := kd.split
-	pivot := kd.domElt
-	leftHr := hr.Copy()
-	rightHr := hr.Copy()
-	leftHr.Max[s] = pivot[s]
-	rightHr.Min[s] = pivot[s]
-	targetInLeft := target[s] <= pivot[s]
-	var nearerKd, furtherKd *kdNode
-	var nearerHr, furtherHr HyperRect
-	if targetInLeft {
-		nearerKd, nearerHr = kd.left, leftHr
-		furtherKd, furtherHr = kd.right, rightHr
+//
+// The double recursion of the paper is replaced by an explicit stack.
+// Descending toward the target, each node is pushed so that its further
+// subtree can be considered later.  Popping a node corresponds to
+// returning from the recursive call on the nearer subtree: the pivot and
+// further subtree are considered only if the further hyperrectangle is
+// within the best distance found so far.
+//
+// Rather than copying hyperrectangles, the search keeps the offset of the
+// target from the current cell in each dimension, and rd, the sum of
+// squared offsets, which is the squared distance to the cell.  Entering a
+// further subtree changes the offset only in the split dimension.  The
+// old offset is saved in a restore frame on the stack and put back when
+// the subtree is exhausted.  Scratch space comes from fixed size arrays
+// so that typical searches do not allocate.
+func nn(kd *kdNode, target Point, hr HyperRect, track bool) (nearest Point,
+	distSqd float64, nodesVisited int) {
+	var stackBuf [64]frame
+	stack := stackBuf[:0]
+	var offBuf [16]float64
+	var off []float64
+	if len(target) <= len(offBuf) {
+		off = offBuf[:len(target)]
 	} else {
-		nearerKd, nearerHr = kd.right, rightHr
-		furtherKd, furtherHr = kd.left, leftHr
-	}
-	var nv int
-	nearest, distSqd, nv = nn(nearerKd, target, nearerHr, maxDistSqd)
-	nodesVisited += nv
-	if distSqd < maxDistSqd {
-		maxDistSqd = distSqd
-	}
-	d := pivot[s] - target[s]
-	d *= d
-	if d > maxDistSqd {
-		return
-	}
-	if d = pivot.Sqd(target); d < distSqd {
-		nearest = pivot
-		distSqd = d
-		maxDistSqd = distSqd
-	}
-	tempNearest, tempSqd, nv := nn(furtherKd, target, furtherHr, maxDistSqd)
-	nodesVisited += nv
-	if tempSqd < distSqd {
-		nearest = tempNearest
-		distSqd = tempSqd
+		off = make([]float64, len(target))
 	}
-	return
+	rd := initOff(off, target, hr)
+	distSqd = math.Inf(1)
+	for {
+		// descend nearer subtrees to a leaf
+		for kd != nil {
+			kd.force()
+			if kd.bounds != nil && kd.bounds.Sqd(target) > distSqd {
+				break
+			}
+			nodesVisited++
+			if track {
+				atomic.AddUint32(&kd.hits, 1)
+			}
+			s := kd.split
+			d := target[s] - kd.domElt[s]
+			stack = append(stack,
+				frame{kd, s, d, rd - off[s]*off[s] + d*d})
+			if d <= 0 {
+				kd = kd.left
+			} else {
+				kd = kd.right
+			}
+		}
+		kd = nil
+		// unwind to a node whose further subtree must be searched
+		for kd == nil {
+			if len(stack) == 0 {
+				return
+			}
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if f.kd == nil {
+				off[f.s] = f.off
+				rd = f.rd
+				continue
+			}
+			if f.rd > distSqd {
+				continue
+			}
+			pivot := f.kd.domElt
+			if d := pivot.Sqd(target); d < distSqd && !f.kd.deleted {
+				nearest = pivot
+				distSqd = d
+			}
+			if f.off <= 0 {
+				kd = f.kd.right
+			} else {
+				kd = f.kd.left
+			}
+			if kd != nil {
+				stack = append(stack, frame{nil, f.s, off[f.s], rd})
+				off[f.s] = f.off
+				rd = f.rd
+			}
+		}
+	}
+}
+
+// a frame of the explicit stack used by nn and similar searches.
+// A frame with kd != nil holds the further subtree of kd, with off and rd
+// for that subtree.  kd == nil is a restore frame for dimension s.
+type frame struct {
+	kd      *kdNode
+	s       int
+	off, rd float64
 }
 
-// a container type used for sorting.  it holds the points to sort and
-// the dimension to use for the sort key.
-type part struct {
-	pts   []Point
-	dPart int
+// initOff sets off to the offsets of target from hr and returns the sum
+// of squared offsets.
+func initOff(off []float64, target Point, hr HyperRect) (rd float64) {
+	for i, t := range target {
+		off[i] = 0
+		if i < len(hr.Min) && t < hr.Min[i] {
+			off[i] = t - hr.Min[i]
+		} else if i < len(hr.Max) && t > hr.Max[i] {
+			off[i] = t - hr.Max[i]
+		}
+		rd += off[i] * off[i]
+	}
+	return
 }
 
-// satisfy sort.Interface
-func (p part) Len() int { return len(p.pts) }
-func (p part) Less(i, j int) bool {
-	return p.pts[i][p.dPart] < p.pts[j][p.dPart]
+// sortDim sorts nodes by coordinate d.  slices.SortFunc avoids the
+// interface method calls of sort.Sort on each comparison and swap.
+func sortDim(nodes []*kdNode, d int) {
+	slices.SortFunc(nodes, func(a, b *kdNode) int {
+		return cmp.Compare(a.domElt[d], b.domElt[d])
+	})
 }
-func (p part) Swap(i, j int) { p.pts[i], p.pts[j] = p.pts[j], p.pts[i] }
@@ -3,6 +3,7 @@ package kdtree
 import (
 	"math"
 	"math/rand"
+	"runtime/debug"
 	"testing"
 	"time"
 )
@@ -11,6 +12,7 @@ import (
 func TestWP2D(t *testing.T) {
 	kd := New([]Point{{2, 3}, {5, 4}, {9, 6}, {4, 7}, {8, 1}, {7, 2}},
 		HyperRect{Point{0, 0}, Point{10, 10}})
+	kd.Brute = false // six points would otherwise be scanned
 	p := Point{9, 2}
 	nn, ssq, nv := kd.Nearest(p)
 	if p.Sqd(nn) != ssq {
@@ -57,6 +59,110 @@ func TestRandom3D(t *testing.T) {
 	}
 }
 
+// brute force and tree search must agree
+func TestBrute(t *testing.T) {
+	if !UseBrute(6, 2) || UseBrute(1000, 3) || !UseBrute(1000, 20) {
+		t.Error("unexpected UseBrute heuristic results")
+	}
+	pts := randomPts(4, 200)
+	kd := New(pts, HyperRect{Point{0, 0, 0, 0}, Point{1, 1, 1, 1}})
+	for i := 0; i < 100; i++ {
+		p := randomPt(4)
+		kd.Brute = true
+		bn, bsqd, bnv := kd.Nearest(p)
+		if bnv != len(pts) {
+			t.Fatal("Expected brute force to visit", len(pts), "found", bnv)
+		}
+		kd.Brute = false
+		tn, tsqd, _ := kd.Nearest(p)
+		if bsqd != tsqd {
+			t.Fatal("brute", bn, bsqd, "tree", tn, tsqd)
+		}
+	}
+}
+
+// duplicate coordinates build a degenerate, deep tree
+func TestDeep(t *testing.T) {
+	pts := make([]Point, 2000)
+	for i := range pts {
+		pts[i] = Point{.5}
+	}
+	pts[0] = Point{.25}
+	kd := New(pts, HyperRect{Point{0}, Point{1}})
+	nn, ssq, _ := kd.Nearest(Point{.3})
+	if nn[0] != .25 || math.Abs(ssq-.0025) > 1e-14 {
+		t.Error("Expected nn =", Point{.25}, "found", nn, ssq)
+	}
+}
+
+func TestBuildStack(t *testing.T) {
+	pts := make([]Point, 8000)
+	for i := range pts {
+		pts[i] = Point{.5, .5}
+	}
+	// a recursive build of this degenerate tree needs over 1MB of stack
+	defer debug.SetMaxStack(debug.SetMaxStack(256 << 10))
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	n := 0
+	walk(kd.n, func(*kdNode) { n++ })
+	if n != len(pts) || kd.n.size != len(pts) {
+		t.Fatal("walked", n, "size", kd.n.size)
+	}
+}
+
+func TestPivotDuplicates(t *testing.T) {
+	// x takes only the values 0 and 1
+	pts := randomPts(2, 4096)
+	for i, p := range pts {
+		p[0] = float64(i % 2)
+	}
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	if s := kd.Stats(); s.Depth > 14 {
+		t.Error("depth", s.Depth)
+	}
+	checkNearest(t, kd, len(pts))
+	for _, p := range pts[:100] {
+		if !kd.Contains(p) {
+			t.Fatal(p, "not found")
+		}
+	}
+}
+
+func TestNearestAllocs(t *testing.T) {
+	kd := New(randomPts(3, 1000), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	p := randomPt(3)
+	if a := testing.AllocsPerRun(100, func() { kd.Nearest(p) }); a != 0 {
+		t.Error("Expected no allocations, found", a)
+	}
+}
+
+// tight bounds on clustered data
+func TestTighten(t *testing.T) {
+	var pts []Point
+	for _, c := range []Point{{.1, .1}, {.9, .9}, {.1, .9}} {
+		for i := 0; i < 300; i++ {
+			pts = append(pts, Point{c[0] + rand.Float64()*.05,
+				c[1] + rand.Float64()*.05})
+		}
+	}
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	p := Point{.5, .5}
+	nn1, ssq1, nv1 := kd.Nearest(p)
+	kd.Tighten()
+	nn2, ssq2, nv2 := kd.Nearest(p)
+	if ssq1 != ssq2 {
+		t.Fatal("Expected", nn1, ssq1, "with tight bounds, found", nn2, ssq2)
+	}
+	if nv2 > nv1 {
+		t.Error("Expected fewer nodes visited.  loose:", nv1, "tight:", nv2)
+	}
+	_, sqd, _ := kd.KNearest(p, 5)
+	if len(sqd) != 5 {
+		t.Error("Expected 5 neighbors, found", len(sqd))
+	}
+}
+
 func randomPt(dim int) Point {
 	p := make(Point, dim)
 	for d := range p {
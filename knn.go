@@ -0,0 +1,353 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// KNearest finds the k nearest neighbors of p.
+//
+// return values:
+//   - up to k points within the tree, nearest first.  Points at equal
+//     distances are ordered by index, and of points tied with the k-th,
+//     those of least index are returned.
+//   - squares of the distances to the corresponding points.
+//   - a count of the nodes visited in the search.
+//
+// Fewer than k points are returned only if the tree holds fewer than k.
+// Points stored more than once are counted and returned once for each
+// copy, so results describe the multiset of points added.
+// For repeated queries, a Searcher avoids allocating scratch space on
+// each call.
+func (t KdTree) KNearest(p Point, k int) (nn []Point, sqd []float64, nv int) {
+	return t.NewSearcher().KNearest(p, k)
+}
+
+// KNearestApprox finds approximate k nearest neighbors of p, as KNearest
+// but pruning subtrees that cannot hold a point nearer by a factor of
+// more than 1+eps than the k-th best found so far.  The i-th point
+// returned is then within a factor of 1+eps of the distance to the true
+// i-th nearest neighbor.  Larger eps visits fewer nodes.  eps <= 0 gives
+// an exact search, so one tree can serve exact and approximate queries.
+func (t KdTree) KNearestApprox(p Point, k int, eps float64) (nn []Point,
+	sqd []float64, nv int) {
+	return t.NewSearcher().KNearestApprox(p, k, eps)
+}
+
+// KNearestFunc finds the k nearest neighbors of p, as KNearest, then calls
+// f with each, nearest first, and the square of its distance, stopping
+// early if f returns false.
+func (t KdTree) KNearestFunc(p Point, k int, f func(Point, float64) bool) {
+	t.NewSearcher().KNearestFunc(p, k, f)
+}
+
+// Searcher holds scratch space for repeated queries on a tree.
+//
+// A Searcher searches the tree as it was when NewSearcher was called.
+// It must not be used by more than one goroutine at a time; goroutines
+// that search the same tree should each obtain their own Searcher.
+type Searcher struct {
+	t     KdTree
+	stack []frame
+	off   []float64
+	h     KHeap
+	nn    []Point
+	sqd   []float64
+
+	// scale, if not zero, multiplies the pruning bound for approximate
+	// search.
+	scale float64
+
+	// where, if not nil, restricts results to points satisfying it.
+	where []AttrRange
+
+	// labels, if not nil, restricts results to points with these labels.
+	labels labelSet
+
+	// accept, if not nil, restricts results to neighbors it accepts.
+	accept func(Neighbor) bool
+
+	// limit, if limited is set, is the greatest distance of a point
+	// to be kept.
+	limit   float64
+	limited bool
+
+	// shared, if not nil, holds the bits of a pruning bound shared with
+	// other Searchers working on the same query.
+	shared *atomic.Uint64
+}
+
+// NewSearcher returns a Searcher for t.
+func (t KdTree) NewSearcher() *Searcher {
+	return &Searcher{t: t, off: make([]float64, len(t.Bounds.Min)),
+		where: t.filter}
+}
+
+// KNearest finds the k nearest neighbors of p, as KdTree.KNearest.
+//
+// The returned slices are owned by the Searcher and are valid only until
+// its next query.  Once the Searcher has grown its buffers to the
+// size needed, KNearest does not allocate.
+func (s *Searcher) KNearest(p Point, k int) (nn []Point, sqd []float64, nv int) {
+	nv = s.search(p, k)
+	s.nn = s.nn[:0]
+	s.sqd = s.sqd[:0]
+	for _, e := range s.h.e {
+		s.nn = append(s.nn, e.Point)
+		s.sqd = append(s.sqd, e.Sqd)
+	}
+	return s.nn, s.sqd, nv
+}
+
+// KNearestFunc finds the k nearest neighbors of p, as KdTree.KNearestFunc.
+// It does not allocate once the Searcher has grown its buffers.
+func (s *Searcher) KNearestFunc(p Point, k int, f func(Point, float64) bool) {
+	s.search(p, k)
+	for _, e := range s.h.e {
+		if !f(e.Point, e.Sqd) {
+			return
+		}
+	}
+}
+
+// KNearestApprox finds approximate k nearest neighbors of p, as
+// KdTree.KNearestApprox.
+func (s *Searcher) KNearestApprox(p Point, k int, eps float64) (nn []Point,
+	sqd []float64, nv int) {
+	if eps > 0 {
+		s.scale = 1 / ((1 + eps) * (1 + eps))
+		defer func() { s.scale = 0 }()
+	}
+	return s.KNearest(p, k)
+}
+
+// search leaves the k nearest neighbors of p in the heap and returns the
+// number of nodes visited.
+func (s *Searcher) search(p Point, k int) (nv int) {
+	if s.t.observed() {
+		defer s.t.record(s.t.begin("KNearest"), time.Now(), &nv)
+	}
+	s.h.Reset(k)
+	if k <= 0 {
+		return 0
+	}
+	if s.t.Brute {
+		nv = s.scan(p)
+	} else {
+		nv = s.knn(p)
+	}
+	if !s.t.Unsorted {
+		slices.SortFunc(s.h.e, compareNeighbors)
+	}
+	return
+}
+
+// scan pushes every point of the tree to the heap.
+func (s *Searcher) scan(target Point) (nv int) {
+	stack := append(s.stack[:0], frame{kd: s.t.n})
+	for len(stack) > 0 {
+		kd := stack[len(stack)-1].kd
+		stack = stack[:len(stack)-1]
+		if kd == nil {
+			continue
+		}
+		kd.force()
+		nv++
+		if !kd.deleted {
+			s.push(kd.neighbor(kd.domElt.Sqd(target)))
+		}
+		stack = append(stack, frame{kd: kd.left}, frame{kd: kd.right})
+	}
+	s.stack = stack
+	return
+}
+
+// knn is nn generalized to collect k points in the heap.  The pruning
+// bound is the distance to the worst of the k best points found so far.
+func (s *Searcher) knn(target Point) (nodesVisited int) {
+	if len(s.off) < len(target) {
+		s.off = make([]float64, len(target))
+	}
+	off := s.off[:len(target)]
+	rd := initOff(off, target, s.t.Bounds)
+	stack := s.stack[:0]
+	kd := s.t.n
+	for {
+		for kd != nil {
+			kd.force()
+			if kd.bounds != nil && kd.bounds.Sqd(target) > s.bound() ||
+				s.where != nil && !kd.mayMatch(s.where) ||
+				s.t.cells != nil && !s.t.cellsMayMatch(kd) ||
+				s.labels != nil && kd.labels != nil && !kd.labels.intersects(s.labels) {
+				break
+			}
+			nodesVisited++
+			if s.t.TrackQueries {
+				atomic.AddUint32(&kd.hits, 1)
+			}
+			sp := kd.split
+			d := target[sp] - kd.domElt[sp]
+			stack = append(stack,
+				frame{kd, sp, d, rd - off[sp]*off[sp] + d*d})
+			if d <= 0 {
+				kd = kd.left
+			} else {
+				kd = kd.right
+			}
+		}
+		kd = nil
+		for kd == nil {
+			if len(stack) == 0 {
+				s.stack = stack
+				return
+			}
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if f.kd == nil {
+				off[f.s] = f.off
+				rd = f.rd
+				continue
+			}
+			if f.rd > s.bound() {
+				continue
+			}
+			if !f.kd.deleted {
+				s.push(f.kd.neighbor(f.kd.domElt.Sqd(target)))
+			}
+			if f.off <= 0 {
+				kd = f.kd.right
+			} else {
+				kd = f.kd.left
+			}
+			if kd != nil {
+				stack = append(stack, frame{nil, f.s, off[f.s], rd})
+				off[f.s] = f.off
+				rd = f.rd
+			}
+		}
+	}
+}
+
+// bound returns the distance a point must beat to be among the k nearest.
+func (s *Searcher) bound() float64 {
+	w := s.h.Worst()
+	if s.shared != nil {
+		if b := math.Float64frombits(s.shared.Load()); b < w {
+			w = b
+		}
+	}
+	if s.limited && s.limit < w {
+		w = s.limit
+	}
+	if s.scale != 0 {
+		w *= s.scale
+	}
+	return w
+}
+
+// push offers n to the heap, then lowers the shared bound, if any, to
+// the heap's worst distance.
+func (s *Searcher) push(n Neighbor) {
+	if s.limited && n.Sqd > s.limit ||
+		s.where != nil && !s.t.matches(n.Data, s.where) ||
+		s.t.cells != nil && !s.t.inCells(n.Data) ||
+		s.labels != nil && !s.labels.has(s.t.label(n.Data)) ||
+		s.accept != nil && !s.accept(n) {
+		return
+	}
+	s.h.Push(n)
+	if s.shared == nil {
+		return
+	}
+	w := s.h.Worst()
+	for {
+		old := s.shared.Load()
+		if w >= math.Float64frombits(old) ||
+			s.shared.CompareAndSwap(old, math.Float64bits(w)) {
+			return
+		}
+	}
+}
+
+// KHeap is a bounded max-heap keeping the k nearest of the neighbors
+// pushed to it.  It is the collector used by the k-nearest searches of
+// this package and can be used the same way by custom traversals, such
+// as with Visit.
+//
+// The zero value keeps no neighbors; use NewKHeap or Reset to set k.
+type KHeap struct {
+	k int
+	e []Neighbor
+}
+
+// NewKHeap returns a KHeap keeping the k nearest neighbors pushed to it.
+func NewKHeap(k int) *KHeap {
+	return &KHeap{k: k, e: make([]Neighbor, 0, k)}
+}
+
+// Reset empties h and sets it to keep k neighbors, reusing its storage.
+func (h *KHeap) Reset(k int) {
+	h.k = k
+	h.e = h.e[:0]
+}
+
+// Worst returns the squared distance a point must beat to be kept, +Inf
+// until the heap holds k neighbors.
+func (h *KHeap) Worst() float64 {
+	if len(h.e) < h.k {
+		return math.Inf(1)
+	}
+	return h.e[0].Sqd
+}
+
+// Len returns the number of neighbors held, at most k.
+func (h *KHeap) Len() int { return len(h.e) }
+
+// Results returns the neighbors held, sorted by increasing distance.
+// It does not change h.
+func (h *KHeap) Results() Neighbors {
+	n := append(Neighbors{}, h.e...)
+	n.Sort()
+	return n
+}
+
+// Push offers n, keeping it if it is nearer than Worst.  Of neighbors at
+// equal distances, those of least index are kept, so results do not
+// depend on the order of pushes.
+func (h *KHeap) Push(n Neighbor) {
+	if len(h.e) < h.k {
+		h.e = append(h.e, n)
+		for i := len(h.e) - 1; i > 0; {
+			up := (i - 1) / 2
+			if compareNeighbors(h.e[up], h.e[i]) >= 0 {
+				break
+			}
+			h.e[up], h.e[i] = h.e[i], h.e[up]
+			i = up
+		}
+		return
+	}
+	if h.k == 0 || compareNeighbors(n, h.e[0]) >= 0 {
+		return
+	}
+	h.e[0] = n
+	for i := 0; ; {
+		c := 2*i + 1
+		if c >= len(h.e) {
+			break
+		}
+		if c+1 < len(h.e) && compareNeighbors(h.e[c+1], h.e[c]) > 0 {
+			c++
+		}
+		if compareNeighbors(h.e[i], h.e[c]) >= 0 {
+			break
+		}
+		h.e[i], h.e[c] = h.e[c], h.e[i]
+		i = c
+	}
+}
@@ -0,0 +1,155 @@
+package kdtree
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// compare KNearest to sorted distances of all points
+func TestKNearest(t *testing.T) {
+	pts := randomPts(3, 1000)
+	all := append([]Point{}, pts...)
+	kd := New(pts, HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	s := kd.NewSearcher()
+	for _, brute := range []bool{false, true} {
+		s.t.Brute = brute
+		p := randomPt(3)
+		sort.Slice(all, func(i, j int) bool {
+			return all[i].Sqd(p) < all[j].Sqd(p)
+		})
+		nn, sqd, nv := s.KNearest(p, 10)
+		if len(nn) != 10 || len(sqd) != 10 {
+			t.Fatal("Expected 10 results, found", len(nn))
+		}
+		if brute && nv != len(pts) {
+			t.Error("Expected brute force to visit all nodes, found", nv)
+		}
+		sort.Float64s(sqd)
+		for i, d := range sqd {
+			if want := all[i].Sqd(p); d != want {
+				t.Fatal("result", i, "distance^2", d, "expected", want)
+			}
+		}
+	}
+	if nn, _, _ := kd.KNearest(randomPt(3), 2000); len(nn) != len(pts) {
+		t.Error("Expected all", len(pts), "points, found", len(nn))
+	}
+}
+
+func TestSearcherAllocs(t *testing.T) {
+	kd := New(randomPts(3, 1000), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	s := kd.NewSearcher()
+	p := randomPt(3)
+	s.KNearest(p, 10)
+	if a := testing.AllocsPerRun(100, func() { s.KNearest(p, 10) }); a != 0 {
+		t.Error("Expected no allocations, found", a)
+	}
+}
+
+func TestKHeapVisit(t *testing.T) {
+	kd := New(randomPts(3, 1000), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	kd.Brute = false
+	kd.Tighten()
+	p := randomPt(3)
+	h := NewKHeap(8)
+	kd.Visit(func(box HyperRect) bool { return box.Sqd(p) < h.Worst() },
+		func(q Point) bool {
+			h.Push(Neighbor{Point: q, Sqd: q.Sqd(p)})
+			return true
+		})
+	got := h.Results()
+	want := kd.KNearestNeighbors(p, 8)
+	if h.Len() != 8 || len(got) != 8 {
+		t.Fatal("Expected 8 results, got", len(got))
+	}
+	for i := range want {
+		if got[i].Sqd != want[i].Sqd {
+			t.Fatal("result", i, "sqd", got[i].Sqd, "expected", want[i].Sqd)
+		}
+	}
+}
+
+func TestKNearestSorted(t *testing.T) {
+	pts := randomPts(2, 500)
+	// ties: copies of a point, and points symmetric about the target
+	pts = append(pts, pts[3], pts[3], Point{.25, .5}, Point{.75, .5})
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	for _, p := range []Point{pts[3], {.5, .5}, randomPt(2)} {
+		n := kd.KNearestNeighbors(p, 20)
+		_, sqd, _ := kd.KNearest(p, 20)
+		par, _, _ := kd.KNearestParallel(p, 20, 4)
+		for i := range n {
+			if sqd[i] != n[i].Sqd || par[i].Sqd(p) != n[i].Sqd {
+				t.Fatal("result", i, "out of order")
+			}
+			if i > 0 && compareNeighbors(n[i-1], n[i]) >= 0 {
+				t.Fatal("result", i, "out of order")
+			}
+		}
+	}
+	kd.Unsorted = true
+	p := randomPt(2)
+	_, sqd, _ := kd.KNearest(p, 20)
+	sort.Float64s(sqd)
+	if _, want, _ := kd.Nearest(p); sqd[0] != want {
+		t.Error("unsorted results differ")
+	}
+}
+
+func TestKNearestApprox(t *testing.T) {
+	kd := New(randomPts(6, 5000), HyperRect{make(Point, 6), Point{1, 1, 1, 1, 1, 1}})
+	kd.Brute = false
+	s := kd.NewSearcher()
+	var exactNV, approxNV int
+	for i := 0; i < 50; i++ {
+		p := randomPt(6)
+		_, want, nv := s.KNearestApprox(p, 5, 0)
+		exactNV += nv
+		_, got, nv := s.KNearestApprox(p, 5, .5)
+		approxNV += nv
+		for j := range got {
+			if math.Sqrt(got[j]) > 1.5*math.Sqrt(want[j])*(1+1e-12) {
+				t.Fatal("result", j, "distance^2", got[j], "exact", want[j])
+			}
+		}
+	}
+	if approxNV >= exactNV {
+		t.Error("approximate search visited", approxNV, "nodes, exact", exactNV)
+	}
+	if s.scale != 0 {
+		t.Error("approximation not reset")
+	}
+}
+
+func TestKNearestTies(t *testing.T) {
+	// a grid, shuffled, so many points tie and index is not tree order
+	var pts []Point
+	for x := 0; x < 20; x++ {
+		for y := 0; y < 20; y++ {
+			pts = append(pts, Point{float64(x), float64(y)})
+		}
+	}
+	rand.New(rand.NewSource(1)).Shuffle(len(pts), func(i, j int) {
+		pts[i], pts[j] = pts[j], pts[i]
+	})
+	kd := NewWithData(append([]Point{}, pts...), nil, HyperRect{Point{0, 0}, Point{19, 19}})
+	kd.Brute = false
+	p := Point{9.5, 9.5}
+	var want Neighbors
+	for i, q := range pts {
+		want = append(want, Neighbor{Point: q, Index: i, Sqd: q.Sqd(p)})
+	}
+	want.Sort()
+	// each ring of 4 or 8 equidistant points is cut by odd k
+	for _, k := range []int{1, 3, 7, 10, 15} {
+		got := kd.KNearestNeighbors(p, k)
+		for i := range got {
+			if got[i].Index != want[i].Index {
+				t.Fatal("k", k, "result", i, got[i], "expected", want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// LabelFunc extracts a category label from the data associated with a
+// point.  Labels are small non-negative integers.  A negative label
+// marks a point without a label.
+type LabelFunc func(data interface{}) int
+
+// IndexLabels registers label and stores for each subtree the set of
+// labels of its points, as a bitset, so that searches restricted to some
+// labels, such as NearestWithLabel and KNearestWithLabels, skip subtrees
+// holding none of them.
+//
+// This serves many labels in one tree where a tree per label would
+// multiply overhead, particularly when most labels have few points.  The
+// sets are kept up to date as points are added and subtrees are rebuilt.
+// As with IndexAttrs, deleted points leave sets valid but possibly loose.
+// The cost per interior node is a bit per label, up to the greatest label
+// in the subtree.  IndexLabels(nil) drops the index.
+func (t *KdTree) IndexLabels(label LabelFunc) {
+	t.label = label
+	if t.n != nil {
+		indexLabels(t.n, label)
+	}
+}
+
+// WithLabels indexes labels as IndexLabels.
+func WithLabels(label LabelFunc) Option {
+	return func(o *options) { o.label = label }
+}
+
+// NearestWithLabel returns the nearest neighbor of p with label l.  ok is
+// false if t has no point with label l.  t must have labels indexed with
+// IndexLabels.
+func (t KdTree) NearestWithLabel(p Point, l int) (n Neighbor, ok bool) {
+	nb := t.KNearestWithLabels(p, 1, l)
+	if len(nb) == 0 {
+		return Neighbor{}, false
+	}
+	return nb[0], true
+}
+
+// KNearestWithLabels returns the k nearest neighbors of p having any of
+// labels, nearest first.  t must have labels indexed with IndexLabels.
+func (t KdTree) KNearestWithLabels(p Point, k int, labels ...int) Neighbors {
+	s := t.NewSearcher()
+	s.labels = labelSet{}
+	for _, l := range labels {
+		s.labels = s.labels.add(l)
+	}
+	s.search(p, k)
+	n := Neighbors(s.h.e)
+	n.Sort()
+	return n
+}
+
+// labelSet is a bitset of labels.
+type labelSet []uint64
+
+// add returns s with label l added.  Negative labels are not added.
+func (s labelSet) add(l int) labelSet {
+	if l < 0 {
+		return s
+	}
+	for len(s) <= l/64 {
+		s = append(s, 0)
+	}
+	s[l/64] |= 1 << (l % 64)
+	return s
+}
+
+// union returns s with the labels of r added.
+func (s labelSet) union(r labelSet) labelSet {
+	for len(s) < len(r) {
+		s = append(s, 0)
+	}
+	for i, w := range r {
+		s[i] |= w
+	}
+	return s
+}
+
+func (s labelSet) has(l int) bool {
+	return l >= 0 && l/64 < len(s) && s[l/64]&(1<<(l%64)) != 0
+}
+
+func (s labelSet) intersects(r labelSet) bool {
+	for i := 0; i < len(s) && i < len(r); i++ {
+		if s[i]&r[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// indexLabels sets labels for interior nodes of the subtree at kd and
+// returns the label set of the subtree, or clears them if label is nil.
+func indexLabels(kd *kdNode, label LabelFunc) labelSet {
+	kd.force()
+	var s labelSet
+	if label != nil {
+		s = labelSet{}.add(label(kd.rangeElt))
+	}
+	for _, c := range []*kdNode{kd.left, kd.right} {
+		if c != nil {
+			s = s.union(indexLabels(c, label))
+		}
+	}
+	kd.labels = nil
+	if label != nil && (kd.left != nil || kd.right != nil) {
+		kd.labels = s
+		// a set with no labels must still be non-nil to prune
+		if kd.labels == nil {
+			kd.labels = labelSet{}
+		}
+	}
+	return s
+}
+
+// extendLabels adds the label of data to the set of kd.
+func (kd *kdNode) extendLabels(label LabelFunc, data interface{}) {
+	if kd.labels == nil {
+		kd.labels = labelSet{}.add(label(kd.rangeElt))
+	}
+	kd.labels = kd.labels.add(label(data))
+}
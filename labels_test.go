@@ -0,0 +1,76 @@
+package kdtree
+
+import "testing"
+
+func TestLabels(t *testing.T) {
+	pts := randomPts(2, 3000)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		// skewed: label 0 is common, higher labels are rare
+		l := 0
+		if i%10 == 0 {
+			l = 1 + i/10%200
+		}
+		data[i] = l
+	}
+	label := func(d interface{}) int {
+		if d == nil {
+			return -1
+		}
+		return d.(int)
+	}
+	kd := NewWith(pts, WithData(data), WithLabels(label),
+		WithBounds(HyperRect{Point{0, 0}, Point{1, 1}}))
+	kd.Brute = false
+	check := func() {
+		t.Helper()
+		for _, q := range randomPts(2, 20) {
+			for _, l := range []int{0, 7, 150, 170} {
+				var want Neighbor
+				found := false
+				walk(kd.n, func(n *kdNode) {
+					if !n.deleted && label(n.rangeElt) == l {
+						if d := n.domElt.Sqd(q); !found || d < want.Sqd {
+							want, found = n.neighbor(d), true
+						}
+					}
+				})
+				got, ok := kd.NearestWithLabel(q, l)
+				if ok != found || ok && got.Sqd != want.Sqd {
+					t.Fatal("label", l, "got", got, ok, "expected", want, found)
+				}
+			}
+		}
+	}
+	check()
+	nb := kd.KNearestWithLabels(Point{.5, .5}, 3, 3, 5)
+	if len(nb) != 3 {
+		t.Fatal("got", len(nb))
+	}
+	for _, n := range nb {
+		if l := n.Data.(int); l != 3 && l != 5 {
+			t.Fatal("label", l)
+		}
+	}
+	s := kd.NewSearcher()
+	s.labels = labelSet{}.add(150)
+	if nv := s.search(Point{.5, .5}, 1); nv > len(pts)/4 {
+		t.Error("rare label search visited", nv, "nodes")
+	}
+	// sets are maintained as points are added and removed
+	for i, p := range randomPts(2, 200) {
+		kd.InsertWithData(p, 170+i%3)
+	}
+	kd.InsertAll(randomPts(2, 100))
+	for i := 0; i < 300; i++ {
+		kd.Delete(pts[i])
+	}
+	check()
+	if _, ok := kd.NearestWithLabel(Point{.5, .5}, 1000); ok {
+		t.Error("found missing label")
+	}
+	kd.IndexLabels(nil)
+	if kd.n.labels != nil {
+		t.Error("index not dropped")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// PointSource is a stream of points in batches, such as from a reader of
+// a large file, for indexing without holding the whole input in memory.
+//
+// ReadBatch returns the next points and their associated data, which may
+// be nil.  At the end of the stream it returns io.EOF, possibly with a
+// final batch.  The points returned are kept, so a source must not reuse
+// their memory for later batches.
+type PointSource interface {
+	ReadBatch() (pts []Point, data []interface{}, err error)
+}
+
+// NewFromSource constructs a tree, as NewWith, from the points of src.
+// Only the points and data are kept, so, for example, a file of lidar
+// records can be indexed while reading it a batch at a time.
+func NewFromSource(src PointSource, opts ...Option) (KdTree, error) {
+	var b Builder
+	for {
+		pts, data, err := src.ReadBatch()
+		for i, p := range pts {
+			var d interface{}
+			if i < len(data) {
+				d = data[i]
+			}
+			b.Add(p, d)
+		}
+		if err == io.EOF {
+			return b.Build(opts...), nil
+		}
+		if err != nil {
+			return KdTree{}, err
+		}
+	}
+}
+
+// LASPoint is the data associated with each point read by a LASReader.
+// Fields absent from the point format are zero.
+type LASPoint struct {
+	Intensity      uint16
+	Classification uint8
+	GPSTime        float64
+	RGB            [3]uint16
+}
+
+// LASReader is a PointSource of the points of a LAS lidar file, versions
+// 1.0 through 1.4, point formats 0 through 10.  Coordinates are scaled
+// and offset as given by the header.  Compressed LAZ files must be
+// decompressed by another package first.
+type LASReader struct {
+	// BatchSize is the number of points read by each ReadBatch,
+	// DefaultLASBatch if zero.
+	BatchSize int
+
+	r      *bufio.Reader
+	format uint8
+	recLen int
+	left   uint64 // points not yet read
+	scale  [3]float64
+	offset [3]float64
+	rec    []byte
+}
+
+// DefaultLASBatch is the default LASReader.BatchSize.
+const DefaultLASBatch = 1 << 16
+
+var errLAS = errors.New("kdtree: invalid LAS")
+
+// NewLASReader reads the header of a LAS file from r and returns a
+// LASReader of its points.
+func NewLASReader(r io.Reader) (*LASReader, error) {
+	br := bufio.NewReader(r)
+	h := make([]byte, 227)
+	if _, err := io.ReadFull(br, h); err != nil || string(h[:4]) != "LASF" {
+		return nil, errLAS
+	}
+	le := binary.LittleEndian
+	minor := h[25]
+	hsize := int(le.Uint16(h[94:]))
+	start := int(le.Uint32(h[96:]))
+	l := &LASReader{
+		r:      br,
+		format: h[104] &^ 0xc0, // the high bits flag LAZ compression
+		recLen: int(le.Uint16(h[105:])),
+		left:   uint64(le.Uint32(h[107:])),
+	}
+	if h[104]&0xc0 != 0 {
+		return nil, fmt.Errorf("kdtree: compressed LAS not supported")
+	}
+	for i := 0; i < 3; i++ {
+		l.scale[i] = math.Float64frombits(le.Uint64(h[131+8*i:]))
+		l.offset[i] = math.Float64frombits(le.Uint64(h[155+8*i:]))
+	}
+	read := len(h)
+	if minor >= 4 && hsize >= 375 {
+		// LAS 1.4 has a 64 bit point count.
+		h14 := make([]byte, 375-len(h))
+		if _, err := io.ReadFull(br, h14); err != nil {
+			return nil, errLAS
+		}
+		read = 375
+		l.left = le.Uint64(h14[247-227:])
+	}
+	if l.format > 10 || l.recLen < lasMinRecord[l.format] || start < read {
+		return nil, errLAS
+	}
+	// skip the rest of the header and the variable length records.
+	if _, err := br.Discard(start - read); err != nil {
+		return nil, errLAS
+	}
+	l.rec = make([]byte, l.recLen)
+	return l, nil
+}
+
+// lasMinRecord is the least record length of each point format.
+var lasMinRecord = [11]int{20, 28, 26, 34, 57, 63, 30, 36, 38, 59, 67}
+
+// ReadBatch returns the next points of the file, each with LASPoint
+// data.
+func (l *LASReader) ReadBatch() (pts []Point, data []interface{}, err error) {
+	n := l.BatchSize
+	if n <= 0 {
+		n = DefaultLASBatch
+	}
+	if uint64(n) > l.left {
+		n = int(l.left)
+	}
+	if n == 0 {
+		return nil, nil, io.EOF
+	}
+	pts = make([]Point, n)
+	data = make([]interface{}, n)
+	coords := make([]float64, 3*n)
+	le := binary.LittleEndian
+	for i := range pts {
+		if _, err := io.ReadFull(l.r, l.rec); err != nil {
+			return pts[:i], data[:i], errLAS
+		}
+		p := Point(coords[3*i : 3*i+3 : 3*i+3])
+		for j := range p {
+			p[j] = float64(int32(le.Uint32(l.rec[4*j:])))*l.scale[j] + l.offset[j]
+		}
+		pts[i] = p
+		data[i] = l.decode(l.rec)
+	}
+	l.left -= uint64(n)
+	return pts, data, nil
+}
+
+// decode returns the attributes of point record rec.
+func (l *LASReader) decode(rec []byte) LASPoint {
+	le := binary.LittleEndian
+	d := LASPoint{Intensity: le.Uint16(rec[12:])}
+	rgb := -1
+	if l.format < 6 {
+		d.Classification = rec[15] & 0x1f
+		switch l.format {
+		case 1, 4:
+			d.GPSTime = math.Float64frombits(le.Uint64(rec[20:]))
+		case 2:
+			rgb = 20
+		case 3, 5:
+			d.GPSTime = math.Float64frombits(le.Uint64(rec[20:]))
+			rgb = 28
+		}
+	} else {
+		d.Classification = rec[16]
+		d.GPSTime = math.Float64frombits(le.Uint64(rec[22:]))
+		if l.format == 7 || l.format == 8 || l.format == 10 {
+			rgb = 30
+		}
+	}
+	if rgb >= 0 {
+		for i := range d.RGB {
+			d.RGB[i] = le.Uint16(rec[rgb+2*i:])
+		}
+	}
+	return d
+}
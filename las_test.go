@@ -0,0 +1,70 @@
+package kdtree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// lasFile returns a LAS 1.2 file of point format 3 holding pts, with a
+// variable length record to be skipped.
+func lasFile(pts []Point) []byte {
+	h := make([]byte, 227)
+	copy(h, "LASF")
+	h[24], h[25] = 1, 2
+	le := binary.LittleEndian
+	le.PutUint16(h[94:], 227)
+	le.PutUint32(h[96:], 227+60)
+	le.PutUint32(h[100:], 1)
+	h[104] = 3
+	le.PutUint16(h[105:], 34)
+	le.PutUint32(h[107:], uint32(len(pts)))
+	for i := 0; i < 3; i++ {
+		le.PutUint64(h[131+8*i:], math.Float64bits(.001))
+		le.PutUint64(h[155+8*i:], math.Float64bits(100))
+	}
+	b := append(h, make([]byte, 60)...)
+	for i, p := range pts {
+		rec := make([]byte, 34)
+		for j, c := range p {
+			le.PutUint32(rec[4*j:], uint32(int32(math.Round((c-100)/.001))))
+		}
+		le.PutUint16(rec[12:], uint16(i))
+		rec[15] = 2
+		le.PutUint64(rec[20:], math.Float64bits(float64(i)/10))
+		le.PutUint16(rec[28:], 65535)
+		b = append(b, rec...)
+	}
+	return b
+}
+
+func TestLAS(t *testing.T) {
+	pts := randomPts(3, 1000)
+	for _, p := range pts {
+		for i := range p {
+			p[i] = math.Round((100+50*p[i])/.001) * .001
+		}
+	}
+	l, err := NewLASReader(bytes.NewReader(lasFile(pts)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.BatchSize = 300
+	kd, err := NewFromSource(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range pts {
+		n := kd.KNearestNeighbors(p, 1)[0]
+		d := n.Data.(LASPoint)
+		if n.Sqd > 1e-18 || n.Index != i || d.Intensity != uint16(i) ||
+			d.Classification != 2 || d.GPSTime != float64(i)/10 ||
+			d.RGB != [3]uint16{65535} {
+			t.Fatal("point", i, n)
+		}
+	}
+	if _, err := NewLASReader(bytes.NewReader([]byte("LASX"))); err == nil {
+		t.Fatal("accepted invalid header")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// Repack moves the nodes of t into a single contiguous allocation in van
+// Emde Boas order.
+//
+// In this cache-oblivious layout the top half of the tree by height is
+// stored first, followed by each of the subtrees hanging from it, each
+// laid out the same way recursively.  Any root to leaf path then crosses
+// only O(log_B n) blocks of any block size B, so searches of trees much
+// larger than cache touch fewer cache lines and pages.  Points themselves
+// are not moved.
+//
+// Repack is a post-build step.  Trees are searched identically before and
+// after.  Nodes added later by modifying the tree are allocated
+// individually, so Repack may be called again to restore the layout.
+func (t *KdTree) Repack() {
+	if t.n == nil {
+		return
+	}
+	var order []*kdNode
+	vebOrder(t.n, height(t.n), &order)
+	slab := make([]kdNode, len(order))
+	index := make(map[*kdNode]*kdNode, len(order))
+	for i, kd := range order {
+		slab[i] = *kd
+		index[kd] = &slab[i]
+	}
+	for i := range slab {
+		slab[i].left = index[slab[i].left]
+		slab[i].right = index[slab[i].right]
+	}
+	t.n = &slab[0]
+}
+
+// height returns the number of levels in the subtree at kd.
+func height(kd *kdNode) int {
+	if kd == nil {
+		return 0
+	}
+	kd.force()
+	l, r := height(kd.left), height(kd.right)
+	if r > l {
+		l = r
+	}
+	return l + 1
+}
+
+// vebOrder appends the nodes of the subtree at kd, truncated to h levels,
+// to order in van Emde Boas order.
+func vebOrder(kd *kdNode, h int, order *[]*kdNode) {
+	if kd == nil {
+		return
+	}
+	if h == 1 {
+		*order = append(*order, kd)
+		return
+	}
+	top := h / 2
+	vebOrder(kd, top, order)
+	var bottom func(*kdNode, int)
+	bottom = func(kd *kdNode, depth int) {
+		if kd == nil {
+			return
+		}
+		if depth == top {
+			vebOrder(kd, h-top, order)
+			return
+		}
+		bottom(kd.left, depth+1)
+		bottom(kd.right, depth+1)
+	}
+	bottom(kd, 0)
+}
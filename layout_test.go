@@ -0,0 +1,25 @@
+package kdtree
+
+import "testing"
+
+func TestRepack(t *testing.T) {
+	pts := randomPts(3, 1000)
+	kd := New(pts, HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	qs := randomPts(3, 50)
+	var want []float64
+	for _, q := range qs {
+		_, sqd, _ := kd.Nearest(q)
+		want = append(want, sqd)
+	}
+	kd.Repack()
+	n := 0
+	walk(kd.n, func(*kdNode) { n++ })
+	if n != len(pts) {
+		t.Fatal("Expected", len(pts), "nodes after Repack, found", n)
+	}
+	for i, q := range qs {
+		if _, sqd, _ := kd.Nearest(q); sqd != want[i] {
+			t.Fatal("Repacked tree found distance^2", sqd, "expected", want[i])
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "sync"
+
+// NewLazy constructs a KdTree as New, but builds only the top depth
+// levels of the tree.  Deeper subtrees are built the first time a query
+// reaches them.
+//
+// For workloads that only touch a small region of space, most subtrees
+// are never built and construction time is mostly avoided.  Queries are
+// safe for concurrent use; a subtree reached by several queries at once
+// is built only once.  Operations that visit every
+// node, such as Tighten or a brute force search, build the whole tree.
+func NewLazy(pts []Point, bounds HyperRect, depth int) KdTree {
+	if depth < 0 {
+		depth = 0
+	}
+	return NewWith(pts, WithBounds(bounds), WithLazy(depth))
+}
+
+// lazySub holds the nodes of a deferred subtree.
+type lazySub struct {
+	once  sync.Once
+	nodes []*kdNode
+	split int
+	rule  SplitRule
+}
+
+// deferred reports whether the subtree at kd is yet to be built.  The
+// lazySub of a built subtree is kept, for its once, so kd.lazy alone does
+// not tell.  It must not be called concurrently with force.
+func (kd *kdNode) deferred() bool {
+	return kd.lazy != nil && kd.lazy.nodes != nil
+}
+
+// force builds the subtree at kd if it was deferred.
+func (kd *kdNode) force() {
+	l := kd.lazy
+	if l == nil {
+		return
+	}
+	l.once.Do(func() {
+		b := nk2(l.nodes, l.split, -1, l.rule)
+		kd.elt, kd.split = b.elt, b.split
+		kd.left, kd.right = b.left, b.right
+		l.nodes = nil
+	})
+}
@@ -0,0 +1,44 @@
+package kdtree
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestNewLazy(t *testing.T) {
+	pts := randomPts(2, 1000)
+	ref := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd := NewLazy(pts, HyperRect{Point{0, 0}, Point{1, 1}}, 3)
+	deferred := 0
+	var count func(*kdNode)
+	count = func(kd *kdNode) {
+		if kd == nil {
+			return
+		}
+		if kd.lazy != nil {
+			deferred++
+			return
+		}
+		count(kd.left)
+		count(kd.right)
+	}
+	count(kd.n)
+	if deferred != 8 {
+		t.Fatal("Expected 8 deferred subtrees, found", deferred)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				p := randomPt(2)
+				_, want, _ := ref.Nearest(p)
+				if _, got, _ := kd.Nearest(p); got != want {
+					t.Error("Expected distance^2", want, "found", got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,86 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"slices"
+)
+
+// LogTree is a dynamic index following the logarithmic method of Bentley
+// and Saxe.  It holds static trees, the i-th of which is either empty or
+// holds exactly 2^i points.
+//
+// Inserting works like incrementing a binary counter: the new point and
+// the points of the full trees at the low end are merged into a single
+// new tree built by New.  Each point takes part in O(log n) builds, for an
+// amortized insert cost of O(log^2 n) while every tree stays balanced.
+// Queries search all of the O(log n) trees, sharing one pruning bound.
+type LogTree struct {
+	Bounds HyperRect
+	trees  []KdTree
+	n      int
+}
+
+// NewLogTree returns an empty LogTree for points within bounds.
+func NewLogTree(bounds HyperRect) *LogTree {
+	return &LogTree{Bounds: bounds}
+}
+
+// Len returns the number of points in l.
+func (l *LogTree) Len() int { return l.n }
+
+// Insert adds p to l, growing l.Bounds if needed to contain p.
+func (l *LogTree) Insert(p Point) {
+	t := KdTree{Bounds: l.Bounds}
+	t.grow(p)
+	l.Bounds = t.Bounds
+	carry := []Point{p}
+	for i := range l.trees {
+		if l.trees[i].n == nil {
+			l.trees[i] = New(carry, l.Bounds)
+			l.n++
+			return
+		}
+		walk(l.trees[i].n, func(kd *kdNode) { carry = append(carry, kd.domElt) })
+		l.trees[i] = KdTree{}
+	}
+	l.trees = append(l.trees, New(carry, l.Bounds))
+	l.n++
+}
+
+// Nearest finds the nearest neighbor of p, as KdTree.Nearest.
+func (l *LogTree) Nearest(p Point) (best Point, bestSqd float64, nv int) {
+	nn, sqd, nv := l.KNearest(p, 1)
+	if len(nn) == 0 {
+		return nil, math.Inf(1), nv
+	}
+	return nn[0], sqd[0], nv
+}
+
+// KNearest finds the k nearest neighbors of p, as KdTree.KNearest.
+func (l *LogTree) KNearest(p Point, k int) (nn []Point, sqd []float64, nv int) {
+	var s Searcher
+	s.h.Reset(k)
+	if k <= 0 {
+		return
+	}
+	for _, t := range l.trees {
+		if t.n == nil {
+			continue
+		}
+		s.t = t
+		if t.Brute {
+			nv += s.scan(p)
+		} else {
+			nv += s.knn(p)
+		}
+	}
+	slices.SortFunc(s.h.e, compareNeighbors)
+	for _, e := range s.h.e {
+		nn = append(nn, e.Point)
+		sqd = append(sqd, e.Sqd)
+	}
+	return
+}
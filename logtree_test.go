@@ -0,0 +1,31 @@
+package kdtree
+
+import "testing"
+
+func TestLogTree(t *testing.T) {
+	l := NewLogTree(HyperRect{Point{0, 0}, Point{1, 1}})
+	if _, _, nv := l.Nearest(Point{.5, .5}); nv != 0 {
+		t.Error("Expected empty LogTree")
+	}
+	pts := randomPts(2, 1000)
+	for _, p := range pts {
+		l.Insert(p)
+	}
+	if l.Len() != 1000 {
+		t.Error("Expected Len 1000, found", l.Len())
+	}
+	// 1000 = 1111101000 binary
+	for i, tr := range l.trees {
+		if full := 1000>>uint(i)&1 == 1; full != (tr.n != nil) {
+			t.Error("tree", i, "full", tr.n != nil)
+		}
+	}
+	ref := New(pts, l.Bounds)
+	for i := 0; i < 50; i++ {
+		p := randomPt(2)
+		_, want, _ := ref.Nearest(p)
+		if _, got, _ := l.Nearest(p); got != want {
+			t.Fatal("Expected distance^2", want, "found", got)
+		}
+	}
+}
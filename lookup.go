@@ -0,0 +1,60 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// Contains reports whether t holds a point with exactly the coordinates
+// of p.
+//
+// The search descends by the split comparisons alone, taking time
+// proportional to the depth of the tree.
+func (t KdTree) Contains(p Point) bool {
+	return t.find(p) != nil
+}
+
+// Lookup returns the data associated with a point of t with exactly the
+// coordinates of p, and whether there is such a point.  If several points
+// have these coordinates, any one of them may be chosen.
+func (t KdTree) Lookup(p Point) (data interface{}, ok bool) {
+	if kd := t.find(p); kd != nil {
+		return kd.rangeElt, true
+	}
+	return nil, false
+}
+
+// Count returns the number of points of t with exactly the coordinates
+// of p.
+//
+// Points with equal coordinates all lie on the path Contains follows, so
+// Count also takes time proportional to the depth of the tree.
+func (t KdTree) Count(p Point) int {
+	c := 0
+	for kd := t.n; kd != nil; {
+		kd.force()
+		if t.sees(kd) && equal(kd.domElt, p) {
+			c++
+		}
+		if p[kd.split] <= kd.domElt[kd.split] {
+			kd = kd.left
+		} else {
+			kd = kd.right
+		}
+	}
+	return c
+}
+
+// find returns a node in the view of t with the coordinates of p, or nil.
+func (t KdTree) find(p Point) *kdNode {
+	for kd := t.n; kd != nil; {
+		kd.force()
+		if t.sees(kd) && equal(kd.domElt, p) {
+			return kd
+		}
+		if p[kd.split] <= kd.domElt[kd.split] {
+			kd = kd.left
+		} else {
+			kd = kd.right
+		}
+	}
+	return nil
+}
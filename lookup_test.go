@@ -0,0 +1,55 @@
+package kdtree
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	pts := randomPts(3, 500)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = i
+	}
+	kd := NewWithData(append([]Point{}, pts...), data,
+		HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	for i, p := range pts {
+		if d, ok := kd.Lookup(p); !ok || d != i {
+			t.Fatal("Lookup", p, "got", d, ok)
+		}
+	}
+	q := append(Point{}, pts[0]...)
+	q[2] += 1e-12
+	if kd.Contains(q) {
+		t.Error("Contains reported a point not in the tree")
+	}
+	kd.Remove(pts[1])
+	if kd.Contains(pts[1]) {
+		t.Error("Contains reported a removed point")
+	}
+	kd.Delete(pts[2])
+	if kd.Contains(pts[2]) {
+		t.Error("Contains reported a deleted point")
+	}
+}
+
+func TestCount(t *testing.T) {
+	pts := randomPts(2, 300)
+	// three copies of pts[0], two of pts[1]
+	pts = append(pts, pts[0], pts[1], pts[0])
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	for i, want := range []int{3, 2, 1} {
+		if c := kd.Count(pts[i]); c != want {
+			t.Error("Count", pts[i], c, "expected", want)
+		}
+	}
+	if _, sqd, _ := kd.KNearest(pts[0], 3); sqd[0]+sqd[1]+sqd[2] != 0 {
+		t.Error("expected 3 copies at distance 0, got", sqd)
+	}
+	kd.Remove(pts[0])
+	kd.Delete(pts[0])
+	if c := kd.Count(pts[0]); c != 1 {
+		t.Error("Count after removals", c)
+	}
+	if kd.Count(Point{2, 2}) != 0 {
+		t.Error("Count of absent point")
+	}
+}
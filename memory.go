@@ -0,0 +1,58 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "unsafe"
+
+// MemoryBytes returns an estimate of the heap memory used by t.
+//
+// The estimate counts the nodes, the coordinates of the points, the
+// bounds of t, the per-node bounding boxes stored by Tighten, the
+// attribute ranges stored by IndexAttrs, the label sets stored by
+// IndexLabels, the cell ID ranges stored by IndexCells, and the samples
+// stored by IndexQuantiles.  Subtrees not yet built by a tree from
+// NewLazy are counted as they are, without building them, so for such a
+// tree MemoryBytes must not run concurrently with queries.
+// Points are counted even though New does not copy them, so the memory
+// may be shared with the slice passed to New.  Data associated with
+// points, allocator overhead, and slice capacity beyond length are not
+// counted.
+func (t KdTree) MemoryBytes() uint64 {
+	const (
+		f = uint64(unsafe.Sizeof(float64(0)))
+		n = uint64(unsafe.Sizeof(kdNode{}))
+		h = uint64(unsafe.Sizeof(HyperRect{}))
+		p = uint64(unsafe.Sizeof(Point{}))
+		r = uint64(unsafe.Sizeof(&kdNode{}))
+		l = uint64(unsafe.Sizeof(lazySub{}))
+	)
+	b := uint64(len(t.Bounds.Min)+len(t.Bounds.Max)) * f
+	for stack := []*kdNode{t.n}; len(stack) > 0; {
+		kd := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if kd == nil {
+			continue
+		}
+		b += n + uint64(len(kd.domElt))*f
+		if kd.lazy != nil {
+			// the unlinked nodes of a deferred subtree
+			b += l + uint64(len(kd.lazy.nodes))*r
+			for _, u := range kd.lazy.nodes {
+				b += n + uint64(len(u.domElt))*f
+			}
+		}
+		if kd.bounds != nil {
+			b += h + uint64(len(kd.bounds.Min)+len(kd.bounds.Max))*f
+		}
+		b += uint64(len(kd.attrs)) * f
+		b += uint64(len(kd.labels)) * 8
+		if kd.cellIDs != nil {
+			b += uint64(unsafe.Sizeof(CellRange{}))
+		}
+		// sampled points share coordinates with the nodes.
+		b += uint64(len(kd.sample)) * p
+		stack = append(stack, kd.left, kd.right)
+	}
+	return b
+}
@@ -0,0 +1,52 @@
+package kdtree
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestMemoryBytes(t *testing.T) {
+	kd := New(randomPts(3, 100), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	m := kd.MemoryBytes()
+	want := uint64(6*8 + 100*(unsafe.Sizeof(kdNode{})+3*8))
+	if m != want {
+		t.Error("Expected", want, "bytes, found", m)
+	}
+	if kd.Tighten(); kd.MemoryBytes() <= m {
+		t.Error("Expected Tighten to increase MemoryBytes")
+	}
+	m = kd.MemoryBytes()
+	if kd.IndexQuantiles(8); kd.MemoryBytes() <= m {
+		t.Error("Expected IndexQuantiles to increase MemoryBytes")
+	}
+}
+
+func TestMemoryBytesLazy(t *testing.T) {
+	kd := NewLazy(randomPts(3, 100), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}}, 2)
+	deferred := func() (n int) {
+		for stack := []*kdNode{kd.n}; len(stack) > 0; {
+			x := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if x == nil {
+				continue
+			}
+			if x.deferred() {
+				n++
+				continue
+			}
+			stack = append(stack, x.left, x.right)
+		}
+		return
+	}
+	before := deferred()
+	if before == 0 {
+		t.Fatal("no deferred subtrees")
+	}
+	// each point is counted, without building the deferred subtrees
+	if m := kd.MemoryBytes(); m < uint64(6*8+100*(unsafe.Sizeof(kdNode{})+3*8)) {
+		t.Error("MemoryBytes", m)
+	}
+	if n := deferred(); n != before {
+		t.Error(before-n, "subtrees built")
+	}
+}
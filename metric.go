@@ -0,0 +1,143 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// Metric is a distance function for queries on a tree.
+//
+// Trees are built without regard to any metric; only pruning depends on
+// one.  A metric can be used for a query if BoxDist is a lower bound of
+// Dist from p to every point of the box, which holds for the usual
+// metrics that grow with the difference in each coordinate.
+type Metric interface {
+	// Dist returns the distance between p and q.
+	Dist(p, q Point) float64
+	// BoxDist returns a lower bound on Dist(p, q) for q in box.
+	BoxDist(p Point, box HyperRect) float64
+}
+
+// Euclidean is the Euclidean metric, the metric of queries such as
+// KNearest.
+type Euclidean struct{}
+
+func (Euclidean) Dist(p, q Point) float64 { return math.Sqrt(p.Sqd(q)) }
+
+func (Euclidean) BoxDist(p Point, box HyperRect) float64 {
+	return math.Sqrt(box.Sqd(p))
+}
+
+// WeightedEuclidean is the Euclidean metric with coordinate d scaled by
+// the non-negative weight W[d].
+type WeightedEuclidean struct{ W []float64 }
+
+func (m WeightedEuclidean) Dist(p, q Point) float64 {
+	s := 0.
+	for i, c := range p {
+		d := m.W[i] * (c - q[i])
+		s += d * d
+	}
+	return math.Sqrt(s)
+}
+
+func (m WeightedEuclidean) BoxDist(p Point, box HyperRect) float64 {
+	s := 0.
+	for i, c := range p {
+		d := m.W[i] * boxOffset(c, box, i)
+		s += d * d
+	}
+	return math.Sqrt(s)
+}
+
+// Manhattan is the L1 or taxicab metric.
+type Manhattan struct{}
+
+func (Manhattan) Dist(p, q Point) float64 {
+	s := 0.
+	for i, c := range p {
+		s += math.Abs(c - q[i])
+	}
+	return s
+}
+
+func (Manhattan) BoxDist(p Point, box HyperRect) float64 {
+	s := 0.
+	for i, c := range p {
+		s += math.Abs(boxOffset(c, box, i))
+	}
+	return s
+}
+
+// Chebyshev is the L∞ metric, the greatest coordinate difference.
+type Chebyshev struct{}
+
+func (Chebyshev) Dist(p, q Point) float64 {
+	m := 0.
+	for i, c := range p {
+		m = math.Max(m, math.Abs(c-q[i]))
+	}
+	return m
+}
+
+func (Chebyshev) BoxDist(p Point, box HyperRect) float64 {
+	m := 0.
+	for i, c := range p {
+		m = math.Max(m, math.Abs(boxOffset(c, box, i)))
+	}
+	return m
+}
+
+// boxOffset returns the offset of c from box in dimension i, 0 if within.
+func boxOffset(c float64, box HyperRect, i int) float64 {
+	switch {
+	case c < box.Min[i]:
+		return c - box.Min[i]
+	case c > box.Max[i]:
+		return c - box.Max[i]
+	}
+	return 0
+}
+
+// KNearestMetric finds the k nearest neighbors of p under metric m,
+// nearest first, over the same tree used for Euclidean queries.  The Sqd
+// of each Neighbor is the square of its distance under m.
+//
+// Subtrees are pruned by m.BoxDist of their cells or tight bounds, so
+// the search is exact for any metric meeting the requirement on BoxDist.
+func (t KdTree) KNearestMetric(p Point, k int, m Metric) Neighbors {
+	if t.n == nil || k <= 0 {
+		return Neighbors{}
+	}
+	h := NewKHeap(k)
+	c := newCellStack(t, 0.)
+	for {
+		kd, _, ok := c.pop()
+		if !ok {
+			break
+		}
+		if t.excludes(kd) {
+			continue
+		}
+		box := c.cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		if d := m.BoxDist(p, box); d*d > h.Worst() {
+			continue
+		}
+		if t.sees(kd) {
+			d := m.Dist(p, kd.domElt)
+			h.Push(kd.neighbor(d * d))
+		}
+		// the nearer child is pushed last, to be searched first
+		if p[kd.split] > kd.domElt[kd.split] {
+			c.push(kd, kd.left, 0)
+			c.push(kd, kd.right, 0)
+		} else {
+			c.push(kd, kd.right, 0)
+			c.push(kd, kd.left, 0)
+		}
+	}
+	return h.Results()
+}
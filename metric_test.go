@@ -0,0 +1,55 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKNearestMetric(t *testing.T) {
+	pts := randomPts(3, 2000)
+	kd := New(pts, HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	kd.Brute = false
+	metrics := []Metric{Euclidean{}, Manhattan{}, Chebyshev{},
+		WeightedEuclidean{[]float64{1, 5, .2}}}
+	for _, m := range metrics {
+		for _, q := range randomPts(3, 20) {
+			got := kd.KNearestMetric(q, 4, m)
+			// brute force k-th distance
+			ds := make([]float64, len(pts))
+			for i, p := range pts {
+				ds[i] = m.Dist(q, p)
+			}
+			for i := range got {
+				best := math.Inf(1)
+				bi := -1
+				for j, d := range ds {
+					if d < best {
+						best, bi = d, j
+					}
+				}
+				ds[bi] = math.Inf(1)
+				if got[i].Sqd != best*best {
+					t.Fatalf("%T %d got %v expected %v", m, i, got[i].Sqd, best*best)
+				}
+			}
+		}
+	}
+	want := kd.KNearestNeighbors(Point{.5, .5, .5}, 3)
+	got := kd.KNearestMetric(Point{.5, .5, .5}, 3, Euclidean{})
+	for i := range want {
+		if got[i].Index != want[i].Index {
+			t.Fatal("Euclidean got", got, "expected", want)
+		}
+	}
+}
+
+func TestKNearestMetricDeep(t *testing.T) {
+	kd, _ := deepTree(3000)
+	want := kd.KNearestNeighbors(Point{.5, .4}, 5)
+	got := kd.KNearestMetric(Point{.5, .4}, 5, Euclidean{})
+	for i := range want {
+		if math.Abs(got[i].Sqd-want[i].Sqd) > 1e-12 {
+			t.Fatal("result", i, got[i], "expected", want[i])
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"expvar"
+	"time"
+)
+
+// Metrics receives measurements of the operations on a tree.  Set
+// KdTree.Metrics to record them.
+//
+// Methods may be called concurrently by concurrent queries.
+type Metrics interface {
+	// Query is called after each query with its kind, such as "Nearest"
+	// or "KNearest", the number of nodes visited, and the time taken.
+	Query(kind string, nodesVisited int, elapsed time.Duration)
+	// Rebuild is called after a subtree of size nodes is rebuilt, with
+	// the reason, such as "rebalance" or "compact".
+	Rebuild(reason string, size int)
+}
+
+// rebuilt reports a rebuild to t.Metrics, if set.
+func (t KdTree) rebuilt(reason string, size int) {
+	if t.Metrics != nil {
+		t.Metrics.Rebuild(reason, size)
+	}
+}
+
+// ExpvarMetrics is a Metrics publishing counters with package expvar.
+//
+// For each query kind it keeps kind.count, kind.nodes, and kind.ns, the
+// totals of queries, nodes visited, and nanoseconds taken.  For each
+// rebuild reason it keeps rebuild.reason and rebuild.reason.nodes.
+type ExpvarMetrics struct {
+	m *expvar.Map
+}
+
+// NewExpvarMetrics returns an ExpvarMetrics publishing its counters as an
+// expvar.Map with the given name.  Like expvar.Publish, it panics if the
+// name is already in use.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	return &ExpvarMetrics{expvar.NewMap(name)}
+}
+
+// Map returns the map holding the counters.
+func (e *ExpvarMetrics) Map() *expvar.Map { return e.m }
+
+// Query implements Metrics.
+func (e *ExpvarMetrics) Query(kind string, nodesVisited int, elapsed time.Duration) {
+	e.m.Add(kind+".count", 1)
+	e.m.Add(kind+".nodes", int64(nodesVisited))
+	e.m.Add(kind+".ns", int64(elapsed))
+}
+
+// Rebuild implements Metrics.
+func (e *ExpvarMetrics) Rebuild(reason string, size int) {
+	e.m.Add("rebuild."+reason, 1)
+	e.m.Add("rebuild."+reason+".nodes", int64(size))
+}
@@ -0,0 +1,105 @@
+package kdtree
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testMetrics struct {
+	mu       sync.Mutex
+	queries  map[string]int
+	nodes    int
+	rebuilds map[string]int
+}
+
+func (m *testMetrics) Query(kind string, nv int, d time.Duration) {
+	m.mu.Lock()
+	m.queries[kind]++
+	m.nodes += nv
+	m.mu.Unlock()
+}
+
+func (m *testMetrics) Rebuild(reason string, size int) {
+	m.mu.Lock()
+	m.rebuilds[reason]++
+	m.mu.Unlock()
+}
+
+func TestMetrics(t *testing.T) {
+	m := &testMetrics{queries: map[string]int{}, rebuilds: map[string]int{}}
+	kd := New(randomPts(2, 1000), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	kd.Metrics = m
+	p := randomPt(2)
+	kd.Nearest(p)
+	kd.KNearest(p, 5)
+	kd.KNearestParallel(p, 5, 4)
+	kd.InRadius(p, .1)
+	for kind, n := range map[string]int{"Nearest": 1, "KNearest": 1,
+		"KNearestParallel": 1, "Range": 1} {
+		if m.queries[kind] != n {
+			t.Error(kind, "recorded", m.queries[kind], "times, expected", n)
+		}
+	}
+	if m.nodes == 0 {
+		t.Error("no nodes visited recorded")
+	}
+	kd.Rebalance()
+	for _, p := range randomPts(2, 5) {
+		kd.Remove(p)
+	}
+	kd.Compact()
+	if m.rebuilds["rebalance"] != 1 || m.rebuilds["compact"] != 1 {
+		t.Error("rebuilds", m.rebuilds)
+	}
+}
+
+func TestExpvarMetrics(t *testing.T) {
+	e := NewExpvarMetrics(fmt.Sprint("kdtree_test", time.Now().UnixNano()))
+	kd := New(randomPts(2, 100), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Metrics = e
+	kd.Nearest(randomPt(2))
+	kd.Nearest(randomPt(2))
+	if v := e.Map().Get("Nearest.count"); v == nil || v.String() != "2" {
+		t.Error("Nearest.count", v)
+	}
+}
+
+type testTracer struct {
+	started int
+	ended   []QueryStats
+}
+
+func (tr *testTracer) OnQueryStart(kind string) interface{} {
+	tr.started++
+	return tr.started
+}
+
+func (tr *testTracer) OnQueryEnd(span interface{}, s QueryStats) {
+	if span != len(tr.ended)+1 {
+		panic("span mismatch")
+	}
+	tr.ended = append(tr.ended, s)
+}
+
+func TestTracer(t *testing.T) {
+	tr := &testTracer{}
+	kd := New(randomPts(2, 1000), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	kd.Tracer = tr
+	p := randomPt(2)
+	kd.Nearest(p)
+	kd.KNearest(p, 3)
+	if tr.started != 2 || len(tr.ended) != 2 {
+		t.Fatal("started", tr.started, "ended", len(tr.ended))
+	}
+	s := tr.ended[1]
+	if s.Kind != "KNearest" || s.Size != 1000 || s.NodesVisited == 0 {
+		t.Fatal(s)
+	}
+	if r := s.PruningRatio(); r <= 0 || r >= 1 {
+		t.Error("pruning ratio", r)
+	}
+}
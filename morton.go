@@ -0,0 +1,138 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"math/bits"
+	"slices"
+	"sort"
+)
+
+// NewMorton constructs a KdTree as New, but builds it from the points
+// sorted in Morton order, the Z-order space filling curve.
+//
+// Each coordinate is quantized to a grid over bounds and the bits of the
+// grid coordinates are interleaved into a 64 bit code.  With the points
+// sorted by code, each subtree is a contiguous run and is split where the
+// highest bit in which its codes differ changes, so no further sorting is
+// needed.  Nodes are allocated in code order, so nodes near in space are
+// near in memory.
+//
+// Splits fall at the middle of grid cells rather than at medians, so
+// trees of uniform points are close to balanced but clustered points may
+// give deeper trees than New.  Points in the same finest grid cell are
+// built as New.  Trees of more than 64 dimensions are built as New.
+func NewMorton(pts []Point, bounds HyperRect) KdTree {
+	return NewWith(pts, WithBounds(bounds), WithMortonOrder())
+}
+
+// nkMorton links nodes into a tree in Morton order over bounds.  nodes is
+// reordered.
+func nkMorton(nodes []*kdNode, bounds HyperRect) *kdNode {
+	dim := len(bounds.Min)
+	if len(nodes) == 0 || dim == 0 || dim > 64 {
+		return nk2(nodes, 0, -1, SplitCycle)
+	}
+	// at most 63 bits per dimension, so the grid size fits in a uint64.
+	nb := min(64/dim, 63)
+	codes := make([]uint64, len(nodes))
+	scale := math.Ldexp(1, nb)
+	last := uint64(1)<<nb - 1
+	q := make([]uint64, dim)
+	for i, n := range nodes {
+		for d, c := range n.domElt {
+			w := bounds.Max[d] - bounds.Min[d]
+			f := 0.
+			if w > 0 {
+				f = math.Floor((c - bounds.Min[d]) / w * scale)
+			}
+			// clamped in integers, as scale-1 may round to scale.
+			q[d] = min(uint64(min(max(f, 0), scale)), last)
+		}
+		var code uint64
+		for b := nb - 1; b >= 0; b-- {
+			for d := range q {
+				code = code<<1 | q[d]>>b&1
+			}
+		}
+		codes[i] = code
+	}
+	perm := make([]int, len(nodes))
+	for i := range perm {
+		perm[i] = i
+	}
+	slices.SortFunc(perm, func(a, b int) int {
+		switch {
+		case codes[a] < codes[b]:
+			return -1
+		case codes[a] > codes[b]:
+			return 1
+		}
+		return a - b
+	})
+	slab := make([]kdNode, len(nodes))
+	sorted := make([]*kdNode, len(nodes))
+	sc := make([]uint64, len(nodes))
+	for i, j := range perm {
+		slab[i] = *nodes[j]
+		sorted[i] = &slab[i]
+		sc[i] = codes[j]
+	}
+	return mortonLink(sorted, sc, dim)
+}
+
+// mortonLink links nodes, sorted by their Morton codes, into a subtree.
+func mortonLink(nodes []*kdNode, codes []uint64, dim int) *kdNode {
+	var root *kdNode
+	type job struct {
+		lo, hi int
+		link   **kdNode
+	}
+	stack := []job{{0, len(nodes), &root}}
+	for len(stack) > 0 {
+		j := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		lo, hi := j.lo, j.hi
+		if lo == hi {
+			*j.link = nil
+			continue
+		}
+		x := codes[lo] ^ codes[hi-1]
+		if x == 0 {
+			// all in one grid cell
+			*j.link = nk2(nodes[lo:hi], 0, -1, SplitCycle)
+			continue
+		}
+		// codes of the run agree above bit k and, being sorted, have bit k
+		// clear before m and set from m on.  the run splits there on
+		// dimension d, with grid coordinates below m strictly less.
+		k := 63 - bits.LeadingZeros64(x)
+		d := dim - 1 - k%dim
+		m := lo + sort.Search(hi-lo, func(i int) bool {
+			return codes[lo+i]>>k&1 == 1
+		})
+		// the pivot is the greatest of the lower side in dimension d,
+		// moved to the end of the side keeping the rest in code order.
+		p := lo
+		for i := lo + 1; i < m; i++ {
+			if nodes[i].domElt[d] > nodes[p].domElt[d] {
+				p = i
+			}
+		}
+		kd := nodes[p]
+		c := codes[p]
+		copy(nodes[p:m-1], nodes[p+1:m])
+		copy(codes[p:m-1], codes[p+1:m])
+		nodes[m-1], codes[m-1] = kd, c
+		kd.split = d
+		kd.size = hi - lo
+		kd.bounds = nil
+		kd.attrs = nil
+		kd.labels = nil
+		*j.link = kd
+		stack = append(stack, job{lo, m - 1, &kd.left}, job{m, hi, &kd.right})
+	}
+	return root
+}
@@ -0,0 +1,55 @@
+package kdtree
+
+import "testing"
+
+func TestNewMorton(t *testing.T) {
+	pts := randomPts(3, 5000)
+	pts = append(pts, pts[:50]...)
+	kd := NewMorton(pts, HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	kd.Brute = false
+	checkSizes(t, kd.n)
+	checkNearest(t, kd, len(pts))
+	if s := kd.Stats(); s.Nodes != len(pts) || s.Depth > 30 {
+		t.Errorf("%+v", s)
+	}
+	for i, p := range pts {
+		if !kd.Contains(p) {
+			t.Fatal("point", i, "not found")
+		}
+	}
+	n := kd.KNearestNeighbors(pts[1234], 1)
+	if n[0].Index != 1234 {
+		t.Error("index", n[0].Index)
+	}
+	// points outside the bounds and in a degenerate dimension
+	out := []Point{{-1, .5, 0}, {2, .5, 0}, {.5, .5, 0}, {.25, .5, 0}}
+	kd = NewWith(out, WithMortonOrder(),
+		WithBounds(HyperRect{Point{0, 0, 0}, Point{1, 1, 0}}))
+	checkSizes(t, kd.n)
+	for _, p := range out {
+		if !kd.Contains(p) {
+			t.Fatal(p, "not found")
+		}
+	}
+}
+
+func TestNewMorton1D(t *testing.T) {
+	var pts []Point
+	for i := 0; i < 80; i++ {
+		pts = append(pts, Point{float64(i) / 100})
+	}
+	pts = append(pts, Point{1}, Point{1})
+	kd := NewMorton(pts, HyperRect{Point{0}, Point{1}})
+	kd.Brute = false
+	checkSizes(t, kd.n)
+	checkNearest(t, kd, len(pts))
+	// the root splits at the middle of the grid, not at the median.
+	if kd.n.domElt[0] != .49 {
+		t.Error("root", kd.n.domElt)
+	}
+	for _, p := range pts {
+		if !kd.Contains(p) {
+			t.Fatal(p, "not found")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"slices"
+)
+
+// Neighbor is a point found by a query, with its index and data as
+// numbered and given when the point was added to the tree, and the square
+// of its distance from the query point.
+type Neighbor struct {
+	Point Point
+	Index int
+	Data  interface{}
+	Sqd   float64
+}
+
+// Neighbors is a set of query results.
+type Neighbors []Neighbor
+
+// Sort sorts n by increasing distance.  Neighbors at equal distances are
+// ordered by index.
+func (n Neighbors) Sort() {
+	slices.SortFunc(n, compareNeighbors)
+}
+
+// compareNeighbors orders neighbors by distance, then index.
+func compareNeighbors(a, b Neighbor) int {
+	switch {
+	case a.Sqd < b.Sqd:
+		return -1
+	case a.Sqd > b.Sqd:
+		return 1
+	}
+	return a.Index - b.Index
+}
+
+// Trim sorts n and returns the k nearest.
+func (n Neighbors) Trim(k int) Neighbors {
+	n.Sort()
+	if k < len(n) {
+		n = n[:k]
+	}
+	return n
+}
+
+// Points returns the points of n.
+func (n Neighbors) Points() []Point {
+	pts := make([]Point, len(n))
+	for i, nb := range n {
+		pts[i] = nb.Point
+	}
+	return pts
+}
+
+// Dists returns the distances of n, the square roots of the Sqd fields.
+func (n Neighbors) Dists() []float64 {
+	d := make([]float64, len(n))
+	for i, nb := range n {
+		d[i] = math.Sqrt(nb.Sqd)
+	}
+	return d
+}
+
+// Merge returns the union of n and m, sorted.  A point appearing in both,
+// as identified by its index, appears once in the result.
+func (n Neighbors) Merge(m Neighbors) Neighbors {
+	r := append(append(Neighbors{}, n...), m...)
+	r.Sort()
+	seen := make(map[int]bool, len(r))
+	u := r[:0]
+	for _, nb := range r {
+		if !seen[nb.Index] {
+			seen[nb.Index] = true
+			u = append(u, nb)
+		}
+	}
+	return u
+}
+
+// KNearestNeighbors finds the k nearest neighbors of p, as KNearest, and
+// returns them as sorted Neighbors.
+func (t KdTree) KNearestNeighbors(p Point, k int) Neighbors {
+	s := t.NewSearcher()
+	s.search(p, k)
+	n := Neighbors(s.h.e)
+	n.Sort()
+	return n
+}
+
+// Neighbors returns up to k points of t within distance r of p, the
+// nearest first.
+//
+// Both limits prune the search.  The radius bounds the search from the
+// start, and once k points are found the distance to the k-th bounds it
+// further.
+func (t KdTree) Neighbors(p Point, k int, r float64) Neighbors {
+	s := t.NewSearcher()
+	s.limit, s.limited = r*r, true
+	s.search(p, k)
+	n := Neighbors(s.h.e)
+	n.Sort()
+	return n
+}
+
+// InRadiusNeighbors returns the points of t within distance r of p as
+// sorted Neighbors.
+func (t KdTree) InRadiusNeighbors(p Point, r float64) (n Neighbors) {
+	r2 := r * r
+	box := HyperRect{make(Point, len(p)), make(Point, len(p))}
+	for i, c := range p {
+		box.Min[i] = c - r
+		box.Max[i] = c + r
+	}
+	within := func(q Point) bool { return q.Sqd(p) <= r2 }
+	t.rangeSearch(box, within, func(kd *kdNode) bool {
+		n = append(n, kd.neighbor(kd.domElt.Sqd(p)))
+		return true
+	})
+	n.Sort()
+	return
+}
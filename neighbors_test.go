@@ -0,0 +1,83 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKNearestNeighbors(t *testing.T) {
+	pts := randomPts(2, 300)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = i * 10
+	}
+	kd := NewWithData(append([]Point{}, pts...), data,
+		HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	p := Point{.5, .5}
+	n := kd.KNearestNeighbors(p, 7)
+	if len(n) != 7 {
+		t.Fatal("got", len(n), "neighbors, expected 7")
+	}
+	for i, nb := range n {
+		if i > 0 && nb.Sqd < n[i-1].Sqd {
+			t.Fatal("not sorted")
+		}
+		if !equal(pts[nb.Index], nb.Point) || nb.Data != nb.Index*10 {
+			t.Fatal("bad index or data", nb)
+		}
+	}
+	r := math.Sqrt(n[6].Sqd)
+	// r*r may round below n[6].Sqd
+	in := kd.InRadiusNeighbors(p, math.Nextafter(r, 2))
+	if len(in) != 7 {
+		t.Fatal("InRadiusNeighbors returned", len(in), "expected 7")
+	}
+	for i := range in {
+		if in[i].Index != n[i].Index {
+			t.Fatal("InRadiusNeighbors", in[i], "KNearestNeighbors", n[i])
+		}
+	}
+	if d := n.Dists(); d[6] != r {
+		t.Fatal("Dists", d[6], "expected", r)
+	}
+	m := n[:4].Merge(n[2:])
+	if len(m) != 7 {
+		t.Fatal("Merge returned", len(m), "expected 7")
+	}
+	if tr := m.Trim(3); len(tr) != 3 || tr[2].Index != n[2].Index {
+		t.Fatal("Trim", tr)
+	}
+}
+
+func TestUpdateKeepsData(t *testing.T) {
+	pts := []Point{{.1, .1}, {.9, .9}, {.2, .8}, {.8, .2}}
+	kd := NewWithData(pts, []interface{}{"a", "b", "c", "d"},
+		HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Update(Point{.1, .1}, Point{.95, .95})
+	n := kd.KNearestNeighbors(Point{1, 1}, 1)
+	if n[0].Index != 0 || n[0].Data != "a" {
+		t.Fatal("got", n[0])
+	}
+}
+
+func TestNeighborsKR(t *testing.T) {
+	kd := New(randomPts(3, 2000), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	for _, brute := range []bool{false, true} {
+		kd.Brute = brute
+		for i := 0; i < 20; i++ {
+			p := randomPt(3)
+			r := .05 + .1*float64(i%3)
+			want := kd.InRadiusNeighbors(p, r).Trim(10)
+			got := kd.Neighbors(p, 10, r)
+			if len(got) != len(want) {
+				t.Fatal("got", len(got), "neighbors, expected", len(want))
+			}
+			for j := range got {
+				if got[j].Index != want[j].Index {
+					t.Fatal("neighbor", j, got[j], "expected", want[j])
+				}
+			}
+		}
+	}
+}
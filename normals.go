@@ -0,0 +1,224 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// NormalFunc returns the unit surface normal of a point of an oriented
+// point cloud, given the index and data of the point.  Normals are
+// commonly stored in the data, or in a slice by index such as returned
+// by EstimateNormals.
+type NormalFunc func(index int, data interface{}) Point
+
+// KNearestOriented returns the k nearest neighbors of p whose normals, as
+// given by normals, are within maxAngle radians of the unit normal n,
+// nearest first.
+//
+// Incompatible points are rejected within the search, which continues
+// until k compatible neighbors are found, so results are the same as
+// filtering a complete nearest neighbor ordering.
+func (t KdTree) KNearestOriented(p, n Point, k int, maxAngle float64,
+	normals NormalFunc) Neighbors {
+	cosMin := math.Cos(maxAngle)
+	s := t.NewSearcher()
+	s.accept = func(nb Neighbor) bool {
+		return dot(normals(nb.Index, nb.Data), n) >= cosMin
+	}
+	s.search(p, k)
+	r := Neighbors(s.h.e)
+	r.Sort()
+	return r
+}
+
+// NormalsByIndex returns a NormalFunc giving normals[i] for the point of
+// index i.
+func NormalsByIndex(normals []Point) NormalFunc {
+	return func(i int, _ interface{}) Point { return normals[i] }
+}
+
+// EstimateNormals estimates a unit normal for each point of t as the
+// direction of least variance, by principal component analysis, of the
+// point and its k-1 nearest neighbors.  The result is indexed by point
+// index, with nil for deleted points.
+//
+// The sign of each normal is arbitrary.  OrientNormals makes the signs
+// consistent.
+func (t KdTree) EstimateNormals(k int) []Point {
+	normals := make([]Point, t.next)
+	s := t.NewSearcher()
+	walk(t.n, func(kd *kdNode) {
+		if !t.sees(kd) {
+			return
+		}
+		s.search(kd.domElt, k)
+		normals[kd.index] = leastComponent(s.h.e)
+	})
+	return normals
+}
+
+// leastComponent returns the unit eigenvector of least eigenvalue of the
+// covariance of the points of n.
+func leastComponent(n []Neighbor) Point {
+	dim := len(n[0].Point)
+	mean := make(Point, dim)
+	for _, nb := range n {
+		for i, c := range nb.Point {
+			mean[i] += c / float64(len(n))
+		}
+	}
+	a := make([][]float64, dim)
+	for i := range a {
+		a[i] = make([]float64, dim)
+	}
+	for _, nb := range n {
+		for i := range a {
+			for j := range a {
+				a[i][j] += (nb.Point[i] - mean[i]) * (nb.Point[j] - mean[j])
+			}
+		}
+	}
+	val, vec := jacobiEigen(a)
+	m := 0
+	for i := range val {
+		if val[i] < val[m] {
+			m = i
+		}
+	}
+	p := make(Point, dim)
+	for i := range p {
+		p[i] = vec[i][m]
+	}
+	return p
+}
+
+// jacobiEigen returns the eigenvalues of the symmetric matrix a, which it
+// destroys, and the eigenvectors as the columns of v, by the cyclic
+// Jacobi method.
+func jacobiEigen(a [][]float64) (val []float64, v [][]float64) {
+	n := len(a)
+	v = make([][]float64, n)
+	for i := range v {
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+	for sweep := 0; sweep < 50; sweep++ {
+		off := 0.
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += a[i][j] * a[i][j]
+			}
+		}
+		if off == 0 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				// the rotation zeroing a[p][q]
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p], a[k][q] = c*akp-s*akq, s*akp+c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k], a[q][k] = c*apk-s*aqk, s*apk+c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p], v[k][q] = c*vkp-s*vkq, s*vkp+c*vkq
+				}
+			}
+		}
+	}
+	val = make([]float64, n)
+	for i := range val {
+		val[i] = a[i][i]
+	}
+	return
+}
+
+// OrientNormals flips normals, as returned by EstimateNormals, to make
+// their signs consistent over the surface, following Hoppe et al.
+//
+// Orientation propagates along a minimum spanning tree of the graph
+// joining each point to its k-1 nearest neighbors, weighted so that
+// nearly parallel normals are joined first.  Each connected part is
+// started from its point greatest in the last coordinate, whose normal
+// is made to point toward increasing values of that coordinate.
+func (t KdTree) OrientNormals(normals []Point, k int) {
+	var nodes []*kdNode
+	byIndex := make([]*kdNode, len(normals))
+	walk(t.n, func(kd *kdNode) {
+		if t.sees(kd) && normals[kd.index] != nil {
+			nodes = append(nodes, kd)
+			byIndex[kd.index] = kd
+		}
+	})
+	if len(nodes) == 0 {
+		return
+	}
+	last := len(nodes[0].domElt) - 1
+	slices.SortFunc(nodes, func(a, b *kdNode) int {
+		return cmp.Compare(b.domElt[last], a.domElt[last])
+	})
+	flip := func(i int) {
+		for j, c := range normals[i] {
+			normals[i][j] = -c
+		}
+	}
+	done := make([]bool, len(normals))
+	parent := make([]int, len(normals))
+	best := make([]float64, len(normals))
+	for i := range best {
+		best[i] = math.Inf(1)
+	}
+	s := t.NewSearcher()
+	var q branchQueue
+	for _, seed := range nodes {
+		if done[seed.index] {
+			continue
+		}
+		if normals[seed.index][last] < 0 {
+			flip(seed.index)
+		}
+		parent[seed.index], best[seed.index] = seed.index, 0
+		q.push(branch{seed, 0})
+		for len(q) > 0 {
+			b := q.pop()
+			i := b.kd.index
+			if done[i] || b.rd > best[i] {
+				continue
+			}
+			done[i] = true
+			if dot(normals[i], normals[parent[i]]) < 0 {
+				flip(i)
+			}
+			s.search(b.kd.domElt, k)
+			for _, nb := range s.h.e {
+				j := nb.Index
+				if done[j] || normals[j] == nil {
+					continue
+				}
+				w := 1 - math.Abs(dot(normals[i], normals[j]))
+				if w < best[j] {
+					best[j], parent[j] = w, i
+					q.push(branch{byIndex[j], w})
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKNearestOriented(t *testing.T) {
+	pts := randomPts(3, 2000)
+	normals := make([]Point, len(pts))
+	for i := range normals {
+		normals[i] = unit(randomPt(3))
+	}
+	kd := NewWith(append([]Point{}, pts...))
+	kd.Brute = false
+	n := unit(Point{1, 1, 1})
+	ang := math.Pi / 8
+	p := randomPt(3)
+	got := kd.KNearestOriented(p, n, 5, ang, NormalsByIndex(normals))
+	var want []Neighbor
+	for i, q := range pts {
+		if dot(normals[i], n) >= math.Cos(ang) {
+			want = append(want, Neighbor{q, i, nil, q.Sqd(p)})
+		}
+	}
+	Neighbors(want).Sort()
+	if len(got) != 5 {
+		t.Fatal("got", len(got), "neighbors")
+	}
+	for i, nb := range got {
+		if nb.Index != want[i].Index {
+			t.Fatal("neighbor", i, nb, "expected", want[i])
+		}
+	}
+}
+
+func TestEstimateNormals(t *testing.T) {
+	// points on the unit sphere, whose normals are radial
+	pts := make([]Point, 2000)
+	for i := range pts {
+		pts[i] = unit(Point{randomPt(1)[0] - .5, randomPt(1)[0] - .5,
+			randomPt(1)[0] - .5})
+	}
+	kd := NewWith(append([]Point{}, pts...))
+	normals := kd.EstimateNormals(10)
+	for i, n := range normals {
+		if c := math.Abs(dot(n, pts[i])); c < .95 {
+			t.Fatal("normal", n, "at", pts[i], "off by", math.Acos(c))
+		}
+	}
+	kd.OrientNormals(normals, 10)
+	// consistent orientation makes all normals outward, as the seed is
+	// oriented toward increasing z at the top of the sphere.
+	for i, n := range normals {
+		if dot(n, pts[i]) < 0 {
+			t.Fatal("normal", n, "at", pts[i], "points inward")
+		}
+	}
+}
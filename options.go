@@ -0,0 +1,248 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+)
+
+// Option configures a tree constructed by NewWith.
+type Option func(*options)
+
+type options struct {
+	bounds   *HyperRect
+	data     []interface{}
+	copy     bool
+	fixed    bool
+	tighten  bool
+	lazy     int
+	parallel int
+	attrs    []AttrFunc
+	label    LabelFunc
+	snap     float64
+	merge    bool
+	morton   bool
+	split    SplitRule
+}
+
+// WithBounds sets the bounds of the tree.  Without it, the bounds are the
+// bounding box of the points.
+func WithBounds(bounds HyperRect) Option {
+	return func(o *options) { o.bounds = &bounds }
+}
+
+// WithFixedBounds sets KdTree.FixedBounds.
+func WithFixedBounds() Option {
+	return func(o *options) { o.fixed = true }
+}
+
+// WithData associates data[i] with pts[i] as in NewWithData.
+func WithData(data []interface{}) Option {
+	return func(o *options) { o.data = data }
+}
+
+// WithCopy copies the points so the tree does not share the caller's
+// coordinate slices.
+func WithCopy() Option {
+	return func(o *options) { o.copy = true }
+}
+
+// WithTighten computes tight bounding boxes as KdTree.Tighten.
+func WithTighten() Option {
+	return func(o *options) { o.tighten = true }
+}
+
+// WithLazy builds only the top depth levels of the tree, as NewLazy.
+func WithLazy(depth int) Option {
+	return func(o *options) {
+		if depth < 0 {
+			depth = 0
+		}
+		o.lazy = depth
+	}
+}
+
+// WithParallelism builds subtrees on up to n goroutines.
+func WithParallelism(n int) Option {
+	return func(o *options) { o.parallel = n }
+}
+
+// WithSplitRule sets KdTree.Split, the rule choosing the dimension each
+// node splits on.
+func WithSplitRule(rule SplitRule) Option {
+	return func(o *options) { o.split = rule }
+}
+
+// WithSnap snaps each coordinate to the nearest multiple of resolution,
+// as Snap, removing noise below that resolution so that points can be
+// looked up exactly by their snapped coordinates.  The points are
+// copied, leaving the caller's unchanged.  Bounds given by WithBounds are
+// grown if needed to contain the snapped points.
+//
+// If merge is true, points that snap to the same coordinates are stored
+// once, keeping the index and data of the first of them.  Indexes remain
+// positions in pts, so indexes of merged points are not used.
+func WithSnap(resolution float64, merge bool) Option {
+	return func(o *options) { o.snap, o.merge = resolution, merge }
+}
+
+// Snap returns p with each coordinate rounded to the nearest multiple of
+// resolution.  resolution <= 0 returns a copy of p.
+func Snap(p Point, resolution float64) Point {
+	s := make(Point, len(p))
+	for i, c := range p {
+		if resolution > 0 {
+			c = math.Round(c/resolution) * resolution
+		}
+		if c == 0 {
+			c = 0 // not -0, so equal points have equal bits
+		}
+		s[i] = c
+	}
+	return s
+}
+
+// WithMortonOrder builds the tree from the points sorted in Morton
+// order, as described for NewMorton.  It takes precedence over WithLazy,
+// WithParallelism, and WithSplitRule, though later rebuilds of subtrees
+// follow the split rule.
+func WithMortonOrder() Option {
+	return func(o *options) { o.morton = true }
+}
+
+// NewWith constructs a KdTree from pts as configured by opts.
+//
+// With no options it is the same as New with bounds computed from pts.
+// New, NewWithData, and NewLazy remain for the common cases, as NewWith
+// with WithBounds, WithData, and WithLazy.
+//
+// There are no options for leaf size or metric.  The tree keeps a point
+// in every node, as in the paper, so there are no leaf buckets to size,
+// and its structure does not depend on the metric, which is chosen per
+// query, as with KNearestMetric.
+func NewWith(pts []Point, opts ...Option) KdTree {
+	o := newOptions(opts)
+	pts = o.prepare(pts)
+	var bounds HyperRect
+	if o.bounds == nil && len(pts) > 0 {
+		bounds = HyperRect{append(Point{}, pts[0]...), append(Point{}, pts[0]...)}
+		for _, p := range pts[1:] {
+			bounds.extend(HyperRect{p, p})
+		}
+	}
+	return o.build(newNodes(pts, o.data, 0), bounds)
+}
+
+// newOptions returns the defaults as changed by opts.
+func newOptions(opts []Option) options {
+	o := options{lazy: -1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// prepare returns pts copied or snapped as configured.
+func (o *options) prepare(pts []Point) []Point {
+	if o.copy {
+		c := make([]Point, len(pts))
+		for i, p := range pts {
+			c[i] = append(Point{}, p...)
+		}
+		pts = c
+	}
+	if o.snap > 0 {
+		c := make([]Point, len(pts))
+		for i, p := range pts {
+			c[i] = Snap(p, o.snap)
+		}
+		pts = c
+	}
+	return pts
+}
+
+// build links prepared nodes, numbered from zero, into a tree as
+// configured.  bounds is the bounding box of the nodes, used unless
+// bounds were given with WithBounds.
+func (o *options) build(nodes []*kdNode, bounds HyperRect) KdTree {
+	if o.bounds != nil {
+		bounds = *o.bounds
+		if o.snap > 0 {
+			bounds = bounds.Copy()
+			for _, n := range nodes {
+				bounds.extend(HyperRect{n.domElt, n.domElt})
+			}
+		}
+	}
+	next := len(nodes)
+	if o.snap > 0 && o.merge {
+		nodes = mergeEqual(nodes)
+	}
+	var n *kdNode
+	if o.morton {
+		n = nkMorton(nodes, bounds)
+	} else if o.parallel > 1 && o.lazy < 0 {
+		n = nk2Par(nodes, 0, o.parallel, o.split)
+	} else {
+		n = nk2(nodes, 0, o.lazy, o.split)
+	}
+	t := KdTree{n: n, Bounds: bounds, FixedBounds: o.fixed, Split: o.split,
+		Brute: UseBrute(len(nodes), len(bounds.Min)), next: next}
+	if o.tighten {
+		t.Tighten()
+	}
+	if o.attrs != nil {
+		t.IndexAttrs(o.attrs...)
+	}
+	if o.label != nil {
+		t.IndexLabels(o.label)
+	}
+	return t
+}
+
+// mergeEqual returns nodes without those equal in coordinates to an
+// earlier node.
+func mergeEqual(nodes []*kdNode) []*kdNode {
+	seen := make(map[string]bool, len(nodes))
+	var key []byte
+	m := nodes[:0]
+	for _, n := range nodes {
+		key = key[:0]
+		for _, c := range n.domElt {
+			key = binary.LittleEndian.AppendUint64(key, math.Float64bits(c))
+		}
+		if !seen[string(key)] {
+			seen[string(key)] = true
+			m = append(m, n)
+		}
+	}
+	return m
+}
+
+// nk2Par is nk2 with the two subtrees of each node built concurrently
+// until workers goroutines are in use.  Small subtrees are built serially.
+func nk2Par(exset []*kdNode, split, workers int, rule SplitRule) *kdNode {
+	if workers < 2 || len(exset) < 4096 {
+		return nk2(exset, split, -1, rule)
+	}
+	// the top node is chosen by nk2 with the subtrees deferred, and
+	// then the two subtrees are built in its place.
+	kd := nk2(exset, split, 1, rule)
+	var wg sync.WaitGroup
+	for _, c := range []**kdNode{&kd.left, &kd.right} {
+		if *c == nil {
+			continue
+		}
+		l := (*c).lazy
+		wg.Add(1)
+		go func(c **kdNode, w int) {
+			defer wg.Done()
+			*c = nk2Par(l.nodes, l.split, w, rule)
+		}(c, workers/2)
+	}
+	wg.Wait()
+	return kd
+}
@@ -0,0 +1,79 @@
+package kdtree
+
+import "testing"
+
+func TestNewWith(t *testing.T) {
+	pts := randomPts(3, 20000)
+	for _, opts := range [][]Option{
+		nil,
+		{WithParallelism(8), WithTighten()},
+		{WithLazy(3), WithCopy()},
+		{WithSplitRule(SplitWidest), WithLazy(2)},
+		{WithSplitRule(SplitWidest), WithParallelism(4)},
+		{WithBounds(HyperRect{Point{0, 0, 0}, Point{1, 1, 1}}), WithFixedBounds()},
+	} {
+		kd := NewWith(append([]Point{}, pts...), opts...)
+		kd.Brute = false
+		checkNearest(t, kd, len(pts))
+		checkSizes(t, kd.n)
+	}
+	kd := NewWith(pts[:3], WithData([]interface{}{"a", "b", "c"}))
+	if !kd.Bounds.Contains(pts[2]) {
+		t.Error("computed bounds", kd.Bounds, "do not contain", pts[2])
+	}
+	if n := kd.KNearestNeighbors(pts[1], 1); n[0].Data != "b" {
+		t.Error("got", n[0])
+	}
+	kd = NewWith(pts[:1], WithFixedBounds())
+	if kd.Insert(Point{2, 2, 2}) == nil {
+		t.Error("expected BoundsError")
+	}
+}
+
+func TestWithSnap(t *testing.T) {
+	pts := []Point{{.1001, .2999}, {.0999, .3001}, {.5, .5}, {-.0001, 0}, {0, 0}}
+	orig := pts[0][0]
+	kd := NewWith(pts, WithSnap(.01, false),
+		WithBounds(HyperRect{Point{0, 0}, Point{1, 1}}))
+	if pts[0][0] != orig {
+		t.Fatal("caller's point modified")
+	}
+	if kd.Count(Point{.1, .3}) != 2 || kd.n.size != 5 {
+		t.Fatal("snapped points not found")
+	}
+	kd = NewWith(pts, WithSnap(.01, true))
+	if kd.Count(Point{.1, .3}) != 1 || kd.Count(Point{0, 0}) != 1 ||
+		kd.n.size != 3 {
+		t.Fatal("points not merged")
+	}
+	n := kd.KNearestNeighbors(Snap(Point{.1, .3}, .01), 1)
+	if n[0].Index != 0 || n[0].Sqd != 0 {
+		t.Error("got", n[0])
+	}
+	if i := kd.KNearestNeighbors(Point{.5, .5}, 1)[0].Index; i != 2 {
+		t.Error("index", i)
+	}
+}
+
+func TestWithSplitRule(t *testing.T) {
+	// points stretched along the last axis
+	pts := randomPts(3, 4000)
+	for _, p := range pts {
+		p[2] *= 100
+	}
+	kd := NewWith(append([]Point{}, pts...), WithSplitRule(SplitWidest))
+	kd.Brute = false
+	if kd.n.split != 2 {
+		t.Fatal("root split on", kd.n.split, "not the widest dimension")
+	}
+	for _, p := range randomPts(3, 1000) {
+		kd.Insert(p)
+		pts = append(pts, p)
+	}
+	kd.Rebalance()
+	if kd.n.split != 2 {
+		t.Error("rebuilt root split on", kd.n.split)
+	}
+	checkNearest(t, kd, len(pts))
+	checkSizes(t, kd.n)
+}
@@ -0,0 +1,67 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"slices"
+)
+
+// Outliers returns the statistical outliers of t, in the manner of the
+// filter of the Point Cloud Library.  For each point the mean distance to
+// its k nearest neighbors is computed, and points whose mean exceeds the
+// mean over all points by more than alpha standard deviations are
+// outliers.  The result is in index order, with Sqd the square of the
+// mean distance of each point.
+func (t KdTree) Outliers(k int, alpha float64) Neighbors {
+	var all Neighbors
+	s := t.NewSearcher()
+	walk(t.n, func(kd *kdNode) {
+		if !t.sees(kd) {
+			return
+		}
+		s.search(kd.domElt, k+1)
+		sum, c := 0., 0
+		for _, nb := range s.h.e {
+			if nb.Index != kd.index && c < k {
+				sum += math.Sqrt(nb.Sqd)
+				c++
+			}
+		}
+		if c > 0 {
+			m := sum / float64(c)
+			all = append(all, kd.neighbor(m*m))
+		}
+	})
+	if len(all) == 0 {
+		return nil
+	}
+	mean, sq := 0., 0.
+	for _, nb := range all {
+		m := math.Sqrt(nb.Sqd)
+		mean += m
+		sq += m * m
+	}
+	mean /= float64(len(all))
+	sd := math.Sqrt(math.Max(0, sq/float64(len(all))-mean*mean))
+	limit := mean + alpha*sd
+	var r Neighbors
+	for _, nb := range all {
+		if math.Sqrt(nb.Sqd) > limit {
+			r = append(r, nb)
+		}
+	}
+	slices.SortFunc(r, func(a, b Neighbor) int { return a.Index - b.Index })
+	return r
+}
+
+// RemoveOutliers removes the outliers of t, as found by Outliers, with
+// Remove, and returns the number removed.
+func (t *KdTree) RemoveOutliers(k int, alpha float64) int {
+	r := t.Outliers(k, alpha)
+	for _, nb := range r {
+		t.Remove(nb.Point)
+	}
+	return len(r)
+}
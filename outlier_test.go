@@ -0,0 +1,35 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOutliers(t *testing.T) {
+	// a dense cluster and a few far strays
+	pts := randomPts(3, 2000)
+	for _, p := range pts {
+		for i := range p {
+			p[i] *= .1
+		}
+	}
+	strays := []Point{{.9, .9, .9}, {.8, .1, .5}, {.1, .9, .2}}
+	pts = append(pts, strays...)
+	kd := NewWith(append([]Point{}, pts...))
+	out := kd.Outliers(8, 3)
+	if len(out) != len(strays) {
+		t.Fatal("found", len(out), "outliers")
+	}
+	for i, nb := range out {
+		if nb.Index != 2000+i || math.Sqrt(nb.Sqd) < .5 {
+			t.Fatal("outlier", nb)
+		}
+	}
+	kd.MaxDead = 1
+	if n := kd.RemoveOutliers(8, 3); n != len(strays) {
+		t.Fatal("removed", n)
+	}
+	if _, sqd, _ := kd.Nearest(strays[0]); sqd < .5 {
+		t.Fatal("stray not removed")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KNearestParallel finds the k nearest neighbors of p, as KNearest, but
+// searches subtrees concurrently in up to workers goroutines.  If workers
+// is <= 0, runtime.GOMAXPROCS(0) goroutines are used.
+//
+// The tree is cut at a depth that gives several subtrees per worker.
+// Subtrees are handed out nearest first and each worker searches with
+// its own Searcher.  Workers share a pruning bound, the least of their
+// k-th best distances, so that each benefits from the others' progress.
+// This only pays off for large trees where a single query takes a long
+// time.  Results are ordered as for KNearest.
+func (t KdTree) KNearestParallel(p Point, k, workers int) (nn []Point,
+	sqd []float64, nv int) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if t.Brute || workers == 1 || k <= 0 {
+		return t.KNearest(p, k)
+	}
+	if t.observed() {
+		defer t.record(t.begin("KNearestParallel"), time.Now(), &nv)
+	}
+	// cut the tree into tasks.  nodes above the cut are checked here.
+	type task struct {
+		kd *kdNode
+		hr HyperRect
+		rd float64
+	}
+	var tasks []task
+	// points above the cut go through a Searcher of t for its
+	// restrictions, such as a view's filter.
+	top := t.NewSearcher()
+	top.h.Reset(k)
+	cut := 0
+	for 1<<uint(cut) < 4*workers {
+		cut++
+	}
+	var split func(*kdNode, HyperRect, int)
+	split = func(kd *kdNode, hr HyperRect, depth int) {
+		if kd == nil {
+			return
+		}
+		kd.force()
+		if depth == cut {
+			off := make([]float64, len(p))
+			tasks = append(tasks, task{kd, hr, initOff(off, p, hr)})
+			return
+		}
+		nv++
+		if !kd.deleted {
+			top.push(kd.neighbor(kd.domElt.Sqd(p)))
+		}
+		s := kd.split
+		leftHr, rightHr := hr.Copy(), hr.Copy()
+		leftHr.Max[s] = kd.domElt[s]
+		rightHr.Min[s] = kd.domElt[s]
+		split(kd.left, leftHr, depth+1)
+		split(kd.right, rightHr, depth+1)
+	}
+	split(t.n, t.Bounds, 0)
+	h := &top.h
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].rd < tasks[j].rd })
+
+	var shared atomic.Uint64
+	shared.Store(math.Float64bits(h.Worst()))
+	var visited atomic.Int64
+	ch := make(chan task)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tk := range ch {
+				sub := t
+				sub.n, sub.Bounds = tk.kd, tk.hr
+				s := sub.NewSearcher()
+				s.shared = &shared
+				s.h.Reset(k)
+				visited.Add(int64(s.knn(p)))
+				mu.Lock()
+				for _, e := range s.h.e {
+					h.Push(e)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, tk := range tasks {
+		if tk.rd <= math.Float64frombits(shared.Load()) {
+			ch <- tk
+		}
+	}
+	close(ch)
+	wg.Wait()
+	nv += int(visited.Load())
+	if !t.Unsorted {
+		slices.SortFunc(h.e, compareNeighbors)
+	}
+	for _, e := range h.e {
+		nn = append(nn, e.Point)
+		sqd = append(sqd, e.Sqd)
+	}
+	return
+}
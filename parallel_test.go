@@ -0,0 +1,52 @@
+package kdtree
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestKNearestParallel(t *testing.T) {
+	pts := randomPts(3, 5000)
+	kd := New(pts, HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	for i := 0; i < 20; i++ {
+		p := randomPt(3)
+		_, want, _ := kd.KNearest(p, 7)
+		_, got, _ := kd.KNearestParallel(p, 7, 4)
+		sort.Float64s(want)
+		sort.Float64s(got)
+		if len(got) != len(want) {
+			t.Fatal("Expected", len(want), "results, found", len(got))
+		}
+		for j := range want {
+			if got[j] != want[j] {
+				t.Fatal("Expected distances^2", want, "found", got)
+			}
+		}
+	}
+}
+
+func TestKNearestParallelWhere(t *testing.T) {
+	pts := randomPts(3, 5000)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = float64(i % 5)
+	}
+	kd := NewWith(pts, WithData(data), WithAttrs(func(d interface{}) float64 {
+		return d.(float64)
+	}))
+	kd.Brute = false
+	view := kd.Where(AttrRange{0, 2, 2})
+	for i := 0; i < 20; i++ {
+		p := randomPt(3)
+		_, want, _ := view.KNearest(p, 7)
+		_, got, _ := view.KNearestParallel(p, 7, 4)
+		if len(got) != len(want) {
+			t.Fatal("Expected", len(want), "results, found", len(got))
+		}
+		for j := range want {
+			if got[j] != want[j] {
+				t.Fatal("Expected distances^2", want, "found", got)
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// Projection maps geographic positions to a planar frame in meters and
+// back, so that positions over a limited extent can be indexed and
+// queried as ordinary two dimensional points.
+//
+// Planar distances only approximate distances on the Earth.  The error
+// depends on the projection and the extent; see Azimuthal and UTM.  For
+// exact distances at any extent, use GeoTree.
+type Projection interface {
+	Project(LatLon) Point
+	Unproject(Point) LatLon
+}
+
+// ProjectAll returns the projections of pts.
+func ProjectAll(p Projection, pts []LatLon) []Point {
+	r := make([]Point, len(pts))
+	for i, ll := range pts {
+		r[i] = p.Project(ll)
+	}
+	return r
+}
+
+// UnprojectAll returns the positions of the points of n, as found by a
+// query of a tree of projected points.
+func UnprojectAll(p Projection, n Neighbors) []LatLon {
+	r := make([]LatLon, len(n))
+	for i, nb := range n {
+		r[i] = p.Unproject(nb.Point)
+	}
+	return r
+}
+
+// Azimuthal is the azimuthal equidistant projection on a sphere of radius
+// EarthRadius, centered on Center, with x east and y north.
+//
+// Distances and directions from Center are exact.  Other distances are
+// stretched by a relative error of order (d/EarthRadius)^2 at distance d
+// from Center, about one part in a million at 6 km and one in ten
+// thousand at 60 km, which suits city scale extents around the center.
+type Azimuthal struct {
+	Center LatLon
+}
+
+// Project returns the projection of ll.
+func (a Azimuthal) Project(ll LatLon) Point {
+	lat0, lat := a.Center.Lat*math.Pi/180, ll.Lat*math.Pi/180
+	dlon := (ll.Lon - a.Center.Lon) * math.Pi / 180
+	c := Haversine(a.Center, ll) / EarthRadius
+	k := 1.
+	if s := math.Sin(c); s > 0 {
+		k = c / s
+	}
+	return Point{
+		EarthRadius * k * math.Cos(lat) * math.Sin(dlon),
+		EarthRadius * k * (math.Cos(lat0)*math.Sin(lat) -
+			math.Sin(lat0)*math.Cos(lat)*math.Cos(dlon)),
+	}
+}
+
+// Unproject returns the position projected to p.
+func (a Azimuthal) Unproject(p Point) LatLon {
+	x, y := p[0], p[1]
+	rho := math.Hypot(x, y)
+	if rho == 0 {
+		return a.Center
+	}
+	lat0 := a.Center.Lat * math.Pi / 180
+	c := rho / EarthRadius
+	sc, cc := math.Sincos(c)
+	lat := math.Asin(cc*math.Sin(lat0) + y*sc*math.Cos(lat0)/rho)
+	dlon := math.Atan2(x*sc, rho*math.Cos(lat0)*cc-y*math.Sin(lat0)*sc)
+	return LatLon{lat * 180 / math.Pi, normLon(a.Center.Lon + dlon*180/math.Pi)}
+}
+
+// UTM is the Universal Transverse Mercator projection of a zone, on the
+// WGS 84 ellipsoid, with x the easting and y the northing in meters.
+// Northings in the southern hemisphere, South true, are offset by ten
+// thousand kilometers as usual.
+//
+// Within its zone, UTM scales distances by between 0.9996 and about
+// 1.0004, so planar distances are within 0.04% of geodesic ones.  The
+// error grows quickly outside the zone.
+type UTM struct {
+	Zone  int
+	South bool
+}
+
+// UTMZone returns the UTM zone of the longitude of ll, and whether ll is
+// in the southern hemisphere.  The exceptional zones around Norway and
+// Svalbard are not applied.
+func UTMZone(ll LatLon) UTM {
+	z := int(math.Floor((normLon(ll.Lon)+180)/6)) + 1
+	if z > 60 {
+		z = 60
+	}
+	return UTM{z, ll.Lat < 0}
+}
+
+// WGS 84 ellipsoid and UTM constants.
+const (
+	wgs84A = 6378137
+	wgs84F = 1 / 298.257223563
+	utmK0  = 0.9996
+	utmE0  = 500000
+	utmN0S = 10000000
+)
+
+// Coefficients of the series of Krüger for the transverse Mercator, to
+// third order in the third flattening n, good to well under a millimeter.
+var utmN, utmA, utmAlpha, utmBeta, utmDelta = func() (n, a float64,
+	alpha, beta, delta [3]float64) {
+	n = wgs84F / (2 - wgs84F)
+	n2, n3 := n*n, n*n*n
+	a = wgs84A / (1 + n) * (1 + n2/4 + n2*n2/64)
+	alpha = [3]float64{n/2 - 2*n2/3 + 5*n3/16, 13*n2/48 - 3*n3/5, 61 * n3 / 240}
+	beta = [3]float64{n/2 - 2*n2/3 + 37*n3/96, n2/48 + n3/15, 17 * n3 / 480}
+	delta = [3]float64{2*n - 2*n2/3 - 2*n3, 7*n2/3 - 8*n3/5, 56 * n3 / 15}
+	return
+}()
+
+func (u UTM) centralMeridian() float64 {
+	return float64(6*u.Zone-183) * math.Pi / 180
+}
+
+// Project returns the projection of ll.
+func (u UTM) Project(ll LatLon) Point {
+	lat := ll.Lat * math.Pi / 180
+	dlon := normLon(ll.Lon-float64(6*u.Zone-183)) * math.Pi / 180
+	e := 2 * math.Sqrt(utmN) / (1 + utmN)
+	t := math.Sinh(math.Atanh(math.Sin(lat)) - e*math.Atanh(e*math.Sin(lat)))
+	xi := math.Atan2(t, math.Cos(dlon))
+	eta := math.Atanh(math.Sin(dlon) / math.Sqrt(1+t*t))
+	x, y := eta, xi
+	for j, a := range utmAlpha {
+		k := 2 * float64(j+1)
+		x += a * math.Cos(k*xi) * math.Sinh(k*eta)
+		y += a * math.Sin(k*xi) * math.Cosh(k*eta)
+	}
+	x = utmE0 + utmK0*utmA*x
+	y = utmK0 * utmA * y
+	if u.South {
+		y += utmN0S
+	}
+	return Point{x, y}
+}
+
+// Unproject returns the position projected to p.
+func (u UTM) Unproject(p Point) LatLon {
+	y := p[1]
+	if u.South {
+		y -= utmN0S
+	}
+	xi := y / (utmK0 * utmA)
+	eta := (p[0] - utmE0) / (utmK0 * utmA)
+	xi1, eta1 := xi, eta
+	for j, b := range utmBeta {
+		k := 2 * float64(j+1)
+		xi1 -= b * math.Sin(k*xi) * math.Cosh(k*eta)
+		eta1 -= b * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	chi := math.Asin(math.Sin(xi1) / math.Cosh(eta1))
+	lat := chi
+	for j, d := range utmDelta {
+		lat += d * math.Sin(2*float64(j+1)*chi)
+	}
+	lon := u.centralMeridian() + math.Atan2(math.Sinh(eta1), math.Cos(xi1))
+	return LatLon{lat * 180 / math.Pi, normLon(lon * 180 / math.Pi)}
+}
+
+// normLon returns lon reduced to [-180, 180).
+func normLon(lon float64) float64 {
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	return lon - 180
+}
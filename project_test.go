@@ -0,0 +1,54 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUTM(t *testing.T) {
+	// the Eiffel Tower, zone 31 north
+	ll := LatLon{48.85826, 2.29451}
+	u := UTMZone(ll)
+	if u != (UTM{31, false}) {
+		t.Fatal("zone", u)
+	}
+	// on the central meridian the northing is the scaled meridian arc,
+	// 4984944.378 m from the equator to 45 degrees.
+	p := u.Project(LatLon{45, 3})
+	if math.Abs(p[0]-500000) > 1e-6 || math.Abs(p[1]-.9996*4984944.378) > .01 {
+		t.Fatal("projected", p)
+	}
+	for _, ll := range []LatLon{{-33.8568, 151.2153}, {64.1, -21.9}, {0, 3}} {
+		u := UTMZone(ll)
+		// 1e-8 degrees is about a millimeter
+		r := u.Unproject(u.Project(ll))
+		if math.Abs(r.Lat-ll.Lat) > 1e-8 || math.Abs(r.Lon-ll.Lon) > 1e-8 {
+			t.Fatal(ll, "round trip", r)
+		}
+	}
+}
+
+func TestAzimuthal(t *testing.T) {
+	a := Azimuthal{LatLon{51.5, -0.12}}
+	pts := []LatLon{{51.52, -0.1}, {51.45, -0.2}, {51.6, 0.05}, a.Center}
+	for _, ll := range pts {
+		p := a.Project(ll)
+		if d := math.Hypot(p[0], p[1]); math.Abs(d-Haversine(a.Center, ll)) > 1e-6 {
+			t.Fatal(ll, "distance from center", d)
+		}
+		r := a.Unproject(p)
+		if math.Abs(r.Lat-ll.Lat) > 1e-9 || math.Abs(r.Lon-ll.Lon) > 1e-9 {
+			t.Fatal(ll, "round trip", r)
+		}
+	}
+	// distances away from the center are nearly preserved at city scale
+	ps := ProjectAll(a, pts)
+	if d, h := math.Sqrt(ps[1].Sqd(ps[2])), Haversine(pts[1], pts[2]); math.Abs(d-h) > 1e-4*h {
+		t.Fatal("projected distance", d, "expected", h)
+	}
+	kd := NewWith(ps)
+	n := kd.KNearestNeighbors(a.Project(LatLon{51.51, -0.11}), 1)
+	if got := UnprojectAll(a, n); math.Abs(got[0].Lat-51.52) > 1e-9 {
+		t.Fatal("nearest", got)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ToProto encodes t as a protocol buffer Tree message as defined in
+// kdtree.proto, for exchange with programs not written in Go.
+//
+// Points, their indexes, tombstones, the structure of the tree, and the
+// settings encoded by MarshalBinary are encoded.  As with MarshalBinary,
+// data associated with points is not.
+func (t KdTree) ToProto() []byte {
+	var b []byte
+	if t.Bounds.Min != nil {
+		var hr []byte
+		hr = protoDoubles(hr, 1, t.Bounds.Min)
+		hr = protoDoubles(hr, 2, t.Bounds.Max)
+		b = protoBytes(b, 1, hr)
+	}
+	b = protoBool(b, 2, t.Brute)
+	b = protoDouble(b, 3, t.Alpha)
+	b = protoDouble(b, 4, t.MaxDead)
+	b = protoBool(b, 5, t.FixedBounds)
+	b = protoVarint(b, 6, uint64(t.next))
+	b = protoVarint(b, 9, uint64(t.MaxRebuild))
+	b = protoVarint(b, 10, uint64(t.Split))
+	var node []byte
+	var enc func(*kdNode)
+	enc = func(kd *kdNode) {
+		kd.force()
+		node = protoDoubles(node[:0], 1, kd.domElt)
+		node = protoVarint(node, 2, uint64(kd.index))
+		node = protoVarint(node, 3, uint64(kd.split))
+		node = protoBool(node, 4, kd.deleted)
+		node = protoBool(node, 5, kd.left != nil)
+		node = protoBool(node, 6, kd.right != nil)
+		b = protoBytes(b, 7, node)
+		if kd.left != nil {
+			enc(kd.left)
+		}
+		if kd.right != nil {
+			enc(kd.right)
+		}
+	}
+	if t.n != nil {
+		enc(t.n)
+	}
+	return protoBool(b, 8, t.tight())
+}
+
+// FromProto decodes a Tree message as written by ToProto.
+func FromProto(b []byte) (KdTree, error) {
+	var t KdTree
+	var nodes []*kdNode
+	var links []bool // has_left, has_right of each node
+	tight := false
+	err := protoFields(b, func(f int, v uint64, m []byte) error {
+		switch f {
+		case 1:
+			return protoFields(m, func(f int, v uint64, m []byte) error {
+				switch f {
+				case 1:
+					t.Bounds.Min = protoAppendDoubles(t.Bounds.Min, v, m)
+				case 2:
+					t.Bounds.Max = protoAppendDoubles(t.Bounds.Max, v, m)
+				}
+				return nil
+			})
+		case 2:
+			t.Brute = v != 0
+		case 3:
+			t.Alpha = math.Float64frombits(v)
+		case 4:
+			t.MaxDead = math.Float64frombits(v)
+		case 5:
+			t.FixedBounds = v != 0
+		case 6:
+			if v > math.MaxInt32 {
+				return errProto
+			}
+			t.next = int(v)
+		case 7:
+			kd := &kdNode{}
+			var l, r bool
+			err := protoFields(m, func(f int, v uint64, m []byte) error {
+				switch f {
+				case 1:
+					kd.domElt = protoAppendDoubles(kd.domElt, v, m)
+				case 2:
+					kd.index = int(v)
+				case 3:
+					kd.split = int(v)
+				case 4:
+					kd.deleted = v != 0
+				case 5:
+					l = v != 0
+				case 6:
+					r = v != 0
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, kd)
+			links = append(links, l, r)
+		case 8:
+			tight = v != 0
+		case 9:
+			if v > math.MaxInt32 {
+				return errProto
+			}
+			t.MaxRebuild = int(v)
+		case 10:
+			t.Split = SplitRule(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return KdTree{}, err
+	}
+	// relink the preorder sequence.
+	i := 0
+	var link func() (*kdNode, error)
+	link = func() (*kdNode, error) {
+		if i == len(nodes) {
+			return nil, errProto
+		}
+		kd := nodes[i]
+		l, r := links[2*i], links[2*i+1]
+		i++
+		if kd.split < 0 || kd.split >= len(kd.domElt) {
+			return nil, errProto
+		}
+		kd.size = 1
+		var err error
+		if l {
+			if kd.left, err = link(); err != nil {
+				return nil, err
+			}
+			kd.size += kd.left.size
+		}
+		if r {
+			if kd.right, err = link(); err != nil {
+				return nil, err
+			}
+			kd.size += kd.right.size
+		}
+		return kd, nil
+	}
+	if len(nodes) > 0 {
+		if t.n, err = link(); err != nil {
+			return KdTree{}, err
+		}
+		if i != len(nodes) {
+			return KdTree{}, errProto
+		}
+	}
+	var ok bool
+	if t.dead, ok = t.checkDecoded(); !ok {
+		return KdTree{}, errProto
+	}
+	if tight {
+		t.Tighten()
+	}
+	return t, nil
+}
+
+var errProto = errors.New("kdtree: invalid protocol buffer encoding")
+
+// protocol buffer wire types
+const (
+	wireVarint = 0
+	wire64     = 1
+	wireBytes  = 2
+	wire32     = 5
+)
+
+func protoKey(b []byte, f, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(f<<3|wire))
+}
+
+func protoVarint(b []byte, f int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.AppendUvarint(protoKey(b, f, wireVarint), v)
+}
+
+func protoBool(b []byte, f int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return protoVarint(b, f, 1)
+}
+
+func protoDouble(b []byte, f int, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.LittleEndian.AppendUint64(protoKey(b, f, wire64),
+		math.Float64bits(v))
+}
+
+func protoBytes(b []byte, f int, m []byte) []byte {
+	b = binary.AppendUvarint(protoKey(b, f, wireBytes), uint64(len(m)))
+	return append(b, m...)
+}
+
+// protoDoubles appends p as a packed repeated double.
+func protoDoubles(b []byte, f int, p []float64) []byte {
+	if len(p) == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(protoKey(b, f, wireBytes), uint64(8*len(p)))
+	for _, c := range p {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(c))
+	}
+	return b
+}
+
+// protoAppendDoubles appends to p the value of a repeated double field,
+// packed in m or unpacked in v.
+func protoAppendDoubles(p []float64, v uint64, m []byte) []float64 {
+	if m == nil {
+		return append(p, math.Float64frombits(v))
+	}
+	for ; len(m) >= 8; m = m[8:] {
+		p = append(p, math.Float64frombits(binary.LittleEndian.Uint64(m)))
+	}
+	return p
+}
+
+// protoFields calls f for each field of message b.  Varint and fixed
+// width values are passed as v, length delimited values as m.  Unknown
+// fields are passed too and may be ignored.
+func protoFields(b []byte, f func(field int, v uint64, m []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 || key>>3 == 0 {
+			return errProto
+		}
+		b = b[n:]
+		var v uint64
+		var m []byte
+		switch key & 7 {
+		case wireVarint:
+			if v, n = binary.Uvarint(b); n <= 0 {
+				return errProto
+			}
+			b = b[n:]
+		case wire64:
+			if len(b) < 8 {
+				return errProto
+			}
+			v = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wire32:
+			if len(b) < 4 {
+				return errProto
+			}
+			v = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return errProto
+			}
+			m = b[n : n+int(l) : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			return errProto
+		}
+		if err := f(int(key>>3), v, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package kdtree
+
+import "testing"
+
+func TestProto(t *testing.T) {
+	pts := randomPts(2, 300)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	kd.MaxRebuild = 25
+	kd.Split = SplitWidest
+	kd.Tighten()
+	for _, p := range pts[:10] {
+		kd.Remove(p)
+	}
+	got, err := FromProto(kd.ToProto())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Brute || !got.tight() || got.dead != 10 || got.next != len(pts) ||
+		got.MaxRebuild != 25 || got.Split != SplitWidest {
+		t.Fatal("settings not restored")
+	}
+	checkSizes(t, got.n)
+	for _, p := range randomPts(2, 50) {
+		want := kd.KNearestNeighbors(p, 3)
+		n := got.KNearestNeighbors(p, 3)
+		for i := range want {
+			if n[i].Index != want[i].Index || n[i].Sqd != want[i].Sqd {
+				t.Fatal("got", n[i], "expected", want[i])
+			}
+		}
+	}
+	if _, err := FromProto([]byte{0x3a, 5, 1}); err == nil {
+		t.Error("expected error for truncated message")
+	}
+	if e, err := FromProto(nil); err != nil || e.n != nil {
+		t.Error("empty message", err)
+	}
+}
+
+func TestProtoInvalid(t *testing.T) {
+	valid := func() KdTree {
+		return New([]Point{{0, 0}, {1, 1}, {.5, .2}},
+			HyperRect{Point{0, 0}, Point{1, 1}})
+	}
+	kd := valid()
+	kd.n.left.index = kd.next
+	if _, err := FromProto(kd.ToProto()); err == nil {
+		t.Error("no error for index out of range")
+	}
+	kd = valid()
+	kd.n.right.domElt = Point{1, 1, 1}
+	if _, err := FromProto(kd.ToProto()); err == nil {
+		t.Error("no error for point of wrong dimension")
+	}
+	kd = valid()
+	kd.Split = 5
+	if _, err := FromProto(kd.ToProto()); err == nil {
+		t.Error("no error for unknown split rule")
+	}
+}
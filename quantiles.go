@@ -0,0 +1,133 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"slices"
+	"sort"
+)
+
+// IndexQuantiles stores with each subtree of at least 4 * size points a
+// sample of size of its points, spread evenly through the subtree, so
+// that Quantiles can stand a subtree's sample in for all its points.
+//
+// Samples are taken afresh when subtrees are rebuilt, and a sampled point
+// moved by Update in place moves in the samples.  Points otherwise added
+// or deleted change the weight of the samples of the subtrees holding
+// them but not the samples, so after many updates IndexQuantiles should
+// be called again.  The cost is memory for about a reference per
+// two points.  IndexQuantiles(0) drops the samples.
+func (t *KdTree) IndexQuantiles(size int) {
+	if size < 0 {
+		size = 0
+	}
+	t.sample = size
+	if t.n != nil {
+		indexSamples(t.n, size, nil)
+	}
+}
+
+// indexSamples sets samples of m points for the subtree at kd and its
+// subtrees, appending the live points of the subtree in order to pts and
+// returning the result.  A subtree is contiguous in that order, so each
+// samples its own run of pts.
+func indexSamples(kd *kdNode, m int, pts []Point) []Point {
+	kd.force()
+	lo := len(pts)
+	if kd.left != nil {
+		pts = indexSamples(kd.left, m, pts)
+	}
+	if !kd.deleted {
+		pts = append(pts, kd.domElt)
+	}
+	if kd.right != nil {
+		pts = indexSamples(kd.right, m, pts)
+	}
+	kd.sample = nil
+	if n := len(pts) - lo; m > 0 && n >= 4*m {
+		kd.sample = make([]Point, m)
+		for i := range kd.sample {
+			kd.sample[i] = pts[lo+(2*i+1)*n/(2*m)]
+		}
+	}
+	return pts
+}
+
+// moveSample replaces p, the point of a node moved to to, in the sample
+// at kd if it was sampled.  Samples are shared with snapshots, so the
+// sample is copied rather than changed in place.
+func (kd *kdNode) moveSample(p, to Point) {
+	for i, s := range kd.sample {
+		if len(s) > 0 && &s[0] == &p[0] {
+			kd.sample = slices.Clone(kd.sample)
+			kd.sample[i] = to
+			return
+		}
+	}
+}
+
+// Quantiles returns approximate quantiles of the coordinates of the
+// points of t within hr, one Point for each of q, in [0, 1].  Each
+// coordinate of result j is the q[j] quantile of that coordinate over the
+// points, so Quantiles(hr, .5) returns the median in each dimension.  The
+// result is nil if there are no points within hr.
+//
+// With samples indexed by IndexQuantiles, a subtree whose box lies inside
+// hr contributes its sample, each sampled point weighted by the size of
+// the subtree over the size of the sample, instead of all its points.
+// The work is then about that of finding the subtrees cut by the edges of
+// hr, with errors in rank of a fraction about 1/size.  Without samples,
+// in a view restricted by Where or InCells, or while t has tombstones
+// left by Remove, points are taken one by one and the quantiles are
+// exact.
+func (t KdTree) Quantiles(hr HyperRect, q ...float64) []Point {
+	var pts []Point
+	var w []float64
+	t.boxSearch(hr, func(kd *kdNode, _ HyperRect) bool {
+		if kd.sample == nil || t.dead > 0 {
+			return false
+		}
+		sw := float64(kd.size) / float64(len(kd.sample))
+		for _, p := range kd.sample {
+			pts = append(pts, p)
+			w = append(w, sw)
+		}
+		return true
+	}, func(kd *kdNode) {
+		pts = append(pts, kd.domElt)
+		w = append(w, 1)
+	})
+	if len(pts) == 0 {
+		return nil
+	}
+	total := 0.
+	for _, x := range w {
+		total += x
+	}
+	r := make([]Point, len(q))
+	for j := range r {
+		r[j] = make(Point, len(hr.Min))
+	}
+	o := make([]int, len(pts))
+	for d := range hr.Min {
+		for i := range o {
+			o[i] = i
+		}
+		sort.Slice(o, func(a, b int) bool { return pts[o[a]][d] < pts[o[b]][d] })
+		for j, f := range q {
+			// the least coordinate at which the cumulative weight
+			// reaches the fraction f of the total
+			lim := f * total
+			sum := 0.
+			for _, i := range o {
+				sum += w[i]
+				r[j][d] = pts[i][d]
+				if sum >= lim {
+					break
+				}
+			}
+		}
+	}
+	return r
+}
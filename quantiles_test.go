@@ -0,0 +1,104 @@
+package kdtree
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestQuantiles(t *testing.T) {
+	pts := randomPts(2, 20000)
+	kd := NewWith(append([]Point{}, pts...))
+	kd.Brute = false
+	hr := HyperRect{Point{.1, .3}, Point{.7, .9}}
+	var in [2][]float64
+	for _, p := range pts {
+		if hr.Contains(p) {
+			in[0] = append(in[0], p[0])
+			in[1] = append(in[1], p[1])
+		}
+	}
+	sort.Float64s(in[0])
+	sort.Float64s(in[1])
+	q := []float64{0, .25, .5, 1}
+	// without samples, exact
+	got := kd.Quantiles(hr, q...)
+	for j, f := range q {
+		for d := range got[j] {
+			i := int(math.Ceil(f*float64(len(in[d])))) - 1
+			if i < 0 {
+				i = 0
+			}
+			if got[j][d] != in[d][i] {
+				t.Fatal("quantile", f, "dim", d, got[j][d], "expected", in[d][i])
+			}
+		}
+	}
+	// with samples, within a few percent in rank
+	kd.IndexQuantiles(32)
+	got = kd.Quantiles(hr, q...)
+	for j, f := range q {
+		for d := range got[j] {
+			rank := float64(sort.SearchFloat64s(in[d], got[j][d])) /
+				float64(len(in[d]))
+			if math.Abs(rank-f) > .03 {
+				t.Fatal("quantile", f, "dim", d, got[j][d], "has rank", rank)
+			}
+		}
+	}
+	// still valid after inserts rebuild some subtrees
+	for i := 0; i < 2000; i++ {
+		kd.Insert(randomPt(2))
+	}
+	if c := kd.Quantiles(hr, .5); math.Abs(c[0][0]-.4) > .05 ||
+		math.Abs(c[0][1]-.6) > .05 {
+		t.Fatal("median after inserts", c[0])
+	}
+	if kd.Quantiles(HyperRect{Point{2, 2}, Point{3, 3}}, .5) != nil {
+		t.Error("quantiles of empty box")
+	}
+}
+
+func TestQuantilesUpdate(t *testing.T) {
+	pts := randomPts(2, 5000)
+	kd := NewWith(pts)
+	kd.Brute = false
+	kd.IndexQuantiles(8)
+	moved := 0
+	for i, p := range pts {
+		q := Point{p[0] + 1e-7, p[1]}
+		if kd.Update(p, q) {
+			pts[i] = q
+			moved++
+		}
+	}
+	if moved != len(pts) {
+		t.Fatal("moved", moved)
+	}
+	// every sample is of live points of its subtree
+	walk(kd.n, func(n *kdNode) {
+		for _, s := range n.sample {
+			found := false
+			kd.yieldAll(n, func(n *kdNode) bool {
+				found = equal(n.domElt, s)
+				return !found
+			})
+			if !found {
+				t.Fatal("sampled", s, "not in subtree")
+			}
+		}
+	})
+	for _, p := range pts[:2000] {
+		kd.Remove(p)
+	}
+	pts = pts[2000:]
+	// with tombstones, exact
+	var in []float64
+	for _, p := range pts {
+		in = append(in, p[0])
+	}
+	sort.Float64s(in)
+	if got := kd.Quantiles(kd.Bounds, .5); got[0][0] != in[len(in)/2-1] {
+		t.Fatal("median", got[0][0], "expected", in[len(in)/2-1])
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"iter"
+	"sync/atomic"
+	"time"
+)
+
+// InRange returns the points of t within hr, in no particular order.
+func (t KdTree) InRange(hr HyperRect) []Point {
+	return collect(t.InRangeSeq(hr))
+}
+
+// InRangeSeq returns an iterator over the points of t within hr.
+//
+// Points are found as the iteration proceeds, so a large result need not
+// be held in memory, and breaking out of the loop ends the search.
+func (t KdTree) InRangeSeq(hr HyperRect) iter.Seq[Point] {
+	return func(yield func(Point) bool) { t.InRangeFunc(hr, yield) }
+}
+
+// InRangeFunc calls f for each point of t within hr, stopping early if f
+// returns false.
+func (t KdTree) InRangeFunc(hr HyperRect, f func(Point) bool) {
+	t.rangeSearch(hr, hr.Contains, func(kd *kdNode) bool {
+		return f(kd.domElt)
+	})
+}
+
+// InRadius returns the points of t within distance r of p, in no
+// particular order.
+func (t KdTree) InRadius(p Point, r float64) []Point {
+	return collect(t.InRadiusSeq(p, r))
+}
+
+// InRadiusSeq returns an iterator over the points of t within distance r
+// of p, as InRangeSeq.
+func (t KdTree) InRadiusSeq(p Point, r float64) iter.Seq[Point] {
+	return func(yield func(Point) bool) { t.InRadiusFunc(p, r, yield) }
+}
+
+// InRadiusFunc calls f for each point of t within distance r of p,
+// stopping early if f returns false.
+func (t KdTree) InRadiusFunc(p Point, r float64, f func(Point) bool) {
+	r2 := r * r
+	box := HyperRect{make(Point, len(p)), make(Point, len(p))}
+	for i, c := range p {
+		box.Min[i] = c - r
+		box.Max[i] = c + r
+	}
+	within := func(q Point) bool { return q.Sqd(p) <= r2 }
+	t.rangeSearch(box, within, func(kd *kdNode) bool {
+		return f(kd.domElt)
+	})
+}
+
+// InTolerance returns the points of t differing from p by no more than
+// tol[d] in each dimension d, in no particular order.  This is InRange of
+// the box centered on p with half-widths tol.
+func (t KdTree) InTolerance(p Point, tol []float64) []Point {
+	return t.InRange(toleranceBox(p, tol))
+}
+
+// InToleranceNeighbors returns the points of t within tolerance tol of p,
+// as InTolerance, as Neighbors sorted by distance from p.
+func (t KdTree) InToleranceNeighbors(p Point, tol []float64) (n Neighbors) {
+	box := toleranceBox(p, tol)
+	t.rangeSearch(box, box.Contains, func(kd *kdNode) bool {
+		n = append(n, kd.neighbor(kd.domElt.Sqd(p)))
+		return true
+	})
+	n.Sort()
+	return
+}
+
+func toleranceBox(p Point, tol []float64) HyperRect {
+	box := HyperRect{make(Point, len(p)), make(Point, len(p))}
+	for i, c := range p {
+		box.Min[i] = c - tol[i]
+		box.Max[i] = c + tol[i]
+	}
+	return box
+}
+
+func collect(seq iter.Seq[Point]) (pts []Point) {
+	for p := range seq {
+		pts = append(pts, p)
+	}
+	return
+}
+
+// rangeSearch calls yield for each point of t within box for which match
+// also returns true, stopping early if yield returns false.  Subtrees
+// are pruned by split planes and tight bounds, unless t.Brute is set.  It
+// returns false if stopped early.
+func (t KdTree) rangeSearch(box HyperRect, match func(Point) bool,
+	yield func(*kdNode) bool) bool {
+	return t.rangeSearchWhere(box, match, nil, yield)
+}
+
+// rangeSearchWhere is rangeSearch also pruning subtrees by attribute
+// ranges that cannot satisfy where.
+func (t KdTree) rangeSearchWhere(box HyperRect, match func(Point) bool,
+	where []AttrRange, yield func(*kdNode) bool) bool {
+	nv := 0
+	if t.observed() {
+		defer t.record(t.begin("Range"), time.Now(), &nv)
+	}
+	prune := !t.Brute
+	if t.filter != nil {
+		where = append(t.filter[:len(t.filter):len(t.filter)], where...)
+	}
+	stack := []*kdNode{t.n}
+	for len(stack) > 0 {
+		kd := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if kd == nil {
+			continue
+		}
+		kd.force()
+		nv++
+		if t.TrackQueries {
+			atomic.AddUint32(&kd.hits, 1)
+		}
+		if prune && kd.bounds != nil && !kd.bounds.Intersects(box) ||
+			where != nil && !kd.mayMatch(where) ||
+			t.cells != nil && !t.cellsMayMatch(kd) {
+			continue
+		}
+		if !kd.deleted && match(kd.domElt) &&
+			(t.filter == nil || t.matches(kd.rangeElt, t.filter)) &&
+			(t.cells == nil || t.inCells(kd.rangeElt)) && !yield(kd) {
+			return false
+		}
+		s := kd.split
+		if !prune || box.Max[s] > kd.domElt[s] {
+			stack = append(stack, kd.right)
+		}
+		if !prune || box.Min[s] <= kd.domElt[s] {
+			stack = append(stack, kd.left)
+		}
+	}
+	return true
+}
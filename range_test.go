@@ -0,0 +1,83 @@
+package kdtree
+
+import "testing"
+
+func TestInRange(t *testing.T) {
+	pts := randomPts(3, 1000)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	kd.Tighten()
+	for _, brute := range []bool{false, true} {
+		kd.Brute = brute
+		for i := 0; i < 20; i++ {
+			min := randomPt(3)
+			hr := HyperRect{min, Point{min[0] + .3, min[1] + .3, min[2] + .3}}
+			want := 0
+			for _, p := range pts {
+				if hr.Contains(p) {
+					want++
+				}
+			}
+			got := kd.InRange(hr)
+			if len(got) != want {
+				t.Fatal("Expected", want, "points in range, found", len(got))
+			}
+			for _, p := range got {
+				if !hr.Contains(p) {
+					t.Fatal(p, "not in", hr)
+				}
+			}
+		}
+	}
+}
+
+func TestInRadius(t *testing.T) {
+	pts := randomPts(3, 1000)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	p := randomPt(3)
+	want := 0
+	for _, q := range pts {
+		if q.Sqd(p) <= .04 {
+			want++
+		}
+	}
+	if got := kd.InRadius(p, .2); len(got) != want {
+		t.Fatal("Expected", want, "points in radius, found", len(got))
+	}
+	// early exit
+	n := 0
+	for range kd.InRadiusSeq(p, 1) {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Error("Expected to stop after 3 points, found", n)
+	}
+}
+
+func TestInTolerance(t *testing.T) {
+	pts := randomPts(3, 2000)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	kd.Brute = false
+	p := randomPt(3)
+	tol := []float64{.05, .2, .1}
+	want := 0
+	for _, q := range pts {
+		if abs(q[0]-p[0]) <= tol[0] && abs(q[1]-p[1]) <= tol[1] &&
+			abs(q[2]-p[2]) <= tol[2] {
+			want++
+		}
+	}
+	if got := len(kd.InTolerance(p, tol)); got != want {
+		t.Error("InTolerance found", got, "points, expected", want)
+	}
+	n := kd.InToleranceNeighbors(p, tol)
+	if len(n) != want {
+		t.Fatal("InToleranceNeighbors found", len(n), "points, expected", want)
+	}
+	for i := 1; i < len(n); i++ {
+		if n[i].Sqd < n[i-1].Sqd {
+			t.Fatal("not sorted")
+		}
+	}
+}
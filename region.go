@@ -0,0 +1,344 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "iter"
+
+// results of classifying a box against a region
+const (
+	outside = iota
+	partial
+	inside
+)
+
+// regionSearch calls yield for each point of t for which match returns
+// true, stopping early if yield returns false.  classify places a box
+// outside, partly inside, or inside the region match tests.  Subtrees
+// whose boxes are outside are skipped, and those inside are reported
+// without testing their points.  Boxes are cells or tight bounds, as for
+// Visit.  If t.Brute is set, every point is tested.  Only points in the
+// view of t are yielded.
+func (t KdTree) regionSearch(classify func(HyperRect) int,
+	match func(Point) bool, yield func(*kdNode) bool) {
+	if t.n == nil {
+		return
+	}
+	if t.Brute {
+		t.yieldAll(t.n, func(kd *kdNode) bool {
+			return !match(kd.domElt) || yield(kd)
+		})
+		return
+	}
+	c := newCellStack(t, 0.)
+	for {
+		kd, _, ok := c.pop()
+		if !ok {
+			return
+		}
+		if t.excludes(kd) {
+			continue
+		}
+		box := c.cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		switch classify(box) {
+		case outside:
+			continue
+		case inside:
+			if !t.yieldAll(kd, yield) {
+				return
+			}
+			continue
+		}
+		if t.sees(kd) && match(kd.domElt) && !yield(kd) {
+			return
+		}
+		c.push(kd, kd.right, 0)
+		c.push(kd, kd.left, 0)
+	}
+}
+
+// yieldAll calls yield for the nodes of the subtree at kd whose points
+// are in the view of t, in order, returning false if yield does.
+// Subtrees the view excludes are skipped.  An explicit stack bounds the
+// goroutine stack used on deep trees, as for walk.
+func (t KdTree) yieldAll(kd *kdNode, yield func(*kdNode) bool) bool {
+	var stack []*kdNode
+	for {
+		for ; kd != nil; kd = kd.left {
+			kd.force()
+			if t.excludes(kd) {
+				break
+			}
+			stack = append(stack, kd)
+		}
+		if len(stack) == 0 {
+			return true
+		}
+		kd = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if t.sees(kd) && !yield(kd) {
+			return false
+		}
+		kd = kd.right
+	}
+}
+
+// cellStack replaces recursion in depth first traversals that need the
+// cell of each node, t.Bounds cut by the pivots of its ancestors, so that
+// deep trees cannot overflow the goroutine stack.  A single cell is
+// changed in place as nodes are popped and restored as their subtrees are
+// finished, as a recursion would save and restore it.  K is the type of
+// the value carried to each node, as a recursion would pass it.
+type cellStack[K any] struct {
+	cell  HyperRect
+	stack []cellFrame[K]
+}
+
+// cellFrame is a node to visit, with the bound of the cell to set for
+// it, or if kd is nil, a bound to restore.  key is a value for the
+// node's visit, such as a lower bound computed by its parent.
+type cellFrame[K any] struct {
+	kd  *kdNode
+	s   int // dimension of the bound, or -1 for none
+	max bool
+	v   float64
+	key K
+}
+
+// newCellStack returns a cellStack holding the root of t, with key.
+func newCellStack[K any](t KdTree, key K) *cellStack[K] {
+	c := &cellStack[K]{cell: t.Bounds.Copy()}
+	if t.n != nil {
+		c.stack = append(c.stack, cellFrame[K]{kd: t.n, s: -1, key: key})
+	}
+	return c
+}
+
+// push adds child, a child of kd or nil, to visit after those pushed
+// later, with key.
+func (c *cellStack[K]) push(kd, child *kdNode, key K) {
+	if child == nil {
+		return
+	}
+	c.stack = append(c.stack, cellFrame[K]{kd: child, s: kd.split,
+		max: child == kd.left, v: kd.domElt[kd.split], key: key})
+}
+
+// childCell calls f with c.cell changed to the cell of child, a child of
+// kd, the node last popped, and returns its result.
+func (c *cellStack[K]) childCell(kd, child *kdNode, f func(HyperRect) float64) float64 {
+	s := kd.split
+	b := &c.cell.Min[s]
+	if child == kd.left {
+		b = &c.cell.Max[s]
+	}
+	save := *b
+	*b = kd.domElt[s]
+	r := f(c.cell)
+	*b = save
+	return r
+}
+
+// pop returns the next node to visit, forced, and its key, with c.cell
+// set to its cell.  ok is false when the traversal is done.
+func (c *cellStack[K]) pop() (kd *kdNode, key K, ok bool) {
+	for len(c.stack) > 0 {
+		f := c.stack[len(c.stack)-1]
+		c.stack = c.stack[:len(c.stack)-1]
+		if f.s >= 0 {
+			b := &c.cell.Min[f.s]
+			if f.max {
+				b = &c.cell.Max[f.s]
+			}
+			if f.kd == nil {
+				*b = f.v
+				continue
+			}
+			c.stack = append(c.stack, cellFrame[K]{s: f.s, max: f.max, v: *b})
+			*b = f.v
+		}
+		f.kd.force()
+		return f.kd, f.key, true
+	}
+	return nil, key, false
+}
+
+// HalfSpace is the set of points p on one side of a hyperplane, where
+// the dot product of N and p is >= C.
+type HalfSpace struct {
+	N Point
+	C float64
+}
+
+// Contains reports whether p lies within h.
+func (h HalfSpace) Contains(p Point) bool {
+	return dot(h.N, p) >= h.C
+}
+
+// classify places hr with respect to h by the least and greatest dot
+// products of N with the corners of hr.
+func (h HalfSpace) classify(hr HyperRect) int {
+	lo, hi := 0., 0.
+	for i, n := range h.N {
+		a, b := n*hr.Min[i], n*hr.Max[i]
+		if a > b {
+			a, b = b, a
+		}
+		lo += a
+		hi += b
+	}
+	switch {
+	case hi < h.C:
+		return outside
+	case lo >= h.C:
+		return inside
+	}
+	return partial
+}
+
+func dot(a, b Point) float64 {
+	s := 0.
+	for i, c := range a {
+		s += c * b[i]
+	}
+	return s
+}
+
+// InHalfSpace returns the points of t within h, in no particular order.
+func (t KdTree) InHalfSpace(h HalfSpace) []Point {
+	return collect(t.InHalfSpaceSeq(h))
+}
+
+// InHalfSpaceSeq returns an iterator over the points of t within h, as
+// InRangeSeq.
+func (t KdTree) InHalfSpaceSeq(h HalfSpace) iter.Seq[Point] {
+	return func(yield func(Point) bool) { t.InHalfSpaceFunc(h, yield) }
+}
+
+// InHalfSpaceFunc calls f for each point of t within h, stopping early if
+// f returns false.
+//
+// Subtrees with boxes entirely on the wrong side of the hyperplane are
+// pruned, and those entirely on the right side are reported without
+// testing their points.
+func (t KdTree) InHalfSpaceFunc(h HalfSpace, f func(Point) bool) {
+	t.regionSearch(h.classify, h.Contains, func(kd *kdNode) bool {
+		return f(kd.domElt)
+	})
+}
+
+// Polytope is a convex region, the intersection of half-spaces.
+type Polytope []HalfSpace
+
+// ConvexPolygon returns the polytope bounded by the 2D convex polygon
+// with the given vertices, in either clockwise or counterclockwise order.
+func ConvexPolygon(vertices []Point) Polytope {
+	area := 0.
+	for i, a := range vertices {
+		b := vertices[(i+1)%len(vertices)]
+		area += a[0]*b[1] - b[0]*a[1]
+	}
+	// interior is left of each edge if counterclockwise
+	sign := 1.
+	if area < 0 {
+		sign = -1
+	}
+	pt := make(Polytope, len(vertices))
+	for i, a := range vertices {
+		b := vertices[(i+1)%len(vertices)]
+		n := Point{-sign * (b[1] - a[1]), sign * (b[0] - a[0])}
+		pt[i] = HalfSpace{n, dot(n, a)}
+	}
+	return pt
+}
+
+// Contains reports whether p lies within pt.
+func (pt Polytope) Contains(p Point) bool {
+	for _, h := range pt {
+		if !h.Contains(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// classify places hr with respect to pt.  A box outside pt but not
+// entirely outside any one half-space is classed partial, so it is
+// searched rather than pruned.
+func (pt Polytope) classify(hr HyperRect) int {
+	c := inside
+	for _, h := range pt {
+		switch h.classify(hr) {
+		case outside:
+			return outside
+		case partial:
+			c = partial
+		}
+	}
+	return c
+}
+
+// InPolytope returns the points of t within pt, in no particular order.
+func (t KdTree) InPolytope(pt Polytope) []Point {
+	return collect(t.InPolytopeSeq(pt))
+}
+
+// InPolytopeSeq returns an iterator over the points of t within pt, as
+// InRangeSeq.
+func (t KdTree) InPolytopeSeq(pt Polytope) iter.Seq[Point] {
+	return func(yield func(Point) bool) { t.InPolytopeFunc(pt, yield) }
+}
+
+// InPolytopeFunc calls f for each point of t within pt, stopping early if
+// f returns false.  Subtrees are pruned and accepted as for
+// InHalfSpaceFunc.
+func (t KdTree) InPolytopeFunc(pt Polytope, f func(Point) bool) {
+	t.regionSearch(pt.classify, pt.Contains, func(kd *kdNode) bool {
+		return f(kd.domElt)
+	})
+}
+
+// InAnnulus returns the points of t at distances from p in [rMin, rMax],
+// in no particular order.
+func (t KdTree) InAnnulus(p Point, rMin, rMax float64) []Point {
+	return collect(t.InAnnulusSeq(p, rMin, rMax))
+}
+
+// InAnnulusSeq returns an iterator over the points of t at distances from
+// p in [rMin, rMax], as InRangeSeq.
+func (t KdTree) InAnnulusSeq(p Point, rMin, rMax float64) iter.Seq[Point] {
+	return func(yield func(Point) bool) { t.InAnnulusFunc(p, rMin, rMax, yield) }
+}
+
+// InAnnulusFunc calls f for each point of t at a distance from p in
+// [rMin, rMax], stopping early if f returns false.
+//
+// Subtrees with boxes entirely within rMin or beyond rMax are pruned, and
+// those entirely within the ring are reported without testing their
+// points.
+func (t KdTree) InAnnulusFunc(p Point, rMin, rMax float64, f func(Point) bool) {
+	lo, hi := rMin*rMin, rMax*rMax
+	if rMin < 0 {
+		lo = 0
+	}
+	classify := func(hr HyperRect) int {
+		near, far := hr.Sqd(p), hr.farSqd(p)
+		switch {
+		case near > hi || far < lo:
+			return outside
+		case near >= lo && far <= hi:
+			return inside
+		}
+		return partial
+	}
+	match := func(q Point) bool {
+		d := q.Sqd(p)
+		return d >= lo && d <= hi
+	}
+	t.regionSearch(classify, match, func(kd *kdNode) bool {
+		return f(kd.domElt)
+	})
+}
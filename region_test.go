@@ -0,0 +1,115 @@
+package kdtree
+
+import "testing"
+
+func TestInHalfSpace(t *testing.T) {
+	pts := randomPts(3, 2000)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	h := HalfSpace{Point{1, -2, .5}, -.3}
+	want := 0
+	for _, p := range pts {
+		if h.Contains(p) {
+			want++
+		}
+	}
+	for _, mode := range []string{"brute", "cells", "tight"} {
+		kd.Brute = mode == "brute"
+		if mode == "tight" {
+			kd.Tighten()
+		}
+		got := kd.InHalfSpace(h)
+		if len(got) != want {
+			t.Fatal(mode, "found", len(got), "points, expected", want)
+		}
+		for _, p := range got {
+			if !h.Contains(p) {
+				t.Fatal(mode, "point", p, "outside half-space")
+			}
+		}
+	}
+}
+
+func TestInPolytope(t *testing.T) {
+	pts := randomPts(2, 2000)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	kd.Tighten()
+	// a rotated square, given clockwise and counterclockwise
+	sq := []Point{{.5, .1}, {.9, .5}, {.5, .9}, {.1, .5}}
+	rev := []Point{sq[3], sq[2], sq[1], sq[0]}
+	in := func(p Point) bool {
+		return abs(p[0]-.5)+abs(p[1]-.5) <= .4
+	}
+	want := 0
+	for _, p := range pts {
+		if in(p) {
+			want++
+		}
+	}
+	for _, v := range [][]Point{sq, rev} {
+		got := kd.InPolytope(ConvexPolygon(v))
+		if len(got) != want {
+			t.Fatal("found", len(got), "points, expected", want)
+		}
+	}
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func TestInAnnulus(t *testing.T) {
+	pts := randomPts(2, 3000)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	p := Point{.4, .6}
+	want := 0
+	for _, q := range pts {
+		if d := q.Sqd(p); d >= .1*.1 && d <= .3*.3 {
+			want++
+		}
+	}
+	if got := len(kd.InAnnulus(p, .1, .3)); got != want {
+		t.Error("found", got, "points, expected", want)
+	}
+	if got, all := len(kd.InAnnulus(p, 0, 2)), len(pts); got != all {
+		t.Error("found", got, "points, expected all", all)
+	}
+}
+
+// deepTree returns a tree of n points inserted in sorted order without
+// rebalancing, a path as deep as it has points, and the points.
+func deepTree(n int) (KdTree, []Point) {
+	pts := make([]Point, n)
+	for i := range pts {
+		f := float64(i) / float64(n)
+		pts[i] = Point{f, f}
+	}
+	kd := New(nil, HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Alpha = 1
+	kd.Brute = false
+	for _, p := range pts {
+		kd.Insert(p)
+	}
+	return kd, pts
+}
+
+func TestRegionSearchDeep(t *testing.T) {
+	kd, pts := deepTree(3000)
+	if h := height(kd.n); h < len(pts)/2 {
+		t.Fatal("tree height", h)
+	}
+	h := HalfSpace{Point{1, 0}, .25}
+	want := 0
+	for _, p := range pts {
+		if h.Contains(p) {
+			want++
+		}
+	}
+	if got := len(kd.InHalfSpace(h)); got != want {
+		t.Fatal("found", got, "points, expected", want)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "sync/atomic"
+
+// hotFactor is how many times the visit rate of the whole tree, per node,
+// makes a subtree hot for Reoptimize.
+const hotFactor = 2
+
+// Reoptimize adapts t to the queries counted since t.TrackQueries was set
+// or since the last Reoptimize, and resets the counts.
+//
+// A subtree is hot if the searches visiting it, per node of the subtree,
+// are at least twice those of the whole tree.  Hot subtrees are rebuilt
+// balanced with tight bounding boxes, the finest pruning the tree offers.
+// A subtree is cold if no search visited it.  Cold subtrees drop their
+// tight bounding boxes, saving memory where it buys nothing.  Only the
+// topmost hot or cold subtrees are changed.  It returns the numbers of
+// hot and cold subtrees changed.
+//
+// Real workloads are often skewed, so a tree adapted to its queries can
+// be both faster and smaller than one uniformly tightened.  Reoptimize
+// must not run concurrently with queries.
+func (t *KdTree) Reoptimize() (hot, cold int) {
+	if t.n == nil || t.n.hits == 0 {
+		return
+	}
+	rate := float64(t.n.hits) / float64(t.n.size)
+	var adapt func(link **kdNode)
+	adapt = func(link **kdNode) {
+		kd := *link
+		if kd == nil || kd.deferred() {
+			return // lazy subtrees have not been searched
+		}
+		switch {
+		case kd.size == 1:
+			return
+		case kd.hits == 0:
+			if kd.bounds != nil {
+				walk(kd, func(n *kdNode) { n.bounds = nil })
+				cold++
+			}
+			return
+		case kd != t.n && float64(kd.hits)/float64(kd.size) >= hotFactor*rate:
+			t.rebuilt("reoptimize", kd.size)
+			*link = t.rebuild(kd, true)
+			hot++
+			return
+		}
+		adapt(&kd.left)
+		adapt(&kd.right)
+	}
+	adapt(&t.n)
+	resetHits(t.n)
+	return
+}
+
+// resetHits zeros the visit counts of the built nodes of the subtree at kd.
+func resetHits(kd *kdNode) {
+	for stack := []*kdNode{kd}; len(stack) > 0; {
+		kd := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if kd == nil || kd.deferred() {
+			continue
+		}
+		atomic.StoreUint32(&kd.hits, 0)
+		stack = append(stack, kd.left, kd.right)
+	}
+}
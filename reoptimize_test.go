@@ -0,0 +1,79 @@
+package kdtree
+
+import "testing"
+
+func TestReoptimize(t *testing.T) {
+	pts := randomPts(2, 4000)
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	kd.Tighten()
+	kd.TrackQueries = true
+	// queries only in the corner below .2, .2
+	for _, q := range randomPts(2, 200) {
+		q[0] *= .2
+		q[1] *= .2
+		kd.KNearest(q, 3)
+		kd.Nearest(q)
+	}
+	before := kd.MemoryBytes()
+	hot, cold := kd.Reoptimize()
+	if hot == 0 || cold == 0 {
+		t.Fatal("hot", hot, "cold", cold)
+	}
+	if kd.MemoryBytes() >= before {
+		t.Error("memory not reduced")
+	}
+	if kd.n.hits != 0 {
+		t.Error("hits not reset")
+	}
+	checkSizes(t, kd.n)
+	checkNearest(t, kd, len(pts))
+	// inserts keep bounds valid in a partly tightened tree
+	for _, p := range randomPts(2, 500) {
+		kd.Insert(p)
+		pts = append(pts, p)
+	}
+	checkBounds(t, kd.n)
+	checkNearest(t, kd, len(pts))
+	kd.InsertAll(randomPts(2, 300))
+	checkBounds(t, kd.n)
+	if hot, cold := (&KdTree{}).Reoptimize(); hot != 0 || cold != 0 {
+		t.Error("empty tree")
+	}
+}
+
+// checkBounds checks that stored bounding boxes contain their subtrees.
+func checkBounds(t *testing.T, kd *kdNode) {
+	walk(kd, func(n *kdNode) {
+		if n.bounds == nil {
+			return
+		}
+		walk(n, func(c *kdNode) {
+			if !n.bounds.Contains(c.domElt) {
+				t.Fatal(c.domElt, "outside bounds", *n.bounds)
+			}
+		})
+	})
+}
+
+func TestReoptimizeLazy(t *testing.T) {
+	pts := randomPts(2, 5000)
+	kd := NewLazy(pts, HyperRect{Point{0, 0}, Point{1, 1}}, 1)
+	kd.Brute = false
+	kd.TrackQueries = true
+	for _, q := range randomPts(2, 200) {
+		q[0] *= .2
+		q[1] *= .2
+		kd.KNearest(q, 3)
+	}
+	if hot, _ := kd.Reoptimize(); hot == 0 {
+		t.Fatal("no hot subtrees in built lazy subtrees")
+	}
+	walk(kd.n, func(n *kdNode) {
+		if n.hits != 0 {
+			t.Fatal("hits not reset in built lazy subtree")
+		}
+	})
+	checkSizes(t, kd.n)
+	checkNearest(t, kd, len(pts))
+}
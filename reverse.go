@@ -0,0 +1,72 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// reverseFilters is the number of points near q used to prune subtrees
+// in ReverseNearest.
+const reverseFilters = 16
+
+// ReverseNearest returns the points of t that would have q as a nearest
+// neighbor if q were added to t, the influence set of q, sorted by
+// distance from q.  A point with another point as near as q is still
+// reported.
+//
+// Candidates are filtered with points x near q: every point on x's side
+// of the bisector between x and q is nearer x than q, so subtrees with
+// boxes entirely on that side are pruned.  Each remaining point is checked
+// with a nearest neighbor search bounded by its distance to q.
+func (t KdTree) ReverseNearest(q Point) (n Neighbors) {
+	var filters []HalfSpace
+	var fx []Neighbor
+	for x := range t.NearestSeq(q) {
+		nv := make(Point, len(q))
+		for i := range q {
+			nv[i] = x.Point[i] - q[i]
+		}
+		c := (dot(x.Point, x.Point) - dot(q, q)) / 2
+		filters = append(filters, HalfSpace{nv, math.Nextafter(c, math.Inf(1))})
+		fx = append(fx, x)
+		if len(fx) == reverseFilters {
+			break
+		}
+	}
+	classify := func(hr HyperRect) int {
+		for _, h := range filters {
+			if h.classify(hr) == inside {
+				return outside
+			}
+		}
+		return partial
+	}
+	s := t.NewSearcher()
+	s.limited = true
+	check := func(x Neighbor) {
+		s.limit = x.Sqd
+		s.search(x.Point, 2)
+		for _, e := range s.h.e {
+			if e.Index != x.Index && e.Sqd < x.Sqd {
+				return
+			}
+		}
+		n = append(n, x)
+	}
+	// filter points lie in subtrees pruned by their own half-spaces, so
+	// they are checked separately.
+	isFilter := make(map[int]bool, len(fx))
+	for _, x := range fx {
+		check(x)
+		isFilter[x.Index] = true
+	}
+	t.regionSearch(classify, func(Point) bool { return true },
+		func(kd *kdNode) bool {
+			if !isFilter[kd.index] {
+				check(kd.neighbor(kd.domElt.Sqd(q)))
+			}
+			return true
+		})
+	n.Sort()
+	return
+}
@@ -0,0 +1,41 @@
+package kdtree
+
+import "testing"
+
+func TestReverseNearest(t *testing.T) {
+	for _, dim := range []int{2, 3} {
+		pts := randomPts(dim, 1000)
+		max := make(Point, dim)
+		for i := range max {
+			max[i] = 1
+		}
+		kd := New(append([]Point{}, pts...), HyperRect{make(Point, dim), max})
+		kd.Brute = false
+		for i := 0; i < 20; i++ {
+			q := randomPt(dim)
+			want := map[int]bool{}
+			for j, s := range pts {
+				d := s.Sqd(q)
+				rnn := true
+				for k, o := range pts {
+					if k != j && s.Sqd(o) < d {
+						rnn = false
+						break
+					}
+				}
+				if rnn {
+					want[j] = true
+				}
+			}
+			got := kd.ReverseNearest(q)
+			if len(got) != len(want) {
+				t.Fatal("found", len(got), "reverse neighbors, expected", len(want))
+			}
+			for _, n := range got {
+				if !want[n.Index] {
+					t.Fatal("point", n.Index, "is not a reverse neighbor")
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// SearchTrace records the steps of a k nearest neighbor search so that
+// the search can be shown or animated.  It marshals to JSON with
+// encoding/json.
+type SearchTrace struct {
+	Target Point       `json:"target"`
+	K      int         `json:"k"`
+	Steps  []TraceStep `json:"steps"`
+	Result Neighbors   `json:"result"`
+}
+
+// TraceStep is a step of a traced search.
+//
+// Op is one of:
+//   - "enter": the search enters the subtree with cell Cell, at distance
+//     sqrt(Sqd) from the target.
+//   - "prune": the subtree with cell Cell is skipped, its distance being
+//     more than the k-th best so far.
+//   - "point": the point of a node, at Index, is checked.
+//   - "best": the point checked is among the k best so far.
+//
+// Bound is the squared distance of the k-th best so far, nil until k
+// points have been found.  Depth is the depth of the node, the root
+// being 0.
+type TraceStep struct {
+	Op    string     `json:"op"`
+	Depth int        `json:"depth"`
+	Cell  *HyperRect `json:"cell,omitempty"`
+	Point Point      `json:"point,omitempty"`
+	Index int        `json:"index"`
+	Sqd   float64    `json:"sqd"`
+	Bound *float64   `json:"bound"`
+}
+
+// TraceKNearest finds the k nearest neighbors of p as KNearest, recording
+// the search.
+//
+// The traced search is a plain recursive search over cells, nearer
+// subtree first, pruning subtrees whose cells, or tight bounds if t was
+// tightened, are further than the k-th best point.  This is the strategy
+// of KNearest, without its optimizations, so it is meant for teaching and
+// debugging rather than production queries.
+func (t KdTree) TraceKNearest(p Point, k int) *SearchTrace {
+	tr := &SearchTrace{Target: p, K: k, Steps: []TraceStep{}}
+	h := NewKHeap(k)
+	if t.n == nil || k <= 0 {
+		tr.Result = Neighbors{}
+		return tr
+	}
+	bound := func() *float64 {
+		if w := h.Worst(); !math.IsInf(w, 1) {
+			return &w
+		}
+		return nil
+	}
+	cell := t.Bounds.Copy()
+	var search func(kd *kdNode, depth int)
+	search = func(kd *kdNode, depth int) {
+		kd.force()
+		if t.excludes(kd) {
+			return
+		}
+		box := cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		c := box.Copy()
+		d := box.Sqd(p)
+		if d > h.Worst() {
+			tr.Steps = append(tr.Steps,
+				TraceStep{Op: "prune", Depth: depth, Cell: &c, Sqd: d, Bound: bound()})
+			return
+		}
+		tr.Steps = append(tr.Steps,
+			TraceStep{Op: "enter", Depth: depth, Cell: &c, Sqd: d, Bound: bound()})
+		s := kd.split
+		pivot := kd.domElt[s]
+		near, far := kd.left, kd.right
+		nearMax := true // near is the left, bounded above by pivot
+		if p[s] > pivot {
+			near, far = far, near
+			nearMax = false
+		}
+		sub := func(c *kdNode, max bool) {
+			if c == nil {
+				return
+			}
+			b := &cell.Min
+			if max {
+				b = &cell.Max
+			}
+			save := (*b)[s]
+			(*b)[s] = pivot
+			search(c, depth+1)
+			(*b)[s] = save
+		}
+		sub(near, nearMax)
+		if t.sees(kd) {
+			d := kd.domElt.Sqd(p)
+			step := TraceStep{Op: "point", Depth: depth, Point: kd.domElt,
+				Index: kd.index, Sqd: d}
+			if d < h.Worst() {
+				h.Push(kd.neighbor(d))
+				step.Op = "best"
+			}
+			step.Bound = bound()
+			tr.Steps = append(tr.Steps, step)
+		}
+		sub(far, !nearMax)
+	}
+	search(t.n, 0)
+	tr.Result = h.Results()
+	return tr
+}
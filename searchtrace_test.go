@@ -0,0 +1,49 @@
+package kdtree
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTraceKNearest(t *testing.T) {
+	pts := randomPts(2, 500)
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	for _, p := range randomPts(2, 20) {
+		tr := kd.TraceKNearest(p, 3)
+		want := kd.KNearestNeighbors(p, 3)
+		for i := range want {
+			if tr.Result[i].Index != want[i].Index {
+				t.Fatal("got", tr.Result, "expected", want)
+			}
+		}
+		enter, prune, best := 0, 0, 0
+		for _, s := range tr.Steps {
+			switch s.Op {
+			case "enter":
+				enter++
+				if !s.Cell.Contains(pts[0]) && s.Depth == 0 {
+					t.Fatal("root cell", s.Cell)
+				}
+			case "prune":
+				prune++
+				if s.Bound == nil || s.Sqd <= *s.Bound {
+					t.Fatal("pruned within bound", s)
+				}
+			case "best":
+				best++
+			}
+		}
+		if enter == 0 || enter >= len(pts) || prune == 0 || best < 3 {
+			t.Fatal("enter", enter, "prune", prune, "best", best)
+		}
+		if _, err := json.Marshal(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var empty KdTree
+	if b, err := json.Marshal(empty.TraceKNearest(Point{0, 0}, 1)); err != nil ||
+		string(b) != `{"target":[0,0],"k":1,"steps":[],"result":[]}` {
+		t.Error(string(b), err)
+	}
+}
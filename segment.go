@@ -0,0 +1,82 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// Segment is a line segment from A to B.
+type Segment struct {
+	A, B Point
+}
+
+// Closest returns the point of s closest to p.
+func (s Segment) Closest(p Point) Point {
+	var dot, len2 float64
+	for i, a := range s.A {
+		ab := s.B[i] - a
+		dot += (p[i] - a) * ab
+		len2 += ab * ab
+	}
+	t := 0.
+	if len2 > 0 {
+		t = dot / len2
+	}
+	if t <= 0 {
+		return s.A
+	}
+	if t >= 1 {
+		return s.B
+	}
+	c := make(Point, len(s.A))
+	for i, a := range s.A {
+		c[i] = a + t*(s.B[i]-a)
+	}
+	return c
+}
+
+// Sqd returns the square of the euclidean distance from p to s.
+func (s Segment) Sqd(p Point) float64 {
+	return s.Closest(p).Sqd(p)
+}
+
+// Bounds returns the bounding box of s.
+func (s Segment) Bounds() HyperRect {
+	hr := HyperRect{append(Point{}, s.A...), append(Point{}, s.A...)}
+	hr.extend(HyperRect{s.B, s.B})
+	return hr
+}
+
+// SegmentIndex indexes line segments for nearest segment queries.
+//
+// Segments are stored by their bounding boxes in a BoxTree.  A search
+// prunes by distance to boxes and refines with the exact distance to the
+// segments it cannot prune.
+type SegmentIndex struct {
+	segs []Segment
+	bt   *BoxTree
+}
+
+// NewSegmentIndex builds a SegmentIndex of segs.
+func NewSegmentIndex(segs []Segment) *SegmentIndex {
+	boxes := make([]HyperRect, len(segs))
+	for i, s := range segs {
+		boxes[i] = s.Bounds()
+	}
+	return &SegmentIndex{segs, NewBoxTree(boxes)}
+}
+
+// NearestSegment finds the segment nearest p, as for snapping a position
+// to a road network.
+//
+// return values:
+//   - the index of the segment in the slice passed to NewSegmentIndex,
+//     or -1 if there are no segments.
+//   - the point of the segment closest to p.
+//   - square of the distance to that point.
+func (x *SegmentIndex) NearestSegment(p Point) (i int, closest Point,
+	sqd float64) {
+	i, sqd = x.bt.nearest(p, func(i int) float64 { return x.segs[i].Sqd(p) })
+	if i >= 0 {
+		closest = x.segs[i].Closest(p)
+	}
+	return
+}
@@ -0,0 +1,31 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNearestSegment(t *testing.T) {
+	segs := make([]Segment, 300)
+	for i := range segs {
+		a := randomPt(2)
+		b := randomPt(2)
+		segs[i] = Segment{a, Point{a[0] + (b[0]-.5)*.1, a[1] + (b[1]-.5)*.1}}
+	}
+	x := NewSegmentIndex(segs)
+	for i := 0; i < 100; i++ {
+		p := randomPt(2)
+		want := math.Inf(1)
+		for _, s := range segs {
+			want = math.Min(want, s.Sqd(p))
+		}
+		j, c, got := x.NearestSegment(p)
+		if got != want || c.Sqd(p) != got || segs[j].Sqd(p) != got {
+			t.Fatal("Expected distance^2", want, "found", got)
+		}
+	}
+	s := Segment{Point{0, 0}, Point{2, 0}}
+	if c := s.Closest(Point{1, 1}); c[0] != 1 || c[1] != 0 {
+		t.Error("Expected closest (1, 0), found", c)
+	}
+}
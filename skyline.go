@@ -0,0 +1,107 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// Skyline returns the points of t not dominated by any other, with
+// respect to dimensions dims, or all dimensions if dims is nil.  A point
+// dominates another if it is at least as large in every dimension of dims
+// and larger in at least one.  For a skyline of minima, negate the
+// coordinates.
+//
+// The search is branch and bound skyline, BBS.  Subtrees and points are
+// taken in order of decreasing sum over dims of the upper corner of their
+// boxes, so each point is taken after any point dominating it, and
+// subtrees whose upper corners are dominated by a skyline point are
+// pruned.  Points are returned in the order found.
+func (t KdTree) Skyline(dims []int) (sky []Point) {
+	if t.n == nil {
+		return
+	}
+	if dims == nil {
+		dims = make([]int, len(t.Bounds.Min))
+		for i := range dims {
+			dims[i] = i
+		}
+	}
+	sum := func(p Point) float64 {
+		s := 0.
+		for _, d := range dims {
+			s += p[d]
+		}
+		return s
+	}
+	dominated := func(p Point) bool {
+		for _, s := range sky {
+			if dominates(s, p, dims) {
+				return true
+			}
+		}
+		return false
+	}
+	var q incQueue
+	push := func(kd *kdNode, cell HyperRect) {
+		kd.force()
+		if t.excludes(kd) {
+			return
+		}
+		box := cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		if !dominated(box.Max) {
+			q.push(incEntry{kd: kd, cell: cell, key: -sum(box.Max)})
+		}
+	}
+	push(t.n, t.Bounds.Copy())
+	for len(q) > 0 {
+		e := q.pop()
+		kd := e.kd
+		if e.point {
+			if !dominated(kd.domElt) {
+				sky = append(sky, kd.domElt)
+			}
+			continue
+		}
+		// skyline points found since the push may now dominate it.
+		box := e.cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		if dominated(box.Max) {
+			continue
+		}
+		if t.sees(kd) {
+			q.push(incEntry{kd: kd, point: true, key: -sum(kd.domElt)})
+		}
+		s := kd.split
+		if kd.right != nil {
+			c := e.cell
+			if kd.left != nil {
+				c = e.cell.Copy()
+			}
+			c.Min[s] = kd.domElt[s]
+			push(kd.right, c)
+		}
+		if kd.left != nil {
+			c := e.cell
+			c.Max[s] = kd.domElt[s]
+			push(kd.left, c)
+		}
+	}
+	return
+}
+
+// dominates reports whether p dominates q in dims.
+func dominates(p, q Point, dims []int) bool {
+	gt := false
+	for _, d := range dims {
+		switch {
+		case p[d] < q[d]:
+			return false
+		case p[d] > q[d]:
+			gt = true
+		}
+	}
+	return gt
+}
@@ -0,0 +1,44 @@
+package kdtree
+
+import "testing"
+
+func TestSkyline(t *testing.T) {
+	pts := randomPts(3, 2000)
+	pts = append(pts, pts[0]) // a duplicate
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	for _, dims := range [][]int{nil, {0, 2}} {
+		d := dims
+		if d == nil {
+			d = []int{0, 1, 2}
+		}
+		want := 0
+		for _, p := range pts {
+			dom := false
+			for _, q := range pts {
+				if dominates(q, p, d) {
+					dom = true
+					break
+				}
+			}
+			if !dom {
+				want++
+			}
+		}
+		for _, tight := range []bool{false, true} {
+			if tight {
+				kd.Tighten()
+			}
+			sky := kd.Skyline(dims)
+			if len(sky) != want {
+				t.Fatal(dims, "skyline has", len(sky), "points, expected", want)
+			}
+			for _, p := range sky {
+				for _, q := range pts {
+					if dominates(q, p, d) {
+						t.Fatal(p, "dominated by", q)
+					}
+				}
+			}
+		}
+	}
+}
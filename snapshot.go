@@ -0,0 +1,68 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"iter"
+	"slices"
+)
+
+// Snapshot returns a copy of t that is unaffected by later changes to t.
+//
+// Nodes are copied but points and data are shared, so a snapshot costs
+// about a node per point.  Iterating or querying the snapshot, for
+// example from another goroutine, sees the points of t at the time of the
+// call and never a partly applied Insert, Delete, InsertAll or Compact.
+// Snapshot itself must not run concurrently with changes to t.
+func (t KdTree) Snapshot() KdTree {
+	s := t
+	s.Bounds = t.Bounds.Copy()
+	s.n = clone(t.n)
+	return s
+}
+
+// clone returns a copy of the subtree at kd.
+func clone(kd *kdNode) *kdNode {
+	var root *kdNode
+	type job struct {
+		kd   *kdNode
+		link **kdNode
+	}
+	for stack := []job{{kd, &root}}; len(stack) > 0; {
+		j := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		kd := j.kd
+		if kd == nil {
+			continue
+		}
+		kd.force()
+		c := &kdNode{elt: kd.elt, split: kd.split, size: kd.size}
+		if kd.bounds != nil {
+			b := kd.bounds.Copy()
+			c.bounds = &b
+		}
+		// slices.Clone keeps a non-nil empty label set non-nil, so that
+		// label pruning still applies.
+		c.attrs = slices.Clone(kd.attrs)
+		c.labels = slices.Clone(kd.labels)
+		if kd.cellIDs != nil {
+			r := *kd.cellIDs
+			c.cellIDs = &r
+		}
+		c.sample = kd.sample // never modified in place
+		*j.link = c
+		stack = append(stack, job{kd.left, &c.left}, job{kd.right, &c.right})
+	}
+	return root
+}
+
+// All returns an iterator over the points of t, in no particular order.
+//
+// The iteration is not isolated from changes made to t during the loop;
+// range over t.Snapshot().All() for that.
+func (t KdTree) All() iter.Seq[Point] {
+	return func(yield func(Point) bool) {
+		t.yieldAll(t.n, func(kd *kdNode) bool { return yield(kd.domElt) })
+	}
+}
@@ -0,0 +1,55 @@
+package kdtree
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	pts := randomPts(2, 1000)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Tighten()
+	snap := kd.Snapshot()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, p := range pts[:500] {
+			kd.Delete(p)
+		}
+		kd.InsertAll(randomPts(2, 500))
+	}()
+	for i := 0; i < 5; i++ {
+		n := 0
+		for range snap.All() {
+			n++
+		}
+		if n != len(pts) {
+			t.Fatal("snapshot has", n, "points, expected", len(pts))
+		}
+	}
+	wg.Wait()
+	snap.Brute = false
+	checkNearest(t, snap, len(pts))
+	n := 0
+	for range kd.All() {
+		n++
+	}
+	if n != len(pts) {
+		t.Fatal("tree has", n, "points, expected", len(pts))
+	}
+}
+
+func TestSnapshotLabels(t *testing.T) {
+	// unlabeled points leave empty, non-nil label sets, which prune
+	kd := NewWith(randomPts(2, 500), WithLabels(func(interface{}) int { return -1 }))
+	snap := kd.Snapshot()
+	walk(snap.n, func(n *kdNode) {
+		if (n.left != nil || n.right != nil) && n.labels == nil {
+			t.Fatal("label set lost in snapshot")
+		}
+	})
+	if n := snap.KNearestWithLabels(randomPt(2), 3, 1); len(n) != 0 {
+		t.Error("got", n)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// NearestDistanceHist returns a histogram of the distances from each point
+// of t to the nearest other point, the distribution G of spatial
+// statistics.  edges are increasing bin edges.  counts[i] is the number
+// of distances d with edges[i] <= d < edges[i+1].  Distances outside the
+// edges are not counted.  A point stored more than once has distance 0.
+func (t KdTree) NearestDistanceHist(edges []float64) (counts []int) {
+	if len(edges) < 2 {
+		return nil
+	}
+	counts = make([]int, len(edges)-1)
+	s := t.NewSearcher()
+	walk(t.n, func(kd *kdNode) {
+		if !t.sees(kd) {
+			return
+		}
+		s.search(kd.domElt, 2)
+		for _, n := range s.h.e {
+			if n.Index == kd.index {
+				continue
+			}
+			d := math.Sqrt(n.Sqd)
+			// the last edge <= d
+			i := sort.Search(len(edges), func(i int) bool { return edges[i] > d }) - 1
+			if i >= 0 && i < len(counts) {
+				counts[i]++
+			}
+			return
+		}
+	})
+	return
+}
+
+// RipleyK returns Ripley's K function of the points of t at each of radii,
+// which must be increasing.  It is zero for trees of fewer than two
+// points.
+//
+// K(r) is the study volume divided by n(n-1), times the number of ordered
+// pairs of distinct points within distance r.  For points placed
+// uniformly at random K(r) is the volume of a ball of radius r.  Larger
+// values indicate clustering, smaller values regularity.  The study
+// volume is that of t.Bounds.  No edge correction is applied, so values
+// near the edges of the bounds are biased low.
+func (t KdTree) RipleyK(radii []float64) []float64 {
+	k := make([]float64, len(radii))
+	n := t.live()
+	if len(radii) == 0 || n < 2 {
+		return k
+	}
+	t.pairs(radii[len(radii)-1], func(d float64) {
+		// count the pair at every radius >= d
+		k[sort.SearchFloat64s(radii, d)]++
+	})
+	c := t.Bounds.volume() / float64(n*(n-1))
+	sum := 0.
+	for i := range k {
+		sum += k[i]
+		k[i] = sum * c
+	}
+	return k
+}
+
+// PairCorrelation returns the pair correlation function g of the points
+// of t at each of radii.
+//
+// g(r) is the density of pairs at distance r relative to that expected of
+// points placed uniformly at random, so it is near 1 for random points.
+// It is estimated from the pairs at distances within width/2 of r,
+// divided by the volume of that spherical shell.  The study volume and
+// edge effects are as for RipleyK.
+func (t KdTree) PairCorrelation(radii []float64, width float64) []float64 {
+	g := make([]float64, len(radii))
+	n := t.live()
+	if len(radii) == 0 || n < 2 {
+		return g
+	}
+	h := width / 2
+	t.pairs(slices.Max(radii)+h, func(d float64) {
+		for i, r := range radii {
+			if math.Abs(d-r) <= h {
+				g[i]++
+			}
+		}
+	})
+	dim := len(t.Bounds.Min)
+	c := t.Bounds.volume() / float64(n*(n-1))
+	for i, r := range radii {
+		shell := ballVolume(dim, r+h) - ballVolume(dim, math.Max(r-h, 0))
+		g[i] *= c / shell
+	}
+	return g
+}
+
+// pairs calls f with the distance of each ordered pair of distinct points
+// of t within distance r of each other.
+func (t KdTree) pairs(r float64, f func(d float64)) {
+	r2 := r * r
+	walk(t.n, func(kd *kdNode) {
+		if !t.sees(kd) {
+			return
+		}
+		p := kd.domElt
+		box := toleranceBox(p, slices.Repeat([]float64{r}, len(p)))
+		t.rangeSearch(box, func(q Point) bool { return q.Sqd(p) <= r2 },
+			func(n *kdNode) bool {
+				if n != kd {
+					f(math.Sqrt(n.domElt.Sqd(p)))
+				}
+				return true
+			})
+	})
+}
+
+// live returns the number of points in the view of t, not counting
+// tombstones.
+func (t KdTree) live() int {
+	if t.n == nil {
+		return 0
+	}
+	if !t.restricted() {
+		return t.n.size - t.dead
+	}
+	n := 0
+	t.yieldAll(t.n, func(*kdNode) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// volume returns the product of the extents of hr.
+func (hr HyperRect) volume() float64 {
+	v := 1.
+	for i := range hr.Min {
+		v *= hr.Max[i] - hr.Min[i]
+	}
+	return v
+}
+
+// ballVolume returns the volume of a ball of radius r in dim dimensions.
+func ballVolume(dim int, r float64) float64 {
+	k := float64(dim) / 2
+	lg, _ := math.Lgamma(k + 1)
+	return math.Exp(k*math.Log(math.Pi)-lg) * math.Pow(r, float64(dim))
+}
@@ -0,0 +1,69 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNearestDistanceHist(t *testing.T) {
+	pts := randomPts(2, 500)
+	pts = append(pts, pts[0])
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	edges := []float64{0, .01, .02, .05, .1}
+	want := make([]int, len(edges)-1)
+	for i, p := range pts {
+		d := math.Inf(1)
+		for j, q := range pts {
+			if j != i {
+				d = math.Min(d, math.Sqrt(p.Sqd(q)))
+			}
+		}
+		for b := range want {
+			if d >= edges[b] && d < edges[b+1] {
+				want[b]++
+			}
+		}
+	}
+	got := kd.NearestDistanceHist(edges)
+	for b := range want {
+		if got[b] != want[b] {
+			t.Fatal("got", got, "expected", want)
+		}
+	}
+}
+
+func TestRipleyK(t *testing.T) {
+	pts := randomPts(2, 2000)
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	radii := []float64{.01, .02, .05}
+	k := kd.RipleyK(radii)
+	for i, r := range radii {
+		pairs := 0
+		for a, p := range pts {
+			for b, q := range pts {
+				if a != b && p.Sqd(q) <= r*r {
+					pairs++
+				}
+			}
+		}
+		want := float64(pairs) / float64(len(pts)*(len(pts)-1))
+		if math.Abs(k[i]-want) > 1e-12 {
+			t.Fatal("K", r, "got", k[i], "expected", want)
+		}
+		// uniform points give about the area of the circle, less edge loss
+		if a := math.Pi * r * r; k[i] < .8*a || k[i] > 1.1*a {
+			t.Error("K", r, "=", k[i], "area", a)
+		}
+	}
+	g := kd.PairCorrelation([]float64{.02, .04}, .01)
+	for _, v := range g {
+		if v < .8 || v > 1.2 {
+			t.Error("g", g)
+		}
+	}
+	if k := New(pts[:1], kd.Bounds).RipleyK(radii); k[2] != 0 {
+		t.Error("K of one point", k)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// IndexRadius gives each point of t a radius, taken from its data by f,
+// making it the center of a sphere for SpheresContaining and
+// SpheresIntersecting.  The greatest radius in each subtree is kept as
+// an attribute after any indexed with IndexAttrs, as with IndexTime, and
+// a later call to IndexAttrs drops the radius index.
+func (t *KdTree) IndexRadius(f func(data interface{}) float64) {
+	t.radiusAttr = t.indexAttr(t.radiusAttr, f)
+}
+
+// SpheresContaining returns the points of t whose spheres contain p, as
+// Neighbors sorted by distance from p.  t must have radii indexed with
+// IndexRadius.
+//
+// A subtree is pruned when p is farther from its box than the greatest
+// radius in the subtree.
+func (t KdTree) SpheresContaining(p Point) Neighbors {
+	return t.spheres(func(box HyperRect, r float64) bool {
+		return box.Sqd(p) <= r*r
+	}, func(q Point) float64 { return q.Sqd(p) })
+}
+
+// SpheresIntersecting returns the points of t whose spheres intersect
+// hr, as Neighbors sorted by distance from hr.  t must have radii indexed
+// with IndexRadius.
+func (t KdTree) SpheresIntersecting(hr HyperRect) Neighbors {
+	return t.spheres(func(box HyperRect, r float64) bool {
+		return boxSqd(box, hr) <= r*r
+	}, hr.Sqd)
+}
+
+// spheres returns the points of t within their radius of a query region,
+// where near reports whether a box may hold such a point given the
+// greatest radius of its points, and sqd is the square of the distance
+// of a point from the region.
+func (t KdTree) spheres(near func(box HyperRect, r float64) bool,
+	sqd func(Point) float64) (n Neighbors) {
+	if t.n == nil {
+		return nil
+	}
+	ra := t.radiusAttr - 1
+	radius := t.attrs[ra]
+	c := newCellStack(t, 0.)
+	for {
+		kd, _, ok := c.pop()
+		if !ok {
+			break
+		}
+		if t.excludes(kd) {
+			continue
+		}
+		box := c.cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		var r float64
+		if kd.attrs != nil {
+			r = kd.attrs[2*ra+1]
+		} else {
+			r = radius(kd.rangeElt)
+		}
+		if !near(box, r) {
+			continue
+		}
+		if t.sees(kd) {
+			r := radius(kd.rangeElt)
+			if d := sqd(kd.domElt); d <= r*r {
+				n = append(n, kd.neighbor(d))
+			}
+		}
+		c.push(kd, kd.right, 0)
+		c.push(kd, kd.left, 0)
+	}
+	n.Sort()
+	return
+}
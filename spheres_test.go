@@ -0,0 +1,71 @@
+package kdtree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSpheres(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	pts := randomPts(2, 2000)
+	radii := make([]interface{}, len(pts))
+	for i := range radii {
+		radii[i] = .03 * rng.Float64()
+		if i%200 == 0 {
+			radii[i] = .3
+		}
+	}
+	kd := NewWith(append([]Point{}, pts...), WithData(radii))
+	kd.Brute = false
+	kd.IndexRadius(func(d interface{}) float64 { return d.(float64) })
+	// spheres added after indexing must be found
+	for i := 0; i < 100; i++ {
+		p := randomPt(2)
+		pts = append(pts, p)
+		radii = append(radii, .05)
+		kd.InsertWithData(p, .05)
+	}
+	r := func(i int) float64 { return radii[i].(float64) }
+	// near a center of radius .3, so some sphere contains p
+	p := Point{pts[0][0] + .1, pts[0][1]}
+	want := 0
+	for i, q := range pts {
+		if q.Sqd(p) <= r(i)*r(i) {
+			want++
+		}
+	}
+	got := kd.SpheresContaining(p)
+	if len(got) != want || want == 0 {
+		t.Fatal("SpheresContaining found", len(got), "expected", want)
+	}
+	for _, n := range got {
+		if n.Sqd > r(n.Index)*r(n.Index) {
+			t.Fatal(n, "does not contain", p)
+		}
+	}
+	hr := HyperRect{Point{.4, .4}, Point{.5, .6}}
+	want = 0
+	for i, q := range pts {
+		if hr.Sqd(q) <= r(i)*r(i) {
+			want++
+		}
+	}
+	if got := kd.SpheresIntersecting(hr); len(got) != want {
+		t.Fatal("SpheresIntersecting found", len(got), "expected", want)
+	}
+}
+
+func TestSpheresDeep(t *testing.T) {
+	kd, pts := deepTree(3000)
+	kd.IndexRadius(func(interface{}) float64 { return .01 })
+	p := Point{.5, .5}
+	want := 0
+	for _, q := range pts {
+		if q.Sqd(p) <= .01*.01 {
+			want++
+		}
+	}
+	if got := len(kd.SpheresContaining(p)); got != want {
+		t.Fatal("found", got, "spheres, expected", want)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// TreeStats describes the shape of a tree and of the points in it.
+type TreeStats struct {
+	Nodes int // nodes, including tombstones left by Remove
+	Dead  int // tombstones
+	Depth int // levels on the longest path from the root
+	// FlatDims lists the dimensions in which all points have the same
+	// coordinate.  The build does not split on these.
+	FlatDims []int
+	// Rank is the dimension of the smallest affine flat containing the
+	// points, 0 if all points are identical.  Rank less than the
+	// dimension of the points means they lie on a lower dimensional
+	// subspace, such as a line or plane, possibly not aligned with axes.
+	Rank int
+	// Identical is true when the tree holds more than one point and all
+	// are identical.  Such a tree cannot be split and degenerates to a
+	// path.
+	Identical bool
+}
+
+// Stats returns statistics of t, detecting degenerate inputs.
+// It reads all points, forcing lazy subtrees.
+func (t KdTree) Stats() (s TreeStats) {
+	if t.n == nil {
+		return
+	}
+	type frame struct {
+		kd    *kdNode
+		depth int
+	}
+	for stack := []frame{{t.n, 1}}; len(stack) > 0; {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		f.kd.force()
+		s.Nodes++
+		if f.kd.deleted {
+			s.Dead++
+		}
+		s.Depth = max(s.Depth, f.depth)
+		for _, c := range []*kdNode{f.kd.left, f.kd.right} {
+			if c != nil {
+				stack = append(stack, frame{c, f.depth + 1})
+			}
+		}
+	}
+	var pts []Point
+	walk(t.n, func(kd *kdNode) {
+		if !kd.deleted {
+			pts = append(pts, kd.domElt)
+		}
+	})
+	if len(pts) == 0 {
+		return
+	}
+	ext := HyperRect{append(Point{}, pts[0]...), append(Point{}, pts[0]...)}
+	for _, p := range pts[1:] {
+		ext.extend(HyperRect{p, p})
+	}
+	scale := 0.
+	for d := range ext.Min {
+		if w := ext.Max[d] - ext.Min[d]; w == 0 {
+			s.FlatDims = append(s.FlatDims, d)
+		} else {
+			scale = math.Max(scale, w)
+		}
+	}
+	s.Rank = affineRank(pts, scale)
+	s.Identical = len(pts) > 1 && s.Rank == 0
+	return
+}
+
+// affineRank returns the dimension of the affine hull of pts, found by
+// Gram-Schmidt orthogonalization of the offsets of the points from the
+// first.  Residuals below a tolerance relative to scale, the largest
+// extent of the points, are taken as zero.
+func affineRank(pts []Point, scale float64) int {
+	tol := 1e-9 * scale
+	var basis []Point
+	r := make(Point, len(pts[0]))
+	for _, p := range pts[1:] {
+		if len(basis) == len(r) {
+			break
+		}
+		for i := range r {
+			r[i] = p[i] - pts[0][i]
+		}
+		for _, b := range basis {
+			c := dot(r, b)
+			for i := range r {
+				r[i] -= c * b[i]
+			}
+		}
+		if n := math.Sqrt(dot(r, r)); n > tol {
+			b := make(Point, len(r))
+			for i := range r {
+				b[i] = r[i] / n
+			}
+			basis = append(basis, b)
+		}
+	}
+	return len(basis)
+}
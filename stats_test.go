@@ -0,0 +1,58 @@
+package kdtree
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestStatsFlat(t *testing.T) {
+	// points on the plane z = .5
+	pts := randomPts(3, 4096)
+	for _, p := range pts {
+		p[2] = .5
+	}
+	kd := New(pts, HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	kd.Brute = false
+	s := kd.Stats()
+	if s.Nodes != len(pts) || s.Rank != 2 || s.Identical ||
+		!slices.Equal(s.FlatDims, []int{2}) {
+		t.Fatalf("%+v", s)
+	}
+	// balanced, as if built in two dimensions
+	if s.Depth > 13 {
+		t.Error("depth", s.Depth)
+	}
+	checkNearest(t, kd, len(pts))
+}
+
+func TestStatsLine(t *testing.T) {
+	pts := make([]Point, 100)
+	for i := range pts {
+		x := float64(i) / 100
+		pts[i] = Point{x, 1 - x, .5 * x}
+	}
+	kd := New(pts, HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	s := kd.Stats()
+	if s.Rank != 1 || s.FlatDims != nil || s.Identical {
+		t.Fatalf("%+v", s)
+	}
+	if want := int(math.Log2(100)) + 1; s.Depth != want {
+		t.Error("depth", s.Depth, "expected", want)
+	}
+}
+
+func TestStatsIdentical(t *testing.T) {
+	pts := []Point{{.5, .5}, {.5, .5}, {.5, .5}}
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.MaxDead = 1
+	kd.Remove(pts[0])
+	s := kd.Stats()
+	if !s.Identical || s.Rank != 0 || s.Nodes != 3 || s.Dead != 1 ||
+		len(s.FlatDims) != 2 {
+		t.Fatalf("%+v", s)
+	}
+	if s = (KdTree{}).Stats(); s.Nodes != 0 || s.Identical {
+		t.Fatalf("%+v", s)
+	}
+}
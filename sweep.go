@@ -0,0 +1,61 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"slices"
+)
+
+// Contact is a point met by a moving point, with T the fraction of the
+// time step, from 0 to 1, at which the moving point first comes within
+// the query distance of it.
+type Contact struct {
+	Neighbor
+	T float64
+}
+
+// Swept returns the points of t that a point moving from from to to
+// during a time step comes within distance r of, ordered by time of
+// first contact, for continuous collision detection against the points
+// of t.  Sqd is the square of the least distance over the step.  Points
+// already within r at the start have T = 0.
+//
+// The swept volume is a capsule, and the tree is searched as by
+// InCorridor, pruning cells the capsule does not reach.
+func (t KdTree) Swept(from, to Point, r float64) []Contact {
+	n := t.InCorridor([]Point{from, to}, r)
+	c := make([]Contact, len(n))
+	d := make(Point, len(from))
+	for i := range d {
+		d[i] = to[i] - from[i]
+	}
+	r2 := r * r
+	a := dot(d, d)
+	for i, nb := range n {
+		c[i].Neighbor = nb
+		// solve |from + T d - q|^2 = r^2 for the lesser root.
+		f := make(Point, len(from))
+		for j := range f {
+			f[j] = from[j] - nb.Point[j]
+		}
+		cc := dot(f, f) - r2
+		if cc <= 0 || a == 0 {
+			continue
+		}
+		b := dot(f, d)
+		tc := (-b - math.Sqrt(math.Max(0, b*b-a*cc))) / a
+		c[i].T = math.Min(1, math.Max(0, tc))
+	}
+	slices.SortFunc(c, func(x, y Contact) int {
+		switch {
+		case x.T < y.T:
+			return -1
+		case x.T > y.T:
+			return 1
+		}
+		return x.Index - y.Index
+	})
+	return c
+}
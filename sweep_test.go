@@ -0,0 +1,34 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSwept(t *testing.T) {
+	pts := randomPts(2, 2000)
+	kd := NewWith(append([]Point{}, pts...))
+	from, to, r := Point{.1, .2}, Point{.9, .7}, .03
+	seg := Segment{from, to}
+	want := 0
+	for _, p := range pts {
+		if seg.Sqd(p) <= r*r {
+			want++
+		}
+	}
+	c := kd.Swept(from, to, r)
+	if len(c) != want || want == 0 {
+		t.Fatal("got", len(c), "contacts, want", want)
+	}
+	for i, ct := range c {
+		if i > 0 && ct.T < c[i-1].T {
+			t.Fatal("contacts not ordered by time")
+		}
+		// at time T the moving point is at distance r, or nearer at T = 0
+		m := Point{from[0] + ct.T*(to[0]-from[0]), from[1] + ct.T*(to[1]-from[1])}
+		d := math.Sqrt(m.Sqd(ct.Point))
+		if ct.T > 0 && math.Abs(d-r) > 1e-9 || ct.T == 0 && d > r {
+			t.Fatal("contact", ct, "at distance", d)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "time"
+
+// TimeFunc extracts a timestamp from the data associated with a point.
+type TimeFunc func(data interface{}) time.Time
+
+// IndexTime indexes the timestamps given by f as an attribute, keeping for
+// each subtree the earliest and latest time of its points so that
+// InWindow queries skip subtrees entirely outside the window.
+//
+// The time is added after any attributes already indexed with IndexAttrs,
+// or replaces a time indexed earlier.  A later call to IndexAttrs drops
+// the time index.
+func (t *KdTree) IndexTime(f TimeFunc) {
+	t.timeOf = f
+	t.timeAttr = t.indexAttr(t.timeAttr, func(data interface{}) float64 {
+		return timeValue(f(data))
+	})
+}
+
+// InWindow returns a view of t, as Where, in which queries see only
+// points with times from from through to inclusive.  t must have a time
+// index set with IndexTime.  A view of a view sees the intersection of
+// the windows.  All queries of the view honor the window, as for Where.
+func (t KdTree) InWindow(from, to time.Time) KdTree {
+	w := &timeWindow{from, to}
+	if t.window != nil {
+		if t.window.from.After(from) {
+			w.from = t.window.from
+		}
+		if t.window.to.Before(to) {
+			w.to = t.window.to
+		}
+	}
+	t.window = w
+	return t.Where(AttrRange{t.timeAttr - 1, timeValue(from), timeValue(to)})
+}
+
+// timeWindow is the window of a view, compared exactly with the time of
+// each point.  The attribute values used to skip subtrees are rounded,
+// about to the microsecond at present dates, so alone they would let in
+// points just outside the window.
+type timeWindow struct {
+	from, to time.Time
+}
+
+func (w *timeWindow) contains(tm time.Time) bool {
+	return !tm.Before(w.from) && !tm.After(w.to)
+}
+
+// timeValue is the attribute value of tm, in nanoseconds since the Unix
+// epoch.  Rounding to float64 keeps the order of times, if not their
+// differences, so subtrees are never skipped wrongly.
+func timeValue(tm time.Time) float64 { return float64(tm.UnixNano()) }
@@ -0,0 +1,112 @@
+package kdtree
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestInWindow(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	pts := randomPts(2, 3000)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = t0.Add(time.Duration(i) * time.Minute)
+	}
+	kd := NewWith(append([]Point{}, pts...), WithData(data),
+		WithBounds(HyperRect{Point{0, 0}, Point{1, 1}}))
+	kd.Brute = false
+	kd.IndexTime(func(d interface{}) time.Time { return d.(time.Time) })
+	from, to := t0.Add(1000*time.Minute), t0.Add(1100*time.Minute)
+	in := func(i int) bool { return i >= 1000 && i <= 1100 }
+	w := kd.InWindow(from, to)
+	p := randomPt(2)
+	var want []float64
+	for i, q := range pts {
+		if in(i) {
+			want = append(want, q.Sqd(p))
+		}
+	}
+	sort.Float64s(want)
+	got := w.KNearestNeighbors(p, 5)
+	if len(got) != 5 {
+		t.Fatal("got", len(got), "results")
+	}
+	for i, n := range got {
+		if !in(n.Index) || n.Sqd != want[i] {
+			t.Fatal("result", i, n, "expected distance^2", want[i])
+		}
+	}
+	if _, sqd, _ := w.Nearest(p); sqd != want[0] {
+		t.Fatal("Nearest", sqd, "expected", want[0])
+	}
+	hr := HyperRect{Point{.2, .2}, Point{.8, .8}}
+	c := 0
+	for i, q := range pts {
+		if in(i) && hr.Contains(q) {
+			c++
+		}
+	}
+	if n := len(w.InRange(hr)); n != c {
+		t.Fatal("InRange", n, "expected", c)
+	}
+	// the tree itself is unrestricted
+	if n := len(kd.InRange(hr)); n <= c {
+		t.Fatal("unfiltered InRange", n)
+	}
+	// a window holding no points
+	if p, _, _ := kd.InWindow(t0.Add(-time.Hour), t0.Add(-time.Minute)).Nearest(p); p != nil {
+		t.Fatal("found", p, "in empty window")
+	}
+}
+
+func TestInWindowNanoseconds(t *testing.T) {
+	// times 100ns apart, finer than float64 nanoseconds at this date
+	t0 := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	pts := randomPts(2, 200)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = t0.Add(time.Duration(i) * 100 * time.Nanosecond)
+	}
+	kd := NewWith(pts, WithData(data))
+	kd.Brute = false
+	kd.IndexTime(func(d interface{}) time.Time { return d.(time.Time) })
+	from, to := data[50].(time.Time), data[60].(time.Time)
+	w := kd.InWindow(from, to)
+	n := w.KNearestNeighbors(Point{.5, .5}, len(pts))
+	if len(n) != 11 {
+		t.Fatal("found", len(n), "points in window, expected 11")
+	}
+	for _, nb := range n {
+		if nb.Index < 50 || nb.Index > 60 {
+			t.Fatal("point", nb.Index, "outside window")
+		}
+	}
+	// nested windows intersect
+	if n := w.InWindow(data[55].(time.Time), data[90].(time.Time)).
+		KNearestNeighbors(Point{.5, .5}, len(pts)); len(n) != 6 {
+		t.Fatal("found", len(n), "points in nested window, expected 6")
+	}
+}
+
+func TestInWindowQueries(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	pts := randomPts(2, 2000)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = t0.Add(time.Duration(i) * time.Minute)
+	}
+	prepare := func(kd *KdTree) {
+		kd.IndexTime(func(d interface{}) time.Time { return d.(time.Time) })
+		kd.IndexRadius(func(interface{}) float64 { return .2 })
+	}
+	kd := NewWith(append([]Point{}, pts...), WithData(data),
+		WithBounds(HyperRect{Point{0, 0}, Point{1, 1}}))
+	kd.Brute = false
+	prepare(&kd)
+	// ends a nanosecond before minutes 600 and 900, closer than the
+	// attribute values resolve
+	w := kd.InWindow(t0.Add(600*time.Minute-time.Nanosecond),
+		t0.Add(900*time.Minute-time.Nanosecond))
+	checkView(t, w, pts, data, func(i int) bool { return i >= 600 && i < 900 }, prepare)
+}
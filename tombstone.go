@@ -0,0 +1,60 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// DefaultMaxDead is the tombstone fraction used when KdTree.MaxDead is
+// zero.
+const DefaultMaxDead = .25
+
+// Remove removes a point with the coordinates of p from t.  It returns
+// false if there is no such point.
+//
+// Unlike Delete, Remove does not restructure the tree.  It only marks the
+// node of the point as a tombstone, which searches then skip.  Tombstones
+// still cost search time and memory, so once they exceed the fraction
+// t.MaxDead of the nodes, Remove calls Compact.
+func (t *KdTree) Remove(p Point) bool {
+	for kd := t.n; kd != nil; {
+		kd.force()
+		if !kd.deleted && equal(kd.domElt, p) {
+			kd.deleted = true
+			t.dead++
+			t.gen++
+			f := t.MaxDead
+			if f == 0 {
+				f = DefaultMaxDead
+			}
+			if f < 1 && float64(t.dead) > f*float64(t.n.size) {
+				t.Compact()
+			}
+			return true
+		}
+		if p[kd.split] <= kd.domElt[kd.split] {
+			kd = kd.left
+		} else {
+			kd = kd.right
+		}
+	}
+	return false
+}
+
+// Compact rebuilds t without the tombstones left by Remove.
+func (t *KdTree) Compact() {
+	if t.n == nil {
+		return
+	}
+	tight := t.tight()
+	live := make([]*kdNode, 0, t.n.size-t.dead)
+	walk(t.n, func(kd *kdNode) {
+		if !kd.deleted {
+			live = append(live, kd)
+		}
+	})
+	t.rebuilt("compact", len(live))
+	t.n = nk2(live, 0, -1, t.Split)
+	if t.n != nil {
+		t.refit(t.n, tight)
+	}
+	t.dead = 0
+}
@@ -0,0 +1,40 @@
+package kdtree
+
+import "testing"
+
+func TestRemove(t *testing.T) {
+	pts := randomPts(2, 1000)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.MaxDead = 2
+	for _, p := range pts[:500] {
+		if !kd.Remove(p) {
+			t.Fatal("Remove", p, "returned false")
+		}
+	}
+	if kd.Remove(pts[0]) {
+		t.Error("Expected false removing a point twice")
+	}
+	if kd.n.size != 1000 || kd.dead != 500 {
+		t.Fatal("Expected 500 tombstones in 1000 nodes, found",
+			kd.dead, kd.n.size)
+	}
+	live := New(append([]Point{}, pts[500:]...), kd.Bounds)
+	for i := 0; i < 50; i++ {
+		p := randomPt(2)
+		_, want, _ := live.Nearest(p)
+		for _, brute := range []bool{false, true} {
+			kd.Brute = brute
+			if _, got, _ := kd.Nearest(p); got != want {
+				t.Fatal("Expected distance^2", want, "found", got)
+			}
+			if _, sqd, _ := kd.KNearest(p, 1); sqd[0] != want {
+				t.Fatal("Expected distance^2", want, "found", sqd[0])
+			}
+		}
+	}
+	kd.MaxDead = 0
+	kd.Remove(pts[500])
+	if kd.dead != 0 || kd.n.size != 499 {
+		t.Error("Expected compaction to 499 nodes, found", kd.n.size)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// TopK returns the k points of t with the least scores, least first, and
+// their scores.
+//
+// score rates a point from the Neighbor describing it, with Sqd its
+// squared distance from p.  A score might combine distance with an
+// attribute of the point's data, such as distance plus a price penalty.
+// bound returns a lower bound on the score of any point in box, given
+// minSqd, the least squared distance from p to the box.  When the score
+// increases with distance and the penalty is never negative, the score of
+// a point at distance minSqd with zero penalty serves.  The tighter the
+// bound, the more the search prunes.  If bound is nil, no subtree is
+// pruned.
+//
+// The search is best first: subtrees are taken in order of bound and
+// points in order of score, so the search ends when k points have been
+// taken.
+func (t KdTree) TopK(p Point, k int, score func(Neighbor) float64,
+	bound func(box HyperRect, minSqd float64) float64) (n Neighbors,
+	scores []float64) {
+	if t.n == nil || k <= 0 {
+		return
+	}
+	var q incQueue
+	push := func(kd *kdNode, cell HyperRect) {
+		kd.force()
+		if t.excludes(kd) {
+			return
+		}
+		key := math.Inf(-1)
+		if bound != nil {
+			box := cell
+			if kd.bounds != nil {
+				box = *kd.bounds
+			}
+			key = bound(box, box.Sqd(p))
+		}
+		q.push(incEntry{kd: kd, cell: cell, key: key})
+	}
+	push(t.n, t.Bounds.Copy())
+	for len(q) > 0 {
+		e := q.pop()
+		kd := e.kd
+		if e.point {
+			n = append(n, kd.neighbor(kd.domElt.Sqd(p)))
+			scores = append(scores, e.key)
+			if len(n) == k {
+				return
+			}
+			continue
+		}
+		if t.sees(kd) {
+			key := score(kd.neighbor(kd.domElt.Sqd(p)))
+			q.push(incEntry{kd: kd, point: true, key: key})
+		}
+		s := kd.split
+		if kd.right != nil {
+			c := e.cell
+			if kd.left != nil {
+				c = e.cell.Copy()
+			}
+			c.Min[s] = kd.domElt[s]
+			push(kd.right, c)
+		}
+		if kd.left != nil {
+			c := e.cell
+			c.Max[s] = kd.domElt[s]
+			push(kd.left, c)
+		}
+	}
+	return
+}
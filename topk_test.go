@@ -0,0 +1,40 @@
+package kdtree
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestTopK(t *testing.T) {
+	pts := randomPts(2, 1000)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = float64(i%10) / 20 // price penalty
+	}
+	kd := NewWithData(append([]Point{}, pts...), data,
+		HyperRect{Point{0, 0}, Point{1, 1}})
+	score := func(n Neighbor) float64 {
+		return math.Sqrt(n.Sqd) + n.Data.(float64)
+	}
+	bound := func(_ HyperRect, minSqd float64) float64 {
+		return math.Sqrt(minSqd)
+	}
+	p := randomPt(2)
+	all := make([]float64, len(pts))
+	for i, q := range pts {
+		all[i] = math.Sqrt(q.Sqd(p)) + data[i].(float64)
+	}
+	sort.Float64s(all)
+	for _, b := range []func(HyperRect, float64) float64{bound, nil} {
+		n, s := kd.TopK(p, 10, score, b)
+		if len(n) != 10 {
+			t.Fatal("got", len(n), "results")
+		}
+		for i := range n {
+			if s[i] != all[i] || score(n[i]) != s[i] {
+				t.Fatal("result", i, "score", s[i], "expected", all[i])
+			}
+		}
+	}
+}
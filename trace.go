@@ -0,0 +1,74 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "time"
+
+// Tracer is notified at the start and end of each query on a tree.  Set
+// KdTree.Tracer to receive the notifications, for example to create a
+// tracing span per query.
+//
+// Methods may be called concurrently by concurrent queries.
+type Tracer interface {
+	// OnQueryStart is called as a query of kind, such as "Nearest",
+	// starts.  The span returned is passed to OnQueryEnd for the same
+	// query.
+	OnQueryStart(kind string) (span interface{})
+	// OnQueryEnd is called as the query ends.
+	OnQueryEnd(span interface{}, stats QueryStats)
+}
+
+// QueryStats describes a completed query.
+type QueryStats struct {
+	Kind         string
+	NodesVisited int
+	Size         int // number of nodes in the tree
+	Elapsed      time.Duration
+}
+
+// PruningRatio returns the fraction of the nodes of the tree that the
+// query did not visit.
+func (s QueryStats) PruningRatio() float64 {
+	if s.Size == 0 {
+		return 0
+	}
+	return 1 - float64(s.NodesVisited)/float64(s.Size)
+}
+
+// query is a query in progress, as passed from begin to record.
+type query struct {
+	kind string
+	span interface{}
+}
+
+// observed reports whether queries on t are measured, by t.Metrics or
+// t.Tracer.
+func (t KdTree) observed() bool {
+	return t.Metrics != nil || t.Tracer != nil
+}
+
+// begin starts a measured query.
+func (t KdTree) begin(kind string) query {
+	q := query{kind: kind}
+	if t.Tracer != nil {
+		q.span = t.Tracer.OnQueryStart(kind)
+	}
+	return q
+}
+
+// record reports a query begun at start.  It is deferred with a pointer
+// to the node count, which is set by the query.
+func (t KdTree) record(q query, start time.Time, nv *int) {
+	elapsed := time.Since(start)
+	if t.Metrics != nil {
+		t.Metrics.Query(q.kind, *nv, elapsed)
+	}
+	if t.Tracer != nil {
+		size := 0
+		if t.n != nil {
+			size = t.n.size
+		}
+		t.Tracer.OnQueryEnd(q.span, QueryStats{q.kind, *nv, size, elapsed})
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"fmt"
+	"time"
+)
+
+// Strategy is a configuration for answering k nearest neighbor queries.
+//
+// There is no leaf size to choose: the tree keeps a point in every node,
+// as in the paper, so the build parameters are the split rule and the
+// order of construction.
+type Strategy struct {
+	Brute  bool      // scan all points, as KdTree.Brute
+	Eps    float64   // approximation as KNearestApprox, 0 for exact
+	Tight  bool      // tight bounding boxes, as Tighten
+	Morton bool      // built in Morton order, as NewMorton
+	Split  SplitRule // split rule, as WithSplitRule, unless Morton
+}
+
+func (s Strategy) String() string {
+	if s.Brute {
+		return "brute"
+	}
+	var b []byte
+	switch {
+	case s.Morton:
+		b = append(b, "morton "...)
+	case s.Split == SplitWidest:
+		b = append(b, "widest "...)
+	}
+	if s.Tight {
+		b = append(b, "tight "...)
+	}
+	return fmt.Sprintf("%seps=%g", b, s.Eps)
+}
+
+// DefaultStrategies returns the strategies compared by CompareStrategies
+// when none are given: brute force, and exact and approximate searches
+// with and without tight bounds, built with each split rule and in Morton
+// order.
+func DefaultStrategies() []Strategy {
+	s := []Strategy{{Brute: true}}
+	for _, build := range []Strategy{{Split: SplitCycle}, {Split: SplitWidest},
+		{Morton: true}} {
+		for _, tight := range []bool{false, true} {
+			for _, eps := range []float64{0, .1, .5, 1, 2} {
+				st := build
+				st.Tight, st.Eps = tight, eps
+				s = append(s, st)
+			}
+		}
+	}
+	return s
+}
+
+// StrategyResult is the measured performance of a Strategy.
+type StrategyResult struct {
+	Strategy
+	PerQuery time.Duration // mean time per query
+	Visited  float64       // mean nodes visited per query
+	Recall   float64       // fraction of the true k nearest found
+}
+
+// StrategyReport is the result of CompareStrategies.
+type StrategyReport struct {
+	Results []StrategyResult // in the order of the strategies compared
+	// Best is the fastest strategy meeting the recall target.  Exact
+	// strategies always meet it, so there is a best strategy whenever
+	// an exact one is compared.
+	Best StrategyResult
+	// OK is false if no strategy met the recall target.
+	OK bool
+}
+
+// CompareStrategies measures k nearest neighbor queries at each of the
+// points of queries, a representative sample, under each strategy, and
+// reports the fastest giving at least the fraction minRecall of the true
+// k nearest neighbors.  If no strategies are given, DefaultStrategies are
+// compared.
+//
+// Each strategy is measured on a copy of t built or tightened as the
+// strategy says.  t is not modified.  A found point counts toward recall
+// if it is no further than the true k-th nearest.  Timings are of the
+// actual data and machine, so are more reliable than rules of thumb, but
+// are subject to noise; a sample of a few hundred queries or more is
+// best.
+func (t KdTree) CompareStrategies(queries []Point, k int, minRecall float64,
+	strategies ...Strategy) StrategyReport {
+	if len(strategies) == 0 {
+		strategies = DefaultStrategies()
+	}
+	// ground truth: the distance of the true k-th nearest of each query.
+	truth := t.Snapshot()
+	truth.Brute, truth.Metrics, truth.Tracer = false, nil, nil
+	kth := make([]float64, len(queries))
+	want := make([]int, len(queries))
+	s := truth.NewSearcher()
+	for i, q := range queries {
+		_, sqd, _ := s.KNearest(q, k)
+		want[i] = len(sqd)
+		if len(sqd) > 0 {
+			kth[i] = sqd[len(sqd)-1]
+		}
+	}
+	var r StrategyReport
+	for _, st := range strategies {
+		v := t.variant(st)
+		s := v.NewSearcher()
+		res := StrategyResult{Strategy: st}
+		found, total := 0, 0
+		start := time.Now()
+		for i, q := range queries {
+			_, sqd, nv := s.KNearestApprox(q, k, st.Eps)
+			res.Visited += float64(nv)
+			total += want[i]
+			for _, d := range sqd {
+				if d <= kth[i] {
+					found++
+				}
+			}
+		}
+		if n := len(queries); n > 0 {
+			res.PerQuery = time.Since(start) / time.Duration(n)
+			res.Visited /= float64(n)
+		}
+		res.Recall = 1
+		if total > 0 {
+			res.Recall = float64(found) / float64(total)
+		}
+		r.Results = append(r.Results, res)
+		if res.Recall >= minRecall && (!r.OK || res.PerQuery < r.Best.PerQuery) {
+			r.Best, r.OK = res, true
+		}
+	}
+	return r
+}
+
+// variant returns a copy of t configured as st.  The copy is rebuilt if
+// st is built in Morton order or with a split rule other than that of t.
+func (t KdTree) variant(st Strategy) KdTree {
+	v := t.Snapshot()
+	v.Metrics, v.Tracer = nil, nil
+	v.Brute = st.Brute
+	if (st.Morton || st.Split != v.Split) && v.n != nil {
+		var nodes []*kdNode
+		walk(v.n, func(kd *kdNode) { nodes = append(nodes, kd) })
+		if st.Morton {
+			v.n = nkMorton(nodes, v.Bounds)
+		} else {
+			v.n = nk2(nodes, 0, -1, st.Split)
+		}
+		v.refit(v.n, false)
+	}
+	if !st.Morton {
+		v.Split = st.Split
+	}
+	switch {
+	case st.Tight:
+		v.Tighten()
+	case v.n != nil:
+		walk(v.n, func(kd *kdNode) { kd.bounds = nil })
+	}
+	return v
+}
+
+// TuneK and TuneRecall are the number of neighbors AutoTune measures
+// queries for and the recall target the chosen strategy must meet.
+var (
+	TuneK      = 10
+	TuneRecall = .9
+)
+
+// AutoTune compares DefaultStrategies as CompareStrategies, with k TuneK,
+// and reconfigures t with the fastest giving at least the fraction
+// TuneRecall of the true nearest neighbors of sampleQueries.
+//
+// t is rebuilt with the strategy's split rule or in Morton order,
+// tightened, or set to brute force as the strategy says.  The strategy
+// is returned so that its Eps can be passed to KNearestApprox; KNearest
+// remains exact.  If no strategy meets TuneRecall, t is unchanged and ok
+// is false.
+func (t *KdTree) AutoTune(sampleQueries []Point) (s Strategy, ok bool) {
+	r := t.CompareStrategies(sampleQueries, TuneK, TuneRecall)
+	if !r.OK {
+		return Strategy{}, false
+	}
+	v := t.variant(r.Best.Strategy)
+	v.Metrics, v.Tracer = t.Metrics, t.Tracer
+	if v.n != nil {
+		t.rebuilt("tune", v.n.size)
+	}
+	*t = v
+	return r.Best.Strategy, true
+}
@@ -0,0 +1,74 @@
+package kdtree
+
+import "testing"
+
+func TestCompareStrategies(t *testing.T) {
+	pts := randomPts(3, 3000)
+	kd := New(pts, HyperRect{Point{0, 0, 0}, Point{1, 1, 1}})
+	r := kd.CompareStrategies(randomPts(3, 100), 5, .9)
+	if !r.OK || r.Best.Recall < .9 || len(r.Results) != len(DefaultStrategies()) {
+		t.Fatalf("%+v", r.Best)
+	}
+	for _, res := range r.Results {
+		if res.Eps == 0 && res.Recall != 1 {
+			t.Error(res.Strategy, "recall", res.Recall)
+		}
+		if res.Brute && res.Visited != float64(len(pts)) {
+			t.Error("brute visited", res.Visited)
+		}
+		if !res.Brute && res.Visited >= float64(len(pts)) {
+			t.Error(res.Strategy, "visited", res.Visited)
+		}
+	}
+	if kd.tight() {
+		t.Error("tree modified")
+	}
+	// an unreachable target
+	r = kd.CompareStrategies(randomPts(3, 10), 5, 2, Strategy{Eps: 1})
+	if r.OK {
+		t.Error("recall 2 met")
+	}
+}
+
+func TestAutoTune(t *testing.T) {
+	pts := randomPts(2, 2000)
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	m := &testMetrics{queries: map[string]int{}, rebuilds: map[string]int{}}
+	kd.Metrics = m
+	defer func(k int, r float64) { TuneK, TuneRecall = k, r }(TuneK, TuneRecall)
+	TuneK, TuneRecall = 3, 1
+	s, ok := kd.AutoTune(randomPts(2, 100))
+	if !ok || s.Eps != 0 {
+		t.Fatal("strategy", s, ok)
+	}
+	if kd.tight() != s.Tight || kd.Brute != s.Brute || kd.Metrics != m ||
+		!s.Morton && kd.Split != s.Split {
+		t.Fatal("tree not configured as", s)
+	}
+	checkSizes(t, kd.n)
+	checkNearest(t, kd, len(pts))
+	if m.rebuilds["tune"] != 1 {
+		t.Error("rebuilds", m.rebuilds)
+	}
+	TuneRecall = 2
+	if _, ok := kd.AutoTune(randomPts(2, 10)); ok {
+		t.Error("recall 2 met")
+	}
+}
+
+func TestStrategySplit(t *testing.T) {
+	// points stretched along one axis favor splitting the widest
+	pts := randomPts(2, 2000)
+	for _, p := range pts {
+		p[0] *= 50
+	}
+	kd := NewWith(pts)
+	v := kd.variant(Strategy{Split: SplitWidest})
+	if v.Split != SplitWidest || v.n.split != 0 || kd.Split != SplitCycle {
+		t.Fatal("variant not rebuilt with split rule")
+	}
+	checkNearest(t, v, len(pts))
+	if s := (Strategy{Split: SplitWidest, Tight: true, Eps: .5}).String(); s != "widest tight eps=0.5" {
+		t.Error(s)
+	}
+}
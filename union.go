@@ -0,0 +1,66 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// KNearestAny returns the k points of t nearest to any of targets, by the
+// least distance to a target, nearest first.  The Sqd of each Neighbor is
+// the square of that least distance.
+//
+// The tree is traversed once for all targets.  A subtree is bounded by
+// the least distance from a target to its cell, or tight bounds, and is
+// pruned when that bound exceeds the k-th best distance, so the search is
+// exact and usually much cheaper than merging a KNearest per target.
+func (t KdTree) KNearestAny(targets []Point, k int) Neighbors {
+	if t.n == nil || k <= 0 || len(targets) == 0 {
+		return Neighbors{}
+	}
+	h := NewKHeap(k)
+	minSqd := func(box HyperRect) float64 {
+		d := math.Inf(1)
+		for _, p := range targets {
+			d = math.Min(d, box.Sqd(p))
+		}
+		return d
+	}
+	// lb returns the lower bound of the subtree at kd with cell.
+	lb := func(kd *kdNode, cell HyperRect) float64 {
+		if kd.bounds != nil {
+			return minSqd(*kd.bounds)
+		}
+		return minSqd(cell)
+	}
+	c := newCellStack(t, 0.)
+	for {
+		kd, bound, ok := c.pop()
+		if !ok {
+			break
+		}
+		if bound > h.Worst() || t.excludes(kd) {
+			continue
+		}
+		if t.sees(kd) {
+			h.Push(kd.neighbor(minSqd(HyperRect{kd.domElt, kd.domElt})))
+		}
+		// bound the children, then search the lesser bound first.
+		var lbl, lbr float64
+		if l := kd.left; l != nil {
+			l.force()
+			lbl = c.childCell(kd, l, func(cell HyperRect) float64 { return lb(l, cell) })
+		}
+		if r := kd.right; r != nil {
+			r.force()
+			lbr = c.childCell(kd, r, func(cell HyperRect) float64 { return lb(r, cell) })
+		}
+		if lbl <= lbr {
+			c.push(kd, kd.right, lbr)
+			c.push(kd, kd.left, lbl)
+		} else {
+			c.push(kd, kd.left, lbl)
+			c.push(kd, kd.right, lbr)
+		}
+	}
+	return h.Results()
+}
@@ -0,0 +1,49 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKNearestAny(t *testing.T) {
+	pts := randomPts(2, 3000)
+	kd := New(pts, HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	for trial := 0; trial < 20; trial++ {
+		targets := randomPts(2, 1+trial%5)
+		got := kd.KNearestAny(targets, 6)
+		var all Neighbors
+		for _, q := range targets {
+			all = all.Merge(kd.KNearestNeighbors(q, 6))
+		}
+		// least distance of each candidate to any target
+		for i := range all {
+			d := math.Inf(1)
+			for _, q := range targets {
+				d = math.Min(d, all[i].Point.Sqd(q))
+			}
+			all[i].Sqd = d
+		}
+		all = all.Trim(6)
+		for i := range all {
+			if got[i].Sqd != all[i].Sqd {
+				t.Fatal("got", got, "expected", all)
+			}
+		}
+	}
+	if n := kd.KNearestAny(nil, 3); len(n) != 0 {
+		t.Error("no targets", n)
+	}
+}
+
+func TestKNearestAnyDeep(t *testing.T) {
+	kd, _ := deepTree(3000)
+	targets := []Point{{.2, .3}, {.7, .6}}
+	got := kd.KNearestAny(targets, 4)
+	want := kd.KNearestNeighbors(targets[0], 4).Merge(kd.KNearestNeighbors(targets[1], 4)).Trim(4)
+	for i := range want {
+		if got[i].Index != want[i].Index {
+			t.Fatal("got", got, "expected", want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// Update moves a point with the coordinates of old to the coordinates of
+// new.  It returns false if there is no point at old, or if new lies
+// outside t.Bounds and t.FixedBounds is set.  Otherwise t.Bounds is grown
+// as needed, as for Insert.
+//
+// If new lies within the cell of the point's node, and between the points
+// of the node's left and right subtrees in its split dimension, the node
+// is reused with only its coordinates changed.  Otherwise Update is the
+// same as Delete(old) followed by Insert(new), except that the index and
+// data of the point are kept.  Small moves of tracked objects usually
+// take the fast path.
+func (t *KdTree) Update(old, new Point) bool {
+	var path []*kdNode
+	var kd *kdNode
+	for kd = t.n; ; {
+		if kd == nil {
+			return false
+		}
+		kd.force()
+		if !kd.deleted && equal(kd.domElt, old) {
+			break
+		}
+		path = append(path, kd)
+		if old[kd.split] <= kd.domElt[kd.split] {
+			kd = kd.left
+		} else {
+			kd = kd.right
+		}
+	}
+	if t.grow(new) != nil {
+		return false
+	}
+	if !fits(path, kd, new) {
+		e := kd.elt
+		t.Delete(old)
+		e.domElt = new
+		t.insert(e)
+		return true
+	}
+	t.gen++
+	prev := kd.domElt
+	kd.domElt = new
+	kd.moveSample(prev, new)
+	nr := HyperRect{new, new}
+	if kd.bounds != nil {
+		kd.bounds.extend(nr)
+	}
+	for _, a := range path {
+		if a.bounds != nil {
+			a.bounds.extend(nr)
+		}
+		a.moveSample(prev, new)
+	}
+	return true
+}
+
+// fits reports whether node kd, found by path, can hold p without
+// violating the split invariants of its ancestors or of itself.
+func fits(path []*kdNode, kd *kdNode, p Point) bool {
+	for i, a := range path {
+		c := kd
+		if i+1 < len(path) {
+			c = path[i+1]
+		}
+		s := a.split
+		if (c == a.left) != (p[s] <= a.domElt[s]) {
+			return false
+		}
+	}
+	s := kd.split
+	if kd.left != nil && maxNode(kd.left, s).domElt[s] > p[s] {
+		return false
+	}
+	return kd.right == nil || minNode(kd.right, s).domElt[s] > p[s]
+}
+
+// minNode returns the node of the subtree at kd with the least coordinate
+// in dimension s.
+func minNode(kd *kdNode, s int) *kdNode {
+	kd.force()
+	m := kd
+	if kd.split == s {
+		if kd.left != nil {
+			if lm := minNode(kd.left, s); lm.domElt[s] <= m.domElt[s] {
+				m = lm
+			}
+		}
+		return m
+	}
+	for _, c := range []*kdNode{kd.left, kd.right} {
+		if c != nil {
+			if cm := minNode(c, s); cm.domElt[s] < m.domElt[s] {
+				m = cm
+			}
+		}
+	}
+	return m
+}
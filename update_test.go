@@ -0,0 +1,33 @@
+package kdtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	pts := randomPts(2, 500)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Brute = false
+	kd.Tighten()
+	for step := 0; step < 5; step++ {
+		for i, p := range pts {
+			q := Point{clamp(p[0] + (rand.Float64()-.5)*.01),
+				clamp(p[1] + (rand.Float64()-.5)*.01)}
+			if !kd.Update(p, q) {
+				t.Fatal("Update", p, "returned false")
+			}
+			pts[i] = q
+		}
+		checkSizes(t, kd.n)
+		checkNearest(t, kd, len(pts))
+	}
+	if kd.Update(Point{2, 2}, Point{0, 0}) {
+		t.Error("Expected false updating a point not in the tree")
+	}
+}
+
+func clamp(c float64) float64 {
+	return math.Max(0, math.Min(1, c))
+}
@@ -0,0 +1,45 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+// Visit traverses t for custom queries.
+//
+// enter is called with a box containing all points of a subtree before
+// the subtree is visited.  If it returns false, the subtree is skipped.
+// The box is the subtree's cell, the part of t.Bounds resulting from the
+// splits above it, or its tight bounding box if t was tightened.  visit
+// is called for each point of entered subtrees.  If it returns false, the
+// traversal stops.  In a view such as one made by Where, subtrees holding
+// no point of the view are not entered and visit sees only points of the
+// view.
+//
+// The box passed to enter is reused.  It must not be modified or retained
+// after enter returns.
+func (t KdTree) Visit(enter func(box HyperRect) bool, visit func(Point) bool) {
+	if t.n == nil {
+		return
+	}
+	c := newCellStack(t, 0.)
+	for {
+		kd, _, ok := c.pop()
+		if !ok {
+			return
+		}
+		if t.excludes(kd) {
+			continue
+		}
+		box := c.cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		if !enter(box) {
+			continue
+		}
+		if t.sees(kd) && !visit(kd.domElt) {
+			return
+		}
+		c.push(kd, kd.right, 0)
+		c.push(kd, kd.left, 0)
+	}
+}
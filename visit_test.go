@@ -0,0 +1,64 @@
+package kdtree
+
+import "testing"
+
+func TestVisit(t *testing.T) {
+	pts := randomPts(2, 500)
+	kd := New(append([]Point{}, pts...), HyperRect{Point{0, 0}, Point{1, 1}})
+	// a range query written with Visit
+	q := HyperRect{Point{.2, .3}, Point{.6, .5}}
+	want := len(kd.InRange(q))
+	for _, tight := range []bool{false, true} {
+		if tight {
+			kd.Tighten()
+		}
+		got := 0
+		kd.Visit(q.Intersects, func(p Point) bool {
+			if q.Contains(p) {
+				got++
+			}
+			return true
+		})
+		if got != want {
+			t.Error("Expected", want, "points, found", got)
+		}
+	}
+	n := 0
+	kd.Visit(func(HyperRect) bool { return true },
+		func(Point) bool { n++; return n < 10 })
+	if n != 10 {
+		t.Error("Expected to stop after 10 points, found", n)
+	}
+}
+
+func TestFuncEarlyStop(t *testing.T) {
+	kd := New(randomPts(2, 500), HyperRect{Point{0, 0}, Point{1, 1}})
+	p := randomPt(2)
+	for name, q := range map[string]func(f func(Point) bool){
+		"InRangeFunc": func(f func(Point) bool) {
+			kd.InRangeFunc(kd.Bounds, f)
+		},
+		"InRadiusFunc": func(f func(Point) bool) { kd.InRadiusFunc(p, 2, f) },
+		"KNearestFunc": func(f func(Point) bool) {
+			kd.KNearestFunc(p, 10, func(q Point, _ float64) bool { return f(q) })
+		},
+	} {
+		n := 0
+		q(func(Point) bool { n++; return n < 3 })
+		if n != 3 {
+			t.Error(name, "expected to stop after 3 points, found", n)
+		}
+	}
+}
+
+func TestVisitDeep(t *testing.T) {
+	kd, pts := deepTree(3000)
+	n := 0
+	kd.Visit(func(HyperRect) bool { return true }, func(Point) bool {
+		n++
+		return true
+	})
+	if n != len(pts) {
+		t.Fatal("visited", n, "points, expected", len(pts))
+	}
+}
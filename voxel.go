@@ -0,0 +1,145 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"slices"
+)
+
+// voxel accumulates the points of t within one cube of a voxel grid.
+type voxel struct {
+	cell []int64 // grid coordinates
+	sum  Point
+	n    int
+}
+
+// Downsample returns one point per occupied voxel of a grid of cubes of
+// side size, aligned with the origin: the centroid of the points of t in
+// the voxel.  Voxels are in lexical order of their grid coordinates.
+//
+// The tree is traversed rather than hashing every point.  A subtree whose
+// box lies within a single voxel is summed directly into it, so only
+// points in subtrees spanning voxel boundaries are placed individually.
+func (t KdTree) Downsample(size float64) []Point {
+	vs := t.voxels(size)
+	c := make([]Point, len(vs))
+	for i, v := range vs {
+		c[i] = v.centroid()
+	}
+	return c
+}
+
+// DownsampleMedoid returns one point of t per occupied voxel as
+// Downsample, the point nearest the centroid of the voxel, with Sqd its
+// squared distance from the centroid.
+func (t KdTree) DownsampleMedoid(size float64) Neighbors {
+	vs := t.voxels(size)
+	n := make(Neighbors, len(vs))
+	for i, v := range vs {
+		c := v.centroid()
+		box := HyperRect{make(Point, len(c)), make(Point, len(c))}
+		for j, x := range v.cell {
+			box.Min[j] = float64(x) * size
+			box.Max[j] = box.Min[j] + size
+		}
+		// the box includes its upper faces, the voxel does not.
+		in := func(p Point) bool {
+			for j, x := range p {
+				if int64(math.Floor(x/size)) != v.cell[j] {
+					return false
+				}
+			}
+			return true
+		}
+		n[i].Sqd = math.Inf(1)
+		t.rangeSearch(box, in, func(kd *kdNode) bool {
+			if d := kd.domElt.Sqd(c); d < n[i].Sqd ||
+				d == n[i].Sqd && kd.index < n[i].Index {
+				n[i] = kd.neighbor(d)
+			}
+			return true
+		})
+	}
+	return n
+}
+
+func (v *voxel) centroid() Point {
+	c := make(Point, len(v.sum))
+	for i, s := range v.sum {
+		c[i] = s / float64(v.n)
+	}
+	return c
+}
+
+// voxels returns the occupied voxels of t, in order.
+func (t KdTree) voxels(size float64) []*voxel {
+	if t.n == nil {
+		return nil
+	}
+	vs := map[string]*voxel{}
+	key := make([]byte, 0, 8*len(t.Bounds.Min))
+	cellOf := func(p Point) []int64 {
+		c := make([]int64, len(p))
+		for i, x := range p {
+			c[i] = int64(math.Floor(x / size))
+		}
+		return c
+	}
+	get := func(c []int64) *voxel {
+		key = key[:0]
+		for _, x := range c {
+			for s := 0; s < 64; s += 8 {
+				key = append(key, byte(x>>s))
+			}
+		}
+		v := vs[string(key)]
+		if v == nil {
+			v = &voxel{cell: c, sum: make(Point, len(c))}
+			vs[string(key)] = v
+		}
+		return v
+	}
+	add := func(v *voxel, p Point) {
+		for i, x := range p {
+			v.sum[i] += x
+		}
+		v.n++
+	}
+	c := newCellStack(t, 0.)
+	for {
+		kd, _, ok := c.pop()
+		if !ok {
+			break
+		}
+		if t.excludes(kd) {
+			continue
+		}
+		box := c.cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		if lo := cellOf(box.Min); slices.Equal(lo, cellOf(box.Max)) {
+			v := get(lo)
+			t.yieldAll(kd, func(n *kdNode) bool {
+				add(v, n.domElt)
+				return true
+			})
+			continue
+		}
+		if t.sees(kd) {
+			add(get(cellOf(kd.domElt)), kd.domElt)
+		}
+		c.push(kd, kd.right, 0)
+		c.push(kd, kd.left, 0)
+	}
+	r := make([]*voxel, 0, len(vs))
+	for _, v := range vs {
+		if v.n > 0 {
+			r = append(r, v)
+		}
+	}
+	slices.SortFunc(r, func(a, b *voxel) int { return slices.Compare(a.cell, b.cell) })
+	return r
+}
@@ -0,0 +1,70 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDownsample(t *testing.T) {
+	pts := randomPts(3, 5000)
+	kd := NewWith(append([]Point{}, pts...))
+	kd.Brute = false
+	size := .2
+	// brute force centroids by voxel
+	type acc struct {
+		sum Point
+		n   int
+	}
+	want := map[[3]int64]*acc{}
+	for _, p := range pts {
+		var k [3]int64
+		for i, x := range p {
+			k[i] = int64(math.Floor(x / size))
+		}
+		a := want[k]
+		if a == nil {
+			a = &acc{sum: Point{0, 0, 0}}
+			want[k] = a
+		}
+		for i, x := range p {
+			a.sum[i] += x
+		}
+		a.n++
+	}
+	got := kd.Downsample(size)
+	if len(got) != len(want) {
+		t.Fatal("got", len(got), "voxels, want", len(want))
+	}
+	for _, c := range got {
+		var k [3]int64
+		for i, x := range c {
+			k[i] = int64(math.Floor(x / size))
+		}
+		a := want[k]
+		for i, x := range c {
+			if math.Abs(x-a.sum[i]/float64(a.n)) > 1e-12 {
+				t.Fatal("centroid", c, "voxel", k)
+			}
+		}
+	}
+	med := kd.DownsampleMedoid(size)
+	for i, m := range med {
+		c := got[i]
+		for j, p := range pts {
+			in := true
+			for d := range p {
+				in = in && math.Floor(p[d]/size) == math.Floor(c[d]/size)
+			}
+			if in && p.Sqd(c) < m.Sqd || j == m.Index && !in {
+				t.Fatal("medoid", m, "of voxel with centroid", c)
+			}
+		}
+	}
+}
+
+func TestDownsampleDeep(t *testing.T) {
+	kd, _ := deepTree(3000)
+	if got := kd.Downsample(.5); len(got) != 2 {
+		t.Fatal("voxels", got)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// VPTree is a vantage-point tree (Yianilos) indexing items of any type
+// under a caller-supplied metric.
+//
+// Unlike KdTree, a VPTree needs no coordinates, only a distance function.
+// It works for edit distance between strings, distances between sets, or
+// any function satisfying the triangle inequality.  Each node holds a
+// vantage item and the median distance mu from it to the items below.
+// Items nearer than mu go in the inside subtree, the rest outside.
+//
+// Queries follow the conventions of KdTree but report distances as
+// returned by the metric, rather than squared.
+type VPTree[T any] struct {
+	root *vpNode[T]
+	dist func(a, b T) float64
+}
+
+type vpNode[T any] struct {
+	item            T
+	mu              float64
+	inside, outside *vpNode[T]
+}
+
+// NewVPTree builds a VPTree of items under the metric dist.  items is
+// reordered.
+func NewVPTree[T any](items []T, dist func(a, b T) float64) *VPTree[T] {
+	rng := rand.New(rand.NewSource(1))
+	type entry struct {
+		item T
+		d    float64
+	}
+	es := make([]entry, len(items))
+	for i, it := range items {
+		es[i].item = it
+	}
+	var build func([]entry) *vpNode[T]
+	build = func(es []entry) *vpNode[T] {
+		if len(es) == 0 {
+			return nil
+		}
+		// a random vantage point, swapped to the front
+		v := rng.Intn(len(es))
+		es[0], es[v] = es[v], es[0]
+		n := &vpNode[T]{item: es[0].item}
+		rest := es[1:]
+		if len(rest) == 0 {
+			return n
+		}
+		for i := range rest {
+			rest[i].d = dist(n.item, rest[i].item)
+		}
+		sort.Slice(rest, func(i, j int) bool { return rest[i].d < rest[j].d })
+		m := len(rest) / 2
+		n.mu = rest[m].d
+		// items at distance mu go outside
+		for m > 0 && rest[m-1].d == n.mu {
+			m--
+		}
+		n.inside = build(rest[:m])
+		n.outside = build(rest[m:])
+		return n
+	}
+	return &VPTree[T]{build(es), dist}
+}
+
+// Nearest finds the item nearest q.
+//
+// return values:
+//   - the nearest item.
+//   - the distance to that item.
+//   - a count of the nodes visited in the search.
+func (t *VPTree[T]) Nearest(q T) (best T, dist float64, nv int) {
+	nn, d, nv := t.KNearest(q, 1)
+	if len(nn) == 0 {
+		return best, math.Inf(1), nv
+	}
+	return nn[0], d[0], nv
+}
+
+// KNearest finds the k items nearest q, in order of increasing distance.
+func (t *VPTree[T]) KNearest(q T, k int) (nn []T, dist []float64, nv int) {
+	if k <= 0 {
+		return
+	}
+	tau := math.Inf(1)
+	var search func(*vpNode[T])
+	search = func(n *vpNode[T]) {
+		if n == nil {
+			return
+		}
+		nv++
+		d := t.dist(q, n.item)
+		if d < tau {
+			nn, dist, tau = keepK(nn, dist, k, n.item, d)
+		}
+		// by the triangle inequality, items inside are at least d-mu from
+		// q and items outside at least mu-d.
+		if d < n.mu {
+			search(n.inside)
+			if d+tau >= n.mu {
+				search(n.outside)
+			}
+		} else {
+			search(n.outside)
+			if d-tau < n.mu {
+				search(n.inside)
+			}
+		}
+	}
+	search(t.root)
+	return
+}
+
+// keepK inserts item at distance d into nn and dist, kept in order of
+// increasing distance, dropping the k+1st item if any.  It returns the
+// updated slices and the distance an item must beat to be kept, +Inf
+// until there are k items.
+func keepK[T any](nn []T, dist []float64, k int, item T,
+	d float64) ([]T, []float64, float64) {
+	i := sort.SearchFloat64s(dist, d)
+	if len(nn) < k {
+		var zero T
+		nn = append(nn, zero)
+		dist = append(dist, 0)
+	}
+	copy(nn[i+1:], nn[i:])
+	copy(dist[i+1:], dist[i:])
+	nn[i], dist[i] = item, d
+	if len(nn) < k {
+		return nn, dist, math.Inf(1)
+	}
+	return nn, dist, dist[k-1]
+}
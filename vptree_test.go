@@ -0,0 +1,63 @@
+package kdtree
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+// edit distance
+func levenshtein(a, b string) float64 {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur := row[j]
+			row[j] = min(row[j]+1, row[j-1]+1, prev+cost)
+			prev = cur
+		}
+	}
+	return float64(row[len(b)])
+}
+
+func TestVPTreeStrings(t *testing.T) {
+	words := []string{"kitten", "sitting", "mitten", "fitting", "bitten",
+		"written", "smitten", "knitting", "sitter", "kitchen"}
+	vp := NewVPTree(append([]string{}, words...), levenshtein)
+	w, d, _ := vp.Nearest("sittin")
+	if d != 1 || (w != "sitting" && w != "sitter") {
+		t.Error("Expected sitting or sitter at 1, found", w, d)
+	}
+}
+
+func TestVPTreePoints(t *testing.T) {
+	pts := randomPts(3, 1000)
+	vp := NewVPTree(append([]Point{}, pts...), func(a, b Point) float64 {
+		return math.Sqrt(a.Sqd(b))
+	})
+	for i := 0; i < 20; i++ {
+		p := randomPt(3)
+		all := make([]float64, len(pts))
+		for j, q := range pts {
+			all[j] = math.Sqrt(p.Sqd(q))
+		}
+		sort.Float64s(all)
+		_, d, nv := vp.KNearest(p, 5)
+		for j := range d {
+			if d[j] != all[j] {
+				t.Fatal("Expected distances", all[:5], "found", d)
+			}
+		}
+		if nv >= len(pts) {
+			t.Error("Expected pruning, visited", nv)
+		}
+	}
+}
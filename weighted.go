@@ -0,0 +1,67 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import "math"
+
+// NearestWeighted finds the point q of t minimizing the weighted distance
+// d(p, q) / w(q), the multiplicatively weighted Voronoi assignment of p.
+// Points with greater weight attract from farther away.  Of points at
+// equal weighted distances, the one with greater weight is chosen.
+//
+// weight gives the weight of each point from the Neighbor describing it.
+// Weights must be positive.  maxWeight must be at least the greatest
+// weight of any point; it bounds the weighted distance to points of a
+// subtree for pruning, so a tight value prunes most.  If maxWeight is
+// <= 0, it is found by visiting every point.
+//
+// ok is false if t has no points.  wd is the weighted distance of n.
+func (t KdTree) NearestWeighted(p Point, weight func(Neighbor) float64,
+	maxWeight float64) (n Neighbor, wd float64, ok bool) {
+	if t.n == nil {
+		return
+	}
+	if maxWeight <= 0 {
+		t.yieldAll(t.n, func(kd *kdNode) bool {
+			maxWeight = math.Max(maxWeight, weight(kd.neighbor(0)))
+			return true
+		})
+	}
+	mw2 := maxWeight * maxWeight
+	best := math.Inf(1) // squared weighted distance
+	bestW := 0.
+	c := newCellStack(t, 0.)
+	for {
+		kd, _, more := c.pop()
+		if !more {
+			break
+		}
+		if t.excludes(kd) {
+			continue
+		}
+		box := c.cell
+		if kd.bounds != nil {
+			box = *kd.bounds
+		}
+		if box.Sqd(p)/mw2 > best {
+			continue
+		}
+		if t.sees(kd) {
+			nb := kd.neighbor(kd.domElt.Sqd(p))
+			w := weight(nb)
+			if d := nb.Sqd / (w * w); d < best || d == best && w > bestW {
+				n, best, bestW, ok = nb, d, w, true
+			}
+		}
+		// the nearer side is pushed last, to be searched first
+		if p[kd.split] > kd.domElt[kd.split] {
+			c.push(kd, kd.left, 0)
+			c.push(kd, kd.right, 0)
+		} else {
+			c.push(kd, kd.right, 0)
+			c.push(kd, kd.left, 0)
+		}
+	}
+	return n, math.Sqrt(best), ok
+}
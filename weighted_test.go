@@ -0,0 +1,40 @@
+package kdtree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNearestWeighted(t *testing.T) {
+	pts := randomPts(2, 500)
+	data := make([]interface{}, len(pts))
+	for i := range data {
+		data[i] = .5 + float64(i%4)
+	}
+	kd := NewWithData(append([]Point{}, pts...), data,
+		HyperRect{Point{0, 0}, Point{1, 1}})
+	kd.Tighten()
+	weight := func(n Neighbor) float64 { return n.Data.(float64) }
+	for i := 0; i < 50; i++ {
+		p := randomPt(2)
+		want := math.Inf(1)
+		for j, q := range pts {
+			want = math.Min(want, math.Sqrt(q.Sqd(p))/data[j].(float64))
+		}
+		for _, mw := range []float64{0, 3.5, 10} {
+			_, wd, ok := kd.NearestWeighted(p, weight, mw)
+			if !ok || math.Abs(wd-want) > 1e-12 {
+				t.Fatal("weighted distance", wd, "expected", want)
+			}
+		}
+	}
+}
+
+func TestNearestWeightedDeep(t *testing.T) {
+	kd, pts := deepTree(3000)
+	n, _, ok := kd.NearestWeighted(Point{.3001, .2999},
+		func(Neighbor) float64 { return 1 }, 1)
+	if !ok || !equal(n.Point, pts[900]) {
+		t.Fatal(n, ok)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2012 Sonia Keys
+// License MIT: http://www.opensource.org/licenses/MIT
+
+package kdtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WKBFeature is the data associated with each point of a tree read by
+// ReadWKB.
+type WKBFeature struct {
+	ID   int    // position of the point in the input, from 0
+	SRID uint32 // spatial reference ID, or 0 if the point had none
+}
+
+// ReadWKB constructs a tree, as NewWith, from a sequence of Well-Known
+// Binary points read from r until EOF.  Each point is associated with a
+// WKBFeature.
+//
+// Points may be 2D or 3D, in either byte order, in ISO WKB or in the
+// extended WKB of PostGIS, which may carry an SRID.  M coordinates are
+// read and discarded.  Empty points, which have NaN coordinates, are
+// skipped but still counted in feature IDs.  All other points must have
+// the same number of dimensions.
+func ReadWKB(r io.Reader, opts ...Option) (KdTree, error) {
+	br := bufio.NewReader(r)
+	var b Builder
+	dim := 0
+	for id := 0; ; id++ {
+		if _, err := br.Peek(1); err == io.EOF {
+			break
+		}
+		p, srid, err := readWKBPoint(br)
+		if err != nil {
+			return KdTree{}, fmt.Errorf("kdtree: WKB point %d: %w", id, err)
+		}
+		if math.IsNaN(p[0]) {
+			continue
+		}
+		if dim == 0 {
+			dim = len(p)
+		} else if len(p) != dim {
+			return KdTree{}, fmt.Errorf(
+				"kdtree: WKB point %d has %d dimensions, expected %d",
+				id, len(p), dim)
+		}
+		b.Add(p, WKBFeature{id, srid})
+	}
+	return b.Build(opts...), nil
+}
+
+// EWKB flags of the geometry type.
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+var errWKBType = errors.New("not a point")
+
+func readWKBPoint(r io.Reader) (p Point, srid uint32, err error) {
+	var order [1]byte
+	if _, err = io.ReadFull(r, order[:]); err != nil {
+		return
+	}
+	var bo binary.ByteOrder
+	switch order[0] {
+	case 0:
+		bo = binary.BigEndian
+	case 1:
+		bo = binary.LittleEndian
+	default:
+		return nil, 0, fmt.Errorf("invalid byte order %d", order[0])
+	}
+	var typ uint32
+	if err = binary.Read(r, bo, &typ); err != nil {
+		return
+	}
+	z := typ&ewkbZ != 0
+	m := typ&ewkbM != 0
+	if typ&ewkbSRID != 0 {
+		if err = binary.Read(r, bo, &srid); err != nil {
+			return
+		}
+	}
+	switch typ &^ (ewkbZ | ewkbM | ewkbSRID) {
+	case 1:
+	case 1001:
+		z = true
+	case 2001:
+		m = true
+	case 3001:
+		z, m = true, true
+	default:
+		return nil, 0, errWKBType
+	}
+	n := 2
+	if z {
+		n++
+	}
+	if m {
+		n++
+	}
+	c := make([]float64, n)
+	if err = binary.Read(r, bo, c); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	if m {
+		c = c[:n-1]
+	}
+	return Point(c), srid, nil
+}
@@ -0,0 +1,53 @@
+package kdtree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestReadWKB(t *testing.T) {
+	var buf bytes.Buffer
+	// ISO 2D little endian
+	buf.WriteByte(1)
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	binary.Write(&buf, binary.LittleEndian, []float64{1, 2})
+	// EWKB big endian with SRID
+	buf.WriteByte(0)
+	binary.Write(&buf, binary.BigEndian, uint32(1|ewkbSRID))
+	binary.Write(&buf, binary.BigEndian, uint32(4326))
+	binary.Write(&buf, binary.BigEndian, []float64{3, 4})
+	// empty point
+	buf.WriteByte(1)
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	binary.Write(&buf, binary.LittleEndian, []float64{math.NaN(), math.NaN()})
+	// ISO M, M discarded
+	buf.WriteByte(1)
+	binary.Write(&buf, binary.LittleEndian, uint32(2001))
+	binary.Write(&buf, binary.LittleEndian, []float64{5, 6, 99})
+
+	kd, err := ReadWKB(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := kd.KNearestNeighbors(Point{3, 4}, 1)
+	if f := n[0].Data.(WKBFeature); f.ID != 1 || f.SRID != 4326 || n[0].Sqd != 0 {
+		t.Error("got", n[0])
+	}
+	n = kd.KNearestNeighbors(Point{5, 6}, 3)
+	if len(n) != 3 || n[0].Data.(WKBFeature).ID != 3 {
+		t.Error("got", n)
+	}
+
+	// a 3D point among 2D ones
+	buf.WriteByte(1)
+	binary.Write(&buf, binary.LittleEndian, uint32(1001))
+	binary.Write(&buf, binary.LittleEndian, []float64{1, 2, 3})
+	if _, err := ReadWKB(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("expected error for mixed dimensions")
+	}
+	if _, err := ReadWKB(bytes.NewReader(buf.Bytes()[:30])); err == nil {
+		t.Error("expected error for truncated input")
+	}
+}